@@ -0,0 +1,763 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	golosClient "github.com/asuleymanov/golos-go/client"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/blockchain"
+	"github.com/GolosTools/golos-vote-bot/conversation"
+	"github.com/GolosTools/golos-vote-bot/helpers"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/router"
+)
+
+const delegationLink = "https://golostools.github.io/golos-vote-bot/"
+
+// linkCredential привязывает аккаунт, владение которым подтверждено, и возвращает текст ответа
+func linkCredential(userID int, chatID int64, login string, lang string) (string, error) {
+	credential := models.Credential{
+		UserID:   userID,
+		ChatID:   chatID,
+		UserName: login,
+		Power:    100,
+		Active:   true,
+		Curates:  false,
+	}
+	// приостановленное при отзыве доступа кураторство возвращается вместе с доступом
+	if previous, err := store.GetCredentialByUserName(login); err == nil && previous.UserID == userID {
+		credential.Power = previous.Power
+		credential.Curates = previous.Curates
+	} else if primary, err := store.GetCredentialByUserID(userID); err == nil {
+		// новый аккаунт куратора сразу участвует в курировании
+		credential.Curates = primary.Curates
+	}
+
+	// награда ждёт, пока приглашённый наберёт нужное количество постов
+	referral, err := store.GetReferralByUserID(userID)
+	if err == nil && !referral.Completed {
+		referral.Completed = true
+		referral.UserName = credential.UserName
+		_, err = store.GetCredentialByUserName(credential.UserName)
+		if err == sql.ErrNoRows {
+			referral.Status = models.ReferralPending
+			// без referral_deadline_days в конфиге приглашённого не торопим
+			if config.ReferralDeadlineDays > 0 {
+				referral.Deadline = time.Now().AddDate(0, 0, config.ReferralDeadlineDays)
+			}
+		}
+		err = store.SaveReferral(referral, models.UserActor(userID))
+		if err != nil {
+			log.Println("не сохранили реферала: " + err.Error())
+		}
+	}
+
+	err = store.SaveCredential(credential, models.UserActor(userID))
+	if err != nil {
+		return "", err
+	}
+	return i18n.Markdown(lang, "add_key.success", config.GroupLink), nil
+}
+
+// confirmClaim привязывает аккаунт по подтверждённой заявке,
+// отбирая его у прежнего владельца и отклоняя конкурирующие заявки
+func confirmClaim(claim models.Claim, lang string) (string, error) {
+	owner, err := store.GetCredentialByUserName(claim.UserName)
+	if err == nil && owner.UserID != claim.UserID {
+		notifyAdmins("admin.claim_moved", claim.UserName, owner.UserID, claim.UserID)
+		if owner.ChatID != 0 {
+			outbox.Send(tgbotapi.NewMessage(owner.ChatID, i18n.T(userLanguage(owner.UserID, nil), "claim.lost", claim.UserName)))
+		}
+	}
+	rivals, err := store.GetRivalClaims(claim)
+	if err != nil {
+		log.Println("не получили конкурирующие заявки: " + err.Error())
+	}
+	for _, rival := range rivals {
+		notifyAdmins("admin.claim_rejected", rival.UserID, rival.UserName, claim.UserID)
+		err = store.DeleteClaim(rival.UserID, models.UserActor(claim.UserID))
+		if err != nil {
+			log.Println("не удалили заявку: " + err.Error())
+		}
+	}
+	text, err := linkCredential(claim.UserID, claim.ChatID, claim.UserName, lang)
+	if err != nil {
+		return "", err
+	}
+	return text, store.DeleteClaim(claim.UserID, models.UserActor(claim.UserID))
+}
+
+// flagClaimConflicts сообщает администраторам о заявках на уже занятые аккаунты
+func flagClaimConflicts(claim models.Claim) {
+	owner, err := store.GetCredentialByUserName(claim.UserName)
+	if err == nil && owner.UserID != claim.UserID {
+		notifyAdmins("admin.claim_taken", claim.UserID, claim.UserName, owner.UserID)
+	}
+	rivals, err := store.GetRivalClaims(claim)
+	if err != nil {
+		log.Println("не получили конкурирующие заявки: " + err.Error())
+		return
+	}
+	for _, rival := range rivals {
+		notifyAdmins("admin.claim_rivals", claim.UserName, rival.UserID, claim.UserID)
+	}
+}
+
+const (
+	statsWeek  = "week"
+	statsMonth = "month"
+	statsAll   = "all"
+)
+
+// statsText рассказывает, как бот распорядился каждым аккаунтом пользователя за период
+func statsText(userID int, period string, lang string) (string, error) {
+	var since time.Time
+	switch period {
+	case statsWeek:
+		since = time.Now().AddDate(0, 0, -7)
+	case statsMonth:
+		since = time.Now().AddDate(0, -1, 0)
+	case statsAll:
+	default:
+		return "", errors.New("неизвестный период статистики: " + period)
+	}
+	credentials, err := store.GetCredentialsByUserID(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(credentials) == 0 {
+		return i18n.T(lang, "info.no_info"), nil
+	}
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+	fund, err := helpers.GetRewardFund(golos)
+	if err != nil {
+		return "", err
+	}
+	text := i18n.Markdown(lang, "stats.title", i18n.T(lang, "stats."+period))
+	for _, credential := range credentials {
+		casts, err := store.GetCastsByUserNameSince(credential.UserName, since)
+		if err != nil {
+			return "", err
+		}
+		weight := 0
+		for _, cast := range casts {
+			weight += cast.Weight
+		}
+		var rewards float64
+		if len(casts) > 0 {
+			// награды за голос приходят после него, поэтому историю достаточно листать до самого раннего голоса
+			earliest := casts[0].Date
+			for _, cast := range casts {
+				if cast.Date.Before(earliest) {
+					earliest = cast.Date
+				}
+			}
+			history, err := helpers.GetAccountHistorySince(golos, credential.UserName, earliest)
+			if err != nil {
+				return "", err
+			}
+			rewards, err = helpers.SumCurationRewards(history, credential.UserName, casts)
+			if err != nil {
+				return "", err
+			}
+		}
+		text += i18n.Markdown(lang, "stats.line", credential.UserName, len(casts), weight/100,
+			fmt.Sprintf("%.3f", fund.GolosPower(rewards)))
+	}
+	return text, nil
+}
+
+func statsMarkup(lang string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.week"), "stats_"+statsWeek),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.month"), "stats_"+statsMonth),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.all_time"), "stats_"+statsAll)))
+}
+
+// myPageSize — сколько постов показывать на одной странице /my
+const myPageSize = 5
+
+// myText описывает предложенные пользователем посты с итогами голосования и тем,
+// когда можно предложить следующий
+func myText(userID int, page int, lang string) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	var markup tgbotapi.InlineKeyboardMarkup
+	total := store.GetVotesCountForUserID(userID)
+	if total == 0 {
+		return i18n.T(lang, "my.empty"), markup, nil
+	}
+	pages := (total + myPageSize - 1) / myPageSize
+	if page < 0 || page >= pages {
+		page = 0
+	}
+	votes, err := store.GetVotesPageForUserID(userID, myPageSize, page*myPageSize)
+	if err != nil {
+		return "", markup, err
+	}
+	trust, err := store.GetTrustByUserID(userID)
+	if err != nil {
+		return "", markup, err
+	}
+	next := store.GetLastVoteForUserID(userID).Date.Add(trust.Interval(config.PostingInterval))
+	text := i18n.Markdown(lang, "my.title", page*myPageSize+1, page*myPageSize+len(votes), total)
+	for i, vote := range votes {
+		positives, negatives := store.GetNumResponsesVoteID(vote.VoteID)
+		text += i18n.Markdown(lang, "my.line", page*myPageSize+i+1, vote.Author, vote.Permalink,
+			i18n.T(lang, "my.status."+vote.Status()), positives, negatives,
+			store.GetCastsCountForVoteID(vote.VoteID))
+	}
+	if time.Now().Before(next) {
+		text += i18n.Markdown(lang, "my.next_at", next.Format("02.01.2006 15:04"))
+	} else {
+		text += i18n.T(lang, "my.next_now")
+	}
+	var buttons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.previous"),
+			"my_"+strconv.Itoa(page-1)))
+	}
+	if page < pages-1 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.next"),
+			"my_"+strconv.Itoa(page+1)))
+	}
+	if len(buttons) > 0 {
+		markup = tgbotapi.NewInlineKeyboardMarkup(buttons)
+	}
+	return text, markup, nil
+}
+
+func languageMarkup() tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, code := range i18n.Languages() {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.T(code, "button.language"), "lang_"+code))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// accountsMarkup предлагает выбрать один из аккаунтов пользователя для действия
+func accountsMarkup(credentials []models.Credential, action string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, credential := range credentials {
+		button := tgbotapi.NewInlineKeyboardButtonData(credential.UserName, action+"_"+credential.UserName)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// stopCredentials отключает аккаунты пользователя и возвращает текст ответа.
+// Когда активных аккаунтов не остаётся, снимается и кураторство
+func stopCredentials(userID int, credentials []models.Credential, lang string) string {
+	for _, credential := range credentials {
+		credential.Active = false
+		err := store.SaveCredential(credential, models.UserActor(userID))
+		if err != nil {
+			log.Println(err.Error())
+			return i18n.Markdown(lang, "error.developer", config.Developer)
+		}
+	}
+	if !store.IsActiveCredential(userID) {
+		err := store.DeactivateCurator(userID, models.UserActor(userID))
+		if err != nil {
+			log.Println(err.Error())
+		}
+	}
+	return i18n.T(lang, "remove_key.done")
+}
+
+func handleStats(ctx *router.Context) error {
+	text, err := statsText(ctx.UserID, statsWeek, ctx.Lang)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(ctx.ChatID, text)
+	msg.ReplyMarkup = statsMarkup(ctx.Lang)
+	return reply(ctx, msg)
+}
+
+func handleMy(ctx *router.Context) error {
+	text, markup, err := myText(ctx.UserID, 0, ctx.Lang)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(ctx.ChatID, text)
+	if len(markup.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = markup
+	}
+	return reply(ctx, msg)
+}
+
+func handleLanguage(ctx *router.Context) error {
+	msg := tgbotapi.NewMessage(ctx.ChatID, i18n.T(ctx.Lang, "language.choose"))
+	msg.ReplyMarkup = languageMarkup()
+	return reply(ctx, msg)
+}
+
+func handleAddKey(ctx *router.Context) error {
+	msg := tgbotapi.NewMessage(ctx.ChatID,
+		i18n.Markdown(ctx.Lang, "add_key.instructions", config.Account, delegationLink, delegationLink))
+	err := fire(ctx, conversation.AddKey, "")
+	if err != nil {
+		return err
+	}
+	return reply(ctx, msg)
+}
+
+func handleRemoveKey(ctx *router.Context) error {
+	credentials, err := store.GetActiveCredentialsByUserID(ctx.UserID)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(ctx.ChatID, "")
+	switch len(credentials) {
+	case 0:
+		msg.Text = i18n.T(ctx.Lang, "account.not_active")
+	case 1:
+		msg.Text = stopCredentials(ctx.UserID, credentials, ctx.Lang)
+	default:
+		msg.Text = i18n.T(ctx.Lang, "remove_key.choose")
+		markup := accountsMarkup(credentials, "stop")
+		markup.InlineKeyboard = append(markup.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(ctx.Lang, "button.all_accounts"), "stop_*")))
+		msg.ReplyMarkup = markup
+	}
+	err = fire(ctx, conversation.Cancel, "")
+	if err != nil {
+		return err
+	}
+	return reply(ctx, msg)
+}
+
+func handleSetPower(ctx *router.Context) error {
+	msg := tgbotapi.NewMessage(ctx.ChatID, "")
+	if false == store.IsActiveCredential(ctx.UserID) {
+		msg.Text = i18n.Markdown(ctx.Lang, "delegate_first", i18n.T(ctx.Lang, buttonAddKey))
+		return reply(ctx, msg)
+	}
+	credentials, err := store.GetActiveCredentialsByUserID(ctx.UserID)
+	if err != nil {
+		return err
+	}
+	msg.Text = i18n.T(ctx.Lang, "power.prompt")
+	if len(credentials) > 1 {
+		msg.Text = i18n.Markdown(ctx.Lang, "power.prompt_choose", credentials[0].UserName)
+		msg.ReplyMarkup = accountsMarkup(credentials[1:], "power")
+	}
+	err = fire(ctx, conversation.SetPower, credentials[0].UserName)
+	if err != nil {
+		return err
+	}
+	return reply(ctx, msg)
+}
+
+func handleInformation(ctx *router.Context) error {
+	userID, lang := ctx.UserID, ctx.Lang
+	msg := tgbotapi.NewMessage(ctx.ChatID, "")
+	if false == store.IsActiveCredential(userID) {
+		msg.Text = i18n.T(lang, "info.no_info")
+		return reply(ctx, msg)
+	}
+	credential, err := store.GetCredentialByUserID(userID)
+	if err != nil {
+		return err
+	}
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+	// экран информации только показывает доверие, сохраняется оно при предложении поста
+	trust, err := currentTrust(userID, golos)
+	if err != nil {
+		return err
+	}
+	credentials, err := store.GetCredentialsByUserID(userID)
+	if err != nil {
+		return err
+	}
+	accountsText := ""
+	for _, account := range credentials {
+		status := ""
+		if !account.Active {
+			status = i18n.T(lang, "info.disabled")
+		}
+		accountsText += i18n.Markdown(lang, "info.account", account.UserName, account.Power, status)
+	}
+	msg.Text = i18n.Markdown(lang, "info",
+		i18n.Raw(accountsText),
+		fmt.Sprintf("%.2f", trust.Score),
+		fmt.Sprintf("%.0f", trust.ApprovalRate*100),
+		fmt.Sprintf("%.0f", trust.AddledRate*100),
+		trust.PlagiarismCount, trust.AccountAgeDays(time.Now()))
+	referralLink, err := referralLink(credential.UserName, "")
+	if err == nil {
+		msg.Text += i18n.Markdown(lang, "info.referral", referralLink, referralLink,
+			fmt.Sprintf("%.3f", config.ReferralFee), config.ReferralMinimumPostCount)
+	} else if err != helpers.ErrNoReferralSecret {
+		return err
+	}
+	var button tgbotapi.InlineKeyboardButton
+	if store.IsActiveCurator(userID) {
+		button = tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.stop_curating"), "curating_stop")
+	} else {
+		button = tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.start_curating"), "curating_start")
+	}
+	keyboard := []tgbotapi.InlineKeyboardButton{button}
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard)
+	msg.ReplyMarkup = markup
+	err = fire(ctx, conversation.Cancel, "")
+	if err != nil {
+		return err
+	}
+	return reply(ctx, msg)
+}
+
+func handleLogin(ctx *router.Context) error {
+	update, userID, chatID, lang := ctx.Update, ctx.UserID, ctx.ChatID, ctx.Lang
+	msg := tgbotapi.NewMessage(chatID, "")
+	login := strings.ToLower(update.Message.Text)
+	login = strings.Trim(login, "@")
+
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+	accounts, err := golos.Rpc.Database.GetAccounts([]string{login})
+	if err != nil {
+		return err
+	} else if len(accounts) == 1 {
+		hasPostingAuth := false
+		for _, auth := range accounts[0].Posting.AccountAuths {
+			if auth.([]interface{})[0] == config.Account {
+				hasPostingAuth = true
+				break
+			}
+		}
+		if hasPostingAuth {
+			// владение этим аккаунтом пользователь уже подтверждал
+			if owner, err := store.GetCredentialByUserName(login); err == nil && owner.UserID == userID {
+				msg.Text, err = linkCredential(userID, chatID, login, lang)
+				if err != nil {
+					return err
+				}
+				err = fire(ctx, conversation.Linked, "")
+				if err != nil {
+					return err
+				}
+				return reply(ctx, msg)
+			}
+			claim, err := models.NewClaim(userID, chatID, login)
+			if err != nil {
+				return err
+			}
+			err = store.SaveClaim(claim, models.UserActor(userID))
+			if err != nil {
+				return err
+			}
+			flagClaimConflicts(claim)
+			msg.Text = i18n.Markdown(lang, "claim.instructions", login, config.Account, claim.Code)
+			button := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.claim_check"), "claim_check")
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(button))
+			err = fire(ctx, conversation.Claim, login)
+			if err != nil {
+				return err
+			}
+		} else {
+			msg.Text = i18n.Markdown(lang, "add_key.no_access", config.Account)
+		}
+	} else {
+		msg.Text = i18n.Markdown(lang, "error.try_later", config.Developer)
+		log.Printf("Введён некорректный логин: %s", update.Message.Text)
+	}
+	return reply(ctx, msg)
+}
+
+func handlePower(ctx *router.Context) error {
+	userID, lang := ctx.UserID, ctx.Lang
+	msg := tgbotapi.NewMessage(ctx.ChatID, "")
+	re := regexp.MustCompile("[0-9]+")
+	valueString := re.FindString(ctx.Message().Text)
+	value, err := strconv.Atoi(valueString)
+	if err != nil || value < 1 || value > 100 {
+		msg.Text = i18n.T(lang, "power.not_understood")
+		return reply(ctx, msg)
+	}
+	if false == store.IsActiveCredential(userID) {
+		msg.Text = i18n.Markdown(lang, "delegate_first", i18n.T(lang, buttonAddKey))
+		return reply(ctx, msg)
+	}
+
+	credential, err := store.GetCredentialByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if selected, err := store.GetCredentialByUserName(ctx.Dialog.Payload); err == nil &&
+		selected.UserID == userID && selected.Active {
+		credential = selected
+	}
+
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+
+	account, err := helpers.GetVotingAccount(golos, credential.UserName)
+	if err != nil {
+		return err
+	}
+	fund, err := helpers.GetRewardFund(golos)
+	if err != nil {
+		return err
+	}
+	vests, err := account.EffectiveVests()
+	if err != nil {
+		return err
+	}
+	fullVoteValue := helpers.EstimateVoteValue(vests, 10000, 10000, fund)
+	if fullVoteValue >= config.MinimumVoteValue {
+		err = store.UpdatePower(credential.UserName, value, models.UserActor(ctx.UserID))
+		if err != nil {
+			return err
+		}
+		voteValue := helpers.EstimateVoteValue(vests, account.VotingPower, value*100, fund)
+		msg.Text = i18n.Markdown(lang, "power.updated", credential.UserName, value,
+			fmt.Sprintf("%.3f", fund.GolosPower(vests)), fmt.Sprintf("%.3f", voteValue))
+	} else {
+		msg.Text = i18n.Markdown(lang, "power.too_small",
+			fmt.Sprintf("%.3f", fullVoteValue), fmt.Sprintf("%.3f", config.MinimumVoteValue))
+	}
+	err = fire(ctx, conversation.PowerUpdated, "")
+	if err != nil {
+		return err
+	}
+	return reply(ctx, msg)
+}
+
+func handleClaimCheck(ctx *router.Context) error {
+	userID, chatID, lang := ctx.UserID, ctx.ChatID, ctx.Lang
+	claim, err := store.GetClaimByUserID(userID)
+	if err == sql.ErrNoRows {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "claim.not_found", i18n.T(lang, buttonAddKey))))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if claim.Expired(time.Now()) {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "claim.expired", i18n.T(lang, buttonAddKey))))
+		return store.DeleteClaim(userID, models.UserActor(userID))
+	}
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+	history, err := helpers.GetAccountHistorySince(golos, claim.UserName, claim.Date)
+	if err != nil {
+		return err
+	}
+	if !helpers.HasOwnershipProof(history, claim.UserName, config.Account, claim.Code) {
+		callbackConfig := tgbotapi.CallbackConfig{
+			CallbackQueryID: ctx.Callback().ID,
+			Text:            i18n.T(lang, "claim.not_yet"),
+		}
+		bot.AnswerCallbackQuery(callbackConfig)
+		return nil
+	}
+	text, err := confirmClaim(claim, lang)
+	if err != nil {
+		return err
+	}
+	// заявка живёт дольше диалога, поэтому подтвердить её можно и после того, как бот перестал ждать
+	err = fire(ctx, conversation.Cancel, "")
+	if err != nil {
+		return err
+	}
+	err = saveConversation(userID, ctx.Dialog)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewEditMessageText(chatID, ctx.Callback().Message.MessageID, text)
+	msg.ParseMode = "Markdown"
+	bot.Send(msg)
+	return nil
+}
+
+func handleStatsCallback(ctx *router.Context) error {
+	text, err := statsText(ctx.UserID, ctx.Arg(0), ctx.Lang)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewEditMessageText(ctx.ChatID, ctx.Callback().Message.MessageID, text)
+	msg.ParseMode = "Markdown"
+	markup := statsMarkup(ctx.Lang)
+	msg.ReplyMarkup = &markup
+	bot.Send(msg)
+	return nil
+}
+
+func handleMyCallback(ctx *router.Context) error {
+	page, err := strconv.Atoi(ctx.Arg(0))
+	if err != nil {
+		return err
+	}
+	text, markup, err := myText(ctx.UserID, page, ctx.Lang)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewEditMessageText(ctx.ChatID, ctx.Callback().Message.MessageID, text)
+	msg.ParseMode = "Markdown"
+	msg.DisableWebPagePreview = true
+	if len(markup.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = &markup
+	}
+	bot.Send(msg)
+	return nil
+}
+
+func handlePowerCallback(ctx *router.Context) error {
+	login := ctx.Arg(0)
+	credential, err := store.GetCredentialByUserName(login)
+	if err != nil || credential.UserID != ctx.UserID || !credential.Active {
+		return errors.New("нельзя настроить чужой или отключённый аккаунт: " + login)
+	}
+	err = fire(ctx, conversation.SetPower, credential.UserName)
+	if err != nil {
+		return err
+	}
+	err = saveConversation(ctx.UserID, ctx.Dialog)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewEditMessageText(ctx.ChatID, ctx.Callback().Message.MessageID,
+		i18n.Markdown(ctx.Lang, "power.prompt_account", credential.UserName))
+	msg.ParseMode = "Markdown"
+	bot.Send(msg)
+	return nil
+}
+
+func handleStopCallback(ctx *router.Context) error {
+	credentials, err := store.GetActiveCredentialsByUserID(ctx.UserID)
+	if err != nil {
+		return err
+	}
+	login := ctx.Arg(0)
+	var selected []models.Credential
+	for _, credential := range credentials {
+		if login == "*" || credential.UserName == login {
+			selected = append(selected, credential)
+		}
+	}
+	msg := tgbotapi.NewEditMessageText(ctx.ChatID, ctx.Callback().Message.MessageID, "")
+	if len(selected) == 0 {
+		msg.Text = i18n.T(ctx.Lang, "account.not_active")
+	} else {
+		msg.Text = stopCredentials(ctx.UserID, selected, ctx.Lang)
+	}
+	msg.ParseMode = "Markdown"
+	bot.Send(msg)
+	return nil
+}
+
+func handleLanguageCallback(ctx *router.Context) error {
+	lang := ctx.Arg(0)
+	if i18n.Language(lang) != lang {
+		return errors.New("неподдерживаемый язык: " + lang)
+	}
+	language := models.Language{UserID: ctx.UserID, Code: lang, Manual: true}
+	err := store.SaveLanguage(language, models.UserActor(ctx.UserID))
+	if err != nil {
+		return err
+	}
+	// обычную клавиатуру нельзя поменять редактированием, поэтому присылаем её заново
+	msg := tgbotapi.NewMessage(ctx.ChatID, i18n.T(lang, "language.set"))
+	msg.ReplyMarkup = mainKeyboard(lang)
+	bot.Send(msg)
+	return nil
+}
+
+// claimCollector раз в час удаляет заявки с устаревшими кодами подтверждения
+func claimCollector() {
+	for {
+		count, err := store.PurgeExpiredClaims(time.Now(), models.SystemActor("claims"))
+		if err != nil {
+			log.Println("не удалили устаревшие заявки: " + err.Error())
+		} else if count > 0 {
+			log.Printf("Удалено устаревших заявок: %d", count)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// followBlocks раздаёт подписчикам события из блокчейна, переподключаясь при ошибках
+func followBlocks() {
+	for {
+		golos := golosClient.NewApi(config.Rpc, config.Chain)
+		blockEvents.Source = golos.Rpc.Database
+		err := blockEvents.Run()
+		log.Println("Обработка блоков прервалась: " + err.Error())
+		golos.Rpc.Close()
+		time.Sleep(time.Minute)
+	}
+}
+
+// onAccountUpdate сразу отключает аккаунты, отозвавшие у бота право голоса
+func onAccountUpdate(event blockchain.Event) {
+	operation, ok := event.AccountUpdate()
+	if !ok || operation.Posting == nil {
+		return
+	}
+	credential, err := store.GetCredentialByUserName(operation.Account)
+	if err != nil || !credential.Active {
+		return
+	}
+	if !helpers.HasPostingAuthority(operation.Posting, config.Account) {
+		revokeCredential(credential)
+	}
+}
+
+// revokeCredential отключает аккаунт, лишивший бота доступа, и предлагает владельцу вернуть его.
+// Кураторство при этом только приостанавливается
+func revokeCredential(credential models.Credential) {
+	log.Printf("Пользователь %s отключён", credential.UserName)
+	credential.Active = false
+	err := store.SaveCredential(credential, models.SystemActor("authority"))
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	if !store.IsActiveCredential(credential.UserID) {
+		trackRevokedMember(credential.UserID)
+	}
+	if credential.ChatID == 0 {
+		return
+	}
+	lang := userLanguage(credential.UserID, nil)
+	text := i18n.Markdown(lang, "revoke", credential.UserName, config.Account, i18n.T(lang, buttonAddKey))
+	if credential.Curates {
+		text += i18n.T(lang, "revoke.curator")
+	}
+	msg := tgbotapi.NewMessage(credential.ChatID, text)
+	msg.ParseMode = "Markdown"
+	button := tgbotapi.NewInlineKeyboardButtonURL(i18n.T(lang, "button.restore_access"), delegationLink)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{button})
+	err = outbox.Send(msg)
+	if err != nil {
+		log.Println("Не отправили сообщение: " + err.Error())
+	}
+}
+
+func checkAuthority() {
+	for {
+		credentials, err := store.GetAllActiveCredentials()
+		log.Printf("Загружено %d аккаунтов для проверки", len(credentials))
+		if err != nil {
+			log.Println(err.Error())
+		}
+		golos := golosClient.NewApi(config.Rpc, config.Chain)
+		for _, credential := range credentials {
+			if !golos.Verify_Delegate_Posting_Key_Sign(credential.UserName, config.Account) {
+				revokeCredential(credential)
+			}
+		}
+		golos.Rpc.Close()
+		time.Sleep(1 * time.Hour)
+	}
+}
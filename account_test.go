@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestStopCredentials(t *testing.T) {
+	setUp(t)
+	credential := models.Credential{UserID: 2, ChatID: 2, UserName: "chiliec", Power: 100, Active: true}
+	err := store.SaveCredential(credential, models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := stopCredentials(2, []models.Credential{credential}, "ru")
+	if text != i18n.T("ru", "remove_key.done") {
+		t.Errorf("неожиданный ответ %q", text)
+	}
+	if store.IsActiveCredential(2) {
+		t.Error("аккаунт должен быть отключён")
+	}
+}
+
+func TestAccountsMarkup(t *testing.T) {
+	markup := accountsMarkup([]models.Credential{{UserName: "chiliec"}, {UserName: "worthless"}}, "stop")
+	if len(markup.InlineKeyboard) != 2 || *markup.InlineKeyboard[1][0].CallbackData != "stop_worthless" {
+		t.Errorf("неожиданные кнопки %#v", markup.InlineKeyboard)
+	}
+}
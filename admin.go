@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/dump"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/router"
+)
+
+// notifyAdmins сообщает администраторам текст key, каждому на его языке
+func notifyAdmins(key string, params ...interface{}) {
+	log.Println(i18n.T(i18n.Default, key, params...))
+	for _, admin := range config.Admins {
+		err := outbox.Send(tgbotapi.NewMessage(int64(admin), i18n.T(userLanguage(admin, nil), key, params...)))
+		if err != nil {
+			log.Println("не уведомили администратора: " + err.Error())
+		}
+	}
+}
+
+// exportOptions разбирает параметры выгрузки из командной строки или команды /export
+func exportOptions(format string, entity string, since string, until string) (options dump.Options, err error) {
+	options.Format = format
+	if len(entity) > 0 && entity != "all" {
+		options.Entities = []string{entity}
+	}
+	options.Since, err = dump.ParseDate(since)
+	if err != nil {
+		return options, err
+	}
+	options.Until, err = dump.ParseDate(until)
+	return options, err
+}
+
+func importSummary(counts dump.Counts, lang string) string {
+	return i18n.T(lang, "admin.imported", counts.Credentials, counts.Referrals, counts.Votes, counts.Responses)
+}
+
+func isAdminContext(ctx *router.Context) bool {
+	return isAdmin(ctx.UserID)
+}
+
+func handleAdminCommand(ctx *router.Context) error {
+	message := ctx.Message()
+	// ответы содержат логины и тексты как есть, без разметки
+	msg := tgbotapi.NewMessage(ctx.ChatID, runAdminCommand(ctx.UserID, message.Command(), message.CommandArguments(), ctx.Lang))
+	msg.DisableWebPagePreview = true
+	_, err := bot.Send(msg)
+	return err
+}
+
+func handleExport(ctx *router.Context) error {
+	arguments := ctx.Message().CommandArguments()
+	args := strings.Fields(arguments)
+	if len(args) == 0 || len(args) > 4 {
+		return reply(ctx, tgbotapi.NewMessage(ctx.ChatID, i18n.T(ctx.Lang, "admin.usage.export")))
+	}
+	for len(args) < 4 {
+		args = append(args, "")
+	}
+	var buffer bytes.Buffer
+	options, err := exportOptions(args[0], args[1], args[2], args[3])
+	count := 0
+	if err == nil {
+		count, err = dump.Export(&buffer, store, options)
+	}
+	result := i18n.T(ctx.Lang, "admin.exported", count)
+	if err != nil {
+		result = i18n.T(ctx.Lang, "admin.failed", err.Error())
+	}
+	saveAdminAction(ctx.UserID, "export", arguments, result)
+	if err != nil {
+		_, err = bot.Send(tgbotapi.NewMessage(ctx.ChatID, result))
+		return err
+	}
+	name := "export-" + time.Now().Format("20060102-150405")
+	if len(options.Entities) == 1 {
+		name += "-" + options.Entities[0]
+	}
+	document := tgbotapi.NewDocumentUpload(ctx.ChatID, tgbotapi.FileBytes{
+		Name:  name + "." + options.Format,
+		Bytes: buffer.Bytes(),
+	})
+	document.Caption = result
+	_, err = bot.Send(document)
+	return err
+}
+
+func handleImport(ctx *router.Context) error {
+	document := ctx.Message().Document
+	if document == nil {
+		_, err := bot.Send(tgbotapi.NewMessage(ctx.ChatID, i18n.T(ctx.Lang, "admin.import_hint")))
+		return err
+	}
+	counts, err := importDocument(document.FileID, models.AdminActor(ctx.UserID))
+	result := importSummary(counts, ctx.Lang)
+	if err != nil {
+		result = i18n.T(ctx.Lang, "admin.failed", err.Error())
+	}
+	saveAdminAction(ctx.UserID, "import", document.FileName, result)
+	_, err = bot.Send(tgbotapi.NewMessage(ctx.ChatID, result))
+	return err
+}
+
+// importDocument скачивает присланный файл выгрузки и загружает его в базу
+func importDocument(fileID string, actor models.Actor) (counts dump.Counts, err error) {
+	fileURL, err := bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return counts, err
+	}
+	response, err := http.Get(fileURL)
+	if err != nil {
+		return counts, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return counts, errors.New("не удалось скачать файл: " + response.Status)
+	}
+	return dump.Import(response.Body, store, actor)
+}
+
+// runAdminCommand выполняет команду администратора и записывает её вместе с ответом в журнал
+func runAdminCommand(adminID int, command string, arguments string, lang string) string {
+	text, err := adminCommand(adminID, command, arguments, lang)
+	if err != nil {
+		text = i18n.T(lang, "admin.failed", err.Error())
+	}
+	log.Printf("Администратор %d: /%s %s", adminID, command, arguments)
+	saveAdminAction(adminID, command, arguments, text)
+	return text
+}
+
+// auditLimit — сколько изменений показывает /audit, чтобы ответ уместился в одно сообщение
+const auditLimit = 15
+
+// auditFilter разбирает аргументы /audit: без аргументов — все изменения,
+// <сущность> [ключ] — изменения сущности, user:<ID>, admin:<ID> или system:<задача> — изменения участника
+func auditFilter(args []string, lang string) (filter models.ChangeFilter, err error) {
+	usage := errors.New(i18n.T(lang, "admin.usage.audit", strings.Join(models.AuditEntities, "|")))
+	switch {
+	case len(args) == 0:
+		return filter, nil
+	case len(args) > 2:
+		return filter, usage
+	case strings.Contains(args[0], ":"):
+		if len(args) > 1 {
+			return filter, usage
+		}
+		actor, err := models.ParseActor(args[0])
+		if err != nil {
+			return filter, err
+		}
+		filter.Actor = actor.String()
+		return filter, nil
+	}
+	for _, entity := range models.AuditEntities {
+		if args[0] == entity {
+			filter.Entity = entity
+		}
+	}
+	if len(filter.Entity) == 0 {
+		return filter, usage
+	}
+	if len(args) > 1 {
+		filter.EntityID = strings.TrimPrefix(args[1], "@")
+	}
+	return filter, nil
+}
+
+// changeText описывает запись журнала одной строкой: когда, кто, что и какие поля изменил
+func changeText(change models.Change, lang string) string {
+	text := fmt.Sprintf("%s %s %s %s:", change.Date.Format("02.01 15:04"), change.Actor, change.Entity, change.EntityID)
+	switch {
+	case len(change.Before) == 0:
+		return text + " " + i18n.T(lang, "admin.change_created")
+	case len(change.After) == 0:
+		return text + " " + i18n.T(lang, "admin.change_deleted")
+	}
+	diff, err := change.Diff()
+	if err != nil {
+		return text + " " + err.Error()
+	}
+	var fields []string
+	for _, field := range diff {
+		fields = append(fields, fmt.Sprintf("%s %s → %s", field.Field, field.Before, field.After))
+	}
+	return text + " " + strings.Join(fields, ", ")
+}
+
+// saveAdminAction записывает действие администратора в журнал
+func saveAdminAction(adminID int, command string, arguments string, result string) {
+	action := models.AdminAction{
+		AdminID:   adminID,
+		Command:   command,
+		Arguments: arguments,
+		Result:    result,
+		Date:      time.Now(),
+	}
+	err := store.SaveAdminAction(action)
+	if err != nil {
+		log.Println("не записали действие администратора: " + err.Error())
+	}
+}
+
+func adminCommand(adminID int, command string, arguments string, lang string) (string, error) {
+	args := strings.Fields(arguments)
+	switch command {
+	case "ban", "unban":
+		if len(args) != 2 || (args[0] != models.BanUser && args[0] != models.BanAuthor) {
+			return "", errors.New(i18n.T(lang, "admin.usage.ban", command))
+		}
+		kind, target := args[0], strings.ToLower(strings.TrimPrefix(args[1], "@"))
+		if kind == models.BanUser {
+			userID, err := resolveUserID(target, lang)
+			if err != nil {
+				return "", err
+			}
+			target = strconv.Itoa(userID)
+		}
+		if command == "unban" {
+			deleted, err := store.DeleteBan(kind, target, models.AdminActor(adminID))
+			if err != nil {
+				return "", err
+			}
+			if !deleted {
+				return i18n.T(lang, "admin.not_banned", kind, target), nil
+			}
+			return i18n.T(lang, "admin.unbanned", kind, target), nil
+		}
+		ban := models.Ban{Kind: kind, Target: target, AdminID: adminID, Date: time.Now()}
+		err := store.SaveBan(ban, models.AdminActor(adminID))
+		if err != nil {
+			return "", err
+		}
+		return i18n.T(lang, "admin.banned", kind, target), nil
+	case "close", "reopen", "forcevote":
+		vote, err := voteArgument(args, lang)
+		if err != nil {
+			return "", err
+		}
+		var result string
+		switch command {
+		case "close":
+			vote.Completed = true
+			vote.Closed = true
+			result = "admin.closed"
+		case "reopen":
+			// бот не должен голосовать за пост второй раз
+			if store.GetCastsCountForVoteID(vote.VoteID) > 0 {
+				return "", errors.New(i18n.T(lang, "admin.vote_cast", vote.VoteID))
+			}
+			vote.Completed = false
+			vote.Rejected = false
+			vote.Addled = false
+			vote.Plagiarism = false
+			vote.Closed = false
+			result = "admin.reopened"
+		case "forcevote":
+			if vote.Completed {
+				return "", errors.New(i18n.T(lang, "admin.vote_completed", vote.VoteID))
+			}
+			if store.IsBanned(models.BanAuthor, vote.Author) {
+				return "", errors.New(i18n.T(lang, "admin.author_banned", vote.Author))
+			}
+			// голосование закрывается до голосования аккаунтов, чтобы повтор команды не проголосовал ещё раз
+			vote.Completed = true
+			_, err = store.SaveVote(vote, models.AdminActor(adminID))
+			if err != nil {
+				return "", err
+			}
+			go voteForPost(vote, models.AdminActor(adminID))
+			return i18n.T(lang, "admin.forcevote", vote.Author, vote.Permalink), nil
+		}
+		_, err = store.SaveVote(vote, models.AdminActor(adminID))
+		if err != nil {
+			return "", err
+		}
+		return i18n.T(lang, result, vote.VoteID, vote.Author, vote.Permalink), nil
+	case "broadcast":
+		if len(args) == 0 {
+			return "", errors.New(i18n.T(lang, "admin.usage.broadcast"))
+		}
+		chatIDs, err := store.GetAllChatIDs()
+		if err != nil {
+			return "", err
+		}
+		// чат, которому не удалось поставить сообщение в очередь, не должен прерывать рассылку остальным
+		var sent, failed int
+		for _, chatID := range chatIDs {
+			err = outbox.Send(tgbotapi.NewMessage(chatID, arguments))
+			if err != nil {
+				log.Printf("Не поставили рассылку в очередь для чата %d: %s", chatID, err.Error())
+				failed++
+				continue
+			}
+			sent++
+		}
+		return i18n.T(lang, "admin.broadcast", sent, failed), nil
+	case "curators":
+		userIDs, err := store.GetAllActiveCuratorsID()
+		if err != nil {
+			return "", err
+		}
+		since := time.Now().AddDate(0, -1, 0)
+		text := i18n.T(lang, "admin.curators", len(userIDs))
+		for _, userID := range userIDs {
+			credential, err := store.GetCredentialByUserID(userID)
+			if err != nil {
+				return "", err
+			}
+			text += i18n.T(lang, "admin.curator", credential.UserName, userID,
+				store.GetNumResponsesForMotivationForUserID(userID, since))
+		}
+		return text, nil
+	case "queue":
+		votes, err := store.GetAllOpenedVotes()
+		if err != nil {
+			return "", err
+		}
+		if len(votes) == 0 {
+			return i18n.T(lang, "admin.queue_empty"), nil
+		}
+		text := i18n.T(lang, "admin.queue", len(votes))
+		for _, vote := range votes {
+			positives, negatives := store.GetNumResponsesVoteID(vote.VoteID)
+			text += i18n.T(lang, "admin.queue_line", vote.VoteID, vote.Author, vote.Permalink,
+				positives, negatives, vote.UserID, vote.Date.Format("02.01 15:04"))
+		}
+		return text, nil
+	case "user":
+		if len(args) != 1 {
+			return "", errors.New(i18n.T(lang, "admin.usage.user"))
+		}
+		userID, err := resolveUserID(strings.ToLower(strings.TrimPrefix(args[0], "@")), lang)
+		if err != nil {
+			return "", err
+		}
+		credentials, err := store.GetCredentialsByUserID(userID)
+		if err != nil {
+			return "", err
+		}
+		trust, err := store.GetTrustByUserID(userID)
+		if err != nil {
+			return "", err
+		}
+		text := i18n.T(lang, "admin.user", userID, fmt.Sprintf("%.2f", trust.Score),
+			store.GetOpenedVotesCountForUserID(userID),
+			store.IsBanned(models.BanUser, strconv.Itoa(userID)))
+		for _, credential := range credentials {
+			text += i18n.T(lang, "admin.user_account", credential.UserName,
+				credential.ChatID, credential.Power, credential.Active, credential.Curates)
+		}
+		return text, nil
+	case "removals":
+		removals, err := store.GetLastRemovals(20)
+		if err != nil {
+			return "", err
+		}
+		if len(removals) == 0 {
+			return i18n.T(lang, "admin.removals_empty"), nil
+		}
+		text := i18n.T(lang, "admin.removals")
+		for _, removal := range removals {
+			text += i18n.T(lang, "admin.removal", removal.Date.Format("02.01 15:04"), removal.UserID, removal.Reason)
+		}
+		return text, nil
+	case "audit":
+		filter, err := auditFilter(args, lang)
+		if err != nil {
+			return "", err
+		}
+		changes, err := store.GetChanges(filter, auditLimit)
+		if err != nil {
+			return "", err
+		}
+		if len(changes) == 0 {
+			return i18n.T(lang, "admin.audit_empty"), nil
+		}
+		text := i18n.T(lang, "admin.audit")
+		for _, change := range changes {
+			text += changeText(change, lang) + "\n"
+		}
+		return text, nil
+	}
+	return "", errors.New(i18n.T(lang, "admin.unknown_command", command))
+}
+
+// resolveUserID находит пользователя Telegram по логину его аккаунта или по ID
+func resolveUserID(target string, lang string) (int, error) {
+	if userID, err := strconv.Atoi(target); err == nil {
+		return userID, nil
+	}
+	credential, err := store.GetCredentialByUserName(target)
+	if err == sql.ErrNoRows {
+		return 0, errors.New(i18n.T(lang, "admin.not_linked", target))
+	}
+	return credential.UserID, err
+}
+
+func voteArgument(args []string, lang string) (models.Vote, error) {
+	if len(args) != 1 {
+		return models.Vote{}, errors.New(i18n.T(lang, "admin.usage.vote"))
+	}
+	voteID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return models.Vote{}, err
+	}
+	vote := store.GetVote(voteID)
+	if vote.VoteID == 0 {
+		return vote, errors.New(i18n.T(lang, "admin.vote_not_found", voteID))
+	}
+	return vote, nil
+}
+
+func isAdmin(userID int) bool {
+	for _, admin := range config.Admins {
+		if admin == userID {
+			return true
+		}
+	}
+	return false
+}
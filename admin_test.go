@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestAuditFilter(t *testing.T) {
+	filter, err := auditFilter([]string{"credential", "@chiliec"}, "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Entity != "credential" || filter.EntityID != "chiliec" {
+		t.Errorf("неожиданный фильтр %#v", filter)
+	}
+	filter, err = auditFilter([]string{"user:2"}, "ru")
+	if err != nil || filter.Actor != models.UserActor(2).String() {
+		t.Errorf("неожиданный фильтр %#v: %v", filter, err)
+	}
+	if _, err = auditFilter([]string{"nothing"}, "ru"); err == nil {
+		t.Error("неизвестная сущность должна давать подсказку")
+	}
+}
+
+func TestChangeText(t *testing.T) {
+	change := models.Change{Actor: "user:2", Entity: "credential", EntityID: "chiliec", After: "{}", Date: time.Now()}
+	text := changeText(change, "en")
+	if !strings.HasSuffix(text, i18n.T("en", "admin.change_created")) {
+		t.Errorf("неожиданное описание %q", text)
+	}
+}
+
+func TestResolveUserID(t *testing.T) {
+	setUp(t)
+	err := store.SaveCredential(models.Credential{UserID: 2, UserName: "chiliec", Active: true}, models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := resolveUserID("chiliec", "ru"); err != nil || userID != 2 {
+		t.Errorf("пользователь %d: %v", userID, err)
+	}
+	if userID, err := resolveUserID("7", "ru"); err != nil || userID != 7 {
+		t.Errorf("пользователь %d: %v", userID, err)
+	}
+	if _, err := resolveUserID("worthless", "ru"); err == nil || err.Error() != i18n.T("ru", "admin.not_linked", "worthless") {
+		t.Errorf("неожиданная ошибка %v", err)
+	}
+}
+
+func TestVoteArgument(t *testing.T) {
+	setUp(t)
+	voteID, err := store.SaveVote(models.Vote{UserID: 2, Author: "chiliec", Permalink: "post", Date: time.Now()}, models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote, err := voteArgument([]string{strconv.FormatInt(voteID, 10)}, "ru")
+	if err != nil || vote.Author != "chiliec" {
+		t.Errorf("неожиданное голосование %#v: %v", vote, err)
+	}
+	if _, err = voteArgument([]string{"100500"}, "ru"); err == nil {
+		t.Error("несуществующее голосование должно давать ошибку")
+	}
+	if _, err = voteArgument(nil, "ru"); err == nil {
+		t.Error("без номера голосования нужна подсказка")
+	}
+}
+
+func TestCloseAndReopen(t *testing.T) {
+	setUp(t)
+	voteID, err := store.SaveVote(models.Vote{UserID: 2, Author: "chiliec", Permalink: "post", Plagiarism: true, Date: time.Now()},
+		models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatInt(voteID, 10)
+	if _, err = adminCommand(1, "close", id, "ru"); err != nil {
+		t.Fatal(err)
+	}
+	if vote := store.GetVote(voteID); !vote.Completed || !vote.Closed {
+		t.Errorf("голосование не закрыто: %#v", vote)
+	}
+	if _, err = adminCommand(1, "reopen", id, "ru"); err != nil {
+		t.Fatal(err)
+	}
+	if status := store.GetVote(voteID).Status(); status != models.VoteOpen {
+		t.Errorf("снова открытое голосование в состоянии %s", status)
+	}
+	err = store.SaveCast(models.Cast{VoteID: voteID, UserName: "chiliec", Author: "chiliec", Permalink: "post", Date: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adminCommand(1, "reopen", id, "ru"); err == nil {
+		t.Error("голосование, за пост которого уже голосовали, открывать нельзя")
+	}
+}
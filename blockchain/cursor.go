@@ -0,0 +1,31 @@
+package blockchain
+
+import (
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/storage"
+)
+
+// Cursor запоминает последний обработанный блок, чтобы после перезапуска продолжить с него
+type Cursor interface {
+	Load() (uint32, error)
+	Save(blockNum uint32) error
+}
+
+type databaseCursor struct {
+	name    string
+	cursors storage.Cursors
+}
+
+// NewDatabaseCursor создаёт курсор, хранящийся в таблице cursors хранилища бота под именем name
+func NewDatabaseCursor(name string, cursors storage.Cursors) Cursor {
+	return databaseCursor{name: name, cursors: cursors}
+}
+
+func (cursor databaseCursor) Load() (uint32, error) {
+	stored, err := cursor.cursors.GetCursor(cursor.name)
+	return stored.BlockNum, err
+}
+
+func (cursor databaseCursor) Save(blockNum uint32) error {
+	return cursor.cursors.SaveCursor(models.Cursor{Name: cursor.name, BlockNum: blockNum})
+}
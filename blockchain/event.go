@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/asuleymanov/golos-go/types"
+)
+
+// Event — операция из необратимого блока вместе с её местом в цепочке
+type Event struct {
+	BlockNum      uint32
+	TransactionID string
+	Timestamp     time.Time
+	Type          types.OpType
+	Operation     types.Operation
+}
+
+// Handler получает события, на которые подписан
+type Handler func(event Event)
+
+func (event Event) AccountUpdate() (*types.AccountUpdateOperation, bool) {
+	op, ok := event.Operation.(*types.AccountUpdateOperation)
+	return op, ok
+}
+
+func (event Event) Vote() (*types.VoteOperation, bool) {
+	op, ok := event.Operation.(*types.VoteOperation)
+	return op, ok
+}
+
+func (event Event) Transfer() (*types.TransferOperation, bool) {
+	op, ok := event.Operation.(*types.TransferOperation)
+	return op, ok
+}
+
+func (event Event) Comment() (*types.CommentOperation, bool) {
+	op, ok := event.Operation.(*types.CommentOperation)
+	return op, ok
+}
+
+func (event Event) CurationReward() (*types.CurationRewardOperation, bool) {
+	op, ok := event.Operation.(*types.CurationRewardOperation)
+	return op, ok
+}
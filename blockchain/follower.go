@@ -0,0 +1,131 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/asuleymanov/golos-go/apis/database"
+	"github.com/asuleymanov/golos-go/types"
+)
+
+// Source — откуда берутся блоки, ему удовлетворяет *database.API
+type Source interface {
+	GetDynamicGlobalProperties() (*database.DynamicGlobalProperties, error)
+	GetBlock(blockNum uint32) (*database.Block, error)
+	GetOpsInBlock(blockNum uint32, onlyVirtual bool) ([]*types.OperationObject, error)
+}
+
+// Follower идёт по необратимым блокам и раздаёт их операции подписчикам.
+// Необратимые блоки уже не могут быть отменены форком, поэтому повторно
+// обрабатывать события после реорганизации цепочки не придётся.
+type Follower struct {
+	Source   Source
+	Cursor   Cursor
+	Interval time.Duration
+	handlers map[types.OpType][]Handler
+}
+
+func NewFollower(source Source, cursor Cursor) *Follower {
+	return &Follower{
+		Source:   source,
+		Cursor:   cursor,
+		Interval: 3 * time.Second,
+		handlers: make(map[types.OpType][]Handler),
+	}
+}
+
+// Subscribe добавляет обработчик операций заданного типа
+func (follower *Follower) Subscribe(opType types.OpType, handler Handler) {
+	follower.handlers[opType] = append(follower.handlers[opType], handler)
+}
+
+// Run обрабатывает блоки, пока не случится ошибка
+func (follower *Follower) Run() error {
+	for {
+		processed, err := follower.Step()
+		if err != nil {
+			return err
+		}
+		if processed == 0 {
+			time.Sleep(follower.Interval)
+		}
+	}
+}
+
+// Step обрабатывает все необратимые блоки после курсора и возвращает их количество.
+// Курсор сдвигается после каждого блока, так что прерванная обработка продолжится
+// со следующего за последним обработанным блока.
+func (follower *Follower) Step() (int, error) {
+	properties, err := follower.Source.GetDynamicGlobalProperties()
+	if err != nil {
+		return 0, err
+	}
+	irreversible := properties.LastIrreversibleBlockNum
+	if irreversible == 0 {
+		return 0, nil
+	}
+	last, err := follower.Cursor.Load()
+	if err != nil {
+		return 0, err
+	}
+	if last == 0 {
+		// первый запуск: начинаем с текущего необратимого блока, а не с генезиса
+		last = irreversible - 1
+	}
+	processed := 0
+	for blockNum := last + 1; blockNum <= irreversible; blockNum++ {
+		err = follower.processBlock(blockNum)
+		if err != nil {
+			return processed, err
+		}
+		err = follower.Cursor.Save(blockNum)
+		if err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (follower *Follower) processBlock(blockNum uint32) error {
+	block, err := follower.Source.GetBlock(blockNum)
+	if err != nil {
+		return err
+	}
+	if block == nil || block.Timestamp == nil || block.Timestamp.Time == nil {
+		return errors.New(fmt.Sprintf("блок %d не найден", blockNum))
+	}
+	operations, err := follower.Source.GetOpsInBlock(blockNum, false)
+	if err != nil {
+		return err
+	}
+	for _, operation := range operations {
+		if operation == nil || operation.Operation == nil {
+			continue
+		}
+		event := Event{
+			BlockNum:      blockNum,
+			TransactionID: operation.TransactionID,
+			Timestamp:     *block.Timestamp.Time,
+			Type:          operation.OperationType,
+			Operation:     operation.Operation,
+		}
+		follower.dispatch(event)
+	}
+	return nil
+}
+
+func (follower *Follower) dispatch(event Event) {
+	for _, handler := range follower.handlers[event.Type] {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Обработчик события %s в блоке %d упал: %v", event.Type, event.BlockNum, r)
+				}
+			}()
+			handler(event)
+		}()
+	}
+}
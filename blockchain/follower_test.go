@@ -0,0 +1,128 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asuleymanov/golos-go/apis/database"
+	"github.com/asuleymanov/golos-go/types"
+)
+
+type fakeSource struct {
+	irreversible uint32
+	operations   map[uint32][]*types.OperationObject
+}
+
+func (source *fakeSource) GetDynamicGlobalProperties() (*database.DynamicGlobalProperties, error) {
+	return &database.DynamicGlobalProperties{LastIrreversibleBlockNum: source.irreversible}, nil
+}
+
+func (source *fakeSource) GetBlock(blockNum uint32) (*database.Block, error) {
+	timestamp := time.Unix(int64(blockNum)*3, 0)
+	return &database.Block{Number: blockNum, Timestamp: &types.Time{Time: &timestamp}}, nil
+}
+
+func (source *fakeSource) GetOpsInBlock(blockNum uint32, onlyVirtual bool) ([]*types.OperationObject, error) {
+	return source.operations[blockNum], nil
+}
+
+type memoryCursor struct {
+	blockNum uint32
+}
+
+func (cursor *memoryCursor) Load() (uint32, error) {
+	return cursor.blockNum, nil
+}
+
+func (cursor *memoryCursor) Save(blockNum uint32) error {
+	cursor.blockNum = blockNum
+	return nil
+}
+
+func voteObject(voter string) *types.OperationObject {
+	return &types.OperationObject{
+		OperationType: types.TypeVote,
+		Operation:     &types.VoteOperation{Voter: voter, Author: "chiliec", Permlink: "test"},
+	}
+}
+
+func TestFollower_Step(t *testing.T) {
+	source := &fakeSource{
+		irreversible: 10,
+		operations: map[uint32][]*types.OperationObject{
+			10: {voteObject("first")},
+			11: {voteObject("second"), {
+				OperationType: types.TypeTransfer,
+				Operation:     &types.TransferOperation{From: "chiliec", To: "golosovalochka"},
+			}},
+			12: {voteObject("third")},
+		},
+	}
+	cursor := &memoryCursor{}
+	follower := NewFollower(source, cursor)
+	var voters []string
+	var transfers int
+	follower.Subscribe(types.TypeVote, func(event Event) {
+		vote, ok := event.Vote()
+		if !ok {
+			t.Fatal("событие не является голосом")
+		}
+		voters = append(voters, vote.Voter)
+	})
+	follower.Subscribe(types.TypeTransfer, func(event Event) {
+		if _, ok := event.Transfer(); ok {
+			transfers++
+		}
+	})
+
+	processed, err := follower.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 1 || cursor.blockNum != 10 {
+		t.Errorf("первый запуск должен начинаться с необратимого блока: %d, %d", processed, cursor.blockNum)
+	}
+
+	source.irreversible = 12
+	processed, err = follower.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 2 || cursor.blockNum != 12 {
+		t.Errorf("неожиданный результат: %d, %d", processed, cursor.blockNum)
+	}
+	if len(voters) != 3 || voters[2] != "third" || transfers != 1 {
+		t.Errorf("неожиданные события: %v, %d", voters, transfers)
+	}
+
+	processed, err = follower.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 0 {
+		t.Error("не должно быть новых блоков")
+	}
+}
+
+func TestFollower_PanicInHandler(t *testing.T) {
+	source := &fakeSource{
+		irreversible: 5,
+		operations:   map[uint32][]*types.OperationObject{5: {voteObject("first")}},
+	}
+	cursor := &memoryCursor{blockNum: 4}
+	follower := NewFollower(source, cursor)
+	called := false
+	follower.Subscribe(types.TypeVote, func(event Event) {
+		panic("упали")
+	})
+	follower.Subscribe(types.TypeVote, func(event Event) {
+		called = true
+	})
+	_, err := follower.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called || cursor.blockNum != 5 {
+		t.Error("паника в обработчике не должна останавливать обработку")
+	}
+}
@@ -6,31 +6,53 @@ import (
 )
 
 type Config struct {
-	DebugMode                bool     `json:"debug_mode"`
-	TelegramToken            string   `json:"telegram_token"`
-	TelegramBotName          string   `json:"telegram_bot_name"`
-	Account                  string   `json:"account"`
-	PostingKey               string   `json:"posting_key"`
-	ActiveKey                string   `json:"active_key"`
-	TextRuToken              string   `json:"text_ru_token"`
-	ReferralFee              float32  `json:"referral_fee"`
-	ReferralMinimumPostCount int      `json:"referral_minimum_post_count"`
-	MaximumOpenedVotes       int      `json:"maximum_opened_votes"`
-	PostingInterval          int      `json:"posting_interval"`
-	MinimumPostLength        int      `json:"minimum_post_length"`
-	Developer                string   `json:"developer"`
-	GroupID                  int64    `json:"group_id"`
-	GroupLink                string   `json:"group_link"`
-	DatabasePath             string   `json:"database_path"`
-	Domains                  []string `json:"domains"`
-	Chain                    string   `json:"chain"`
-	Rpc                      []string `json:"rpc"`
-	Repository               string   `json:"repository"`
-	IgnoreVP                 bool     `json:"ignore_vp"`
-	BannedTags               []string `json:"banned_tags"`
-	Censorship               bool     `json:"censorship"`
-	ReportTags               []string `json:"report_tags"`
-	CurationRules            string   `json:"curation_rules"`
+	DebugMode                bool           `json:"debug_mode"`
+	TelegramToken            string         `json:"telegram_token"`
+	TelegramBotName          string         `json:"telegram_bot_name"`
+	WebhookURL               string         `json:"webhook_url"`
+	WebhookListen            string         `json:"webhook_listen"`
+	WebhookSecret            string         `json:"webhook_secret"`
+	WebhookCert              string         `json:"webhook_cert"`
+	WebhookKey               string         `json:"webhook_key"`
+	Account                  string         `json:"account"`
+	PostingKey               string         `json:"posting_key"`
+	ActiveKey                string         `json:"active_key"`
+	TextRuToken              string         `json:"text_ru_token"`
+	ReferralFee              float32        `json:"referral_fee"`
+	ReferralMinimumPostCount int            `json:"referral_minimum_post_count"`
+	ReferralDeadlineDays     int            `json:"referral_deadline_days"`
+	ReferralMinimumAgeDays   int            `json:"referral_minimum_age_days"`
+	ReferralMonthlyCap       int            `json:"referral_monthly_cap"`
+	ReferralSecret           string         `json:"referral_secret"`
+	ReferralLinkDays         int            `json:"referral_link_days"`
+	MaximumOpenedVotes       int            `json:"maximum_opened_votes"`
+	PostingInterval          int            `json:"posting_interval"`
+	MaximumUserOpenedVotes   int            `json:"maximum_user_opened_votes"`
+	TrustedScore             float64        `json:"trusted_score"`
+	MinimumVoteValue         float64        `json:"minimum_vote_value"`
+	MinimumPostLength        int            `json:"minimum_post_length"`
+	Developer                string         `json:"developer"`
+	Admins                   []int          `json:"admins"`
+	GroupID                  int64          `json:"group_id"`
+	GroupLink                string         `json:"group_link"`
+	GroupGraceMinutes        int            `json:"group_grace_minutes"`
+	GroupAllowlist           []int          `json:"group_allowlist"`
+	DatabasePath             string         `json:"database_path"`
+	DatabaseDriver           string         `json:"database_driver"`
+	DatabaseURL              string         `json:"database_url"`
+	Domains                  []string       `json:"domains"`
+	Chain                    string         `json:"chain"`
+	Rpc                      []string       `json:"rpc"`
+	Repository               string         `json:"repository"`
+	TemplatesDir             string         `json:"templates_dir"`
+	IgnoreVP                 bool           `json:"ignore_vp"`
+	BannedTags               []string       `json:"banned_tags"`
+	AllowedTags              []string       `json:"allowed_tags"`
+	TagQuotas                map[string]int `json:"tag_quotas"`
+	Censorship               bool           `json:"censorship"`
+	ShowSubmitter            bool           `json:"show_submitter"`
+	ReportTags               []string       `json:"report_tags"`
+	CurationRules            string         `json:"curation_rules"`
 }
 
 func LoadConfiguration(file string, config *Config) error {
@@ -15,26 +15,48 @@ func TestLoadConfiguration(t *testing.T) {
 		DebugMode:                false,
 		TelegramToken:            "write-your-telegram-token-here",
 		TelegramBotName:          "golosovalochka_bot",
+		WebhookURL:               "",
+		WebhookListen:            ":8443",
+		WebhookSecret:            "",
+		WebhookCert:              "",
+		WebhookKey:               "",
 		Account:                  "golosovalochka",
 		PostingKey:               "5...",
 		ActiveKey:                "5...",
 		TextRuToken:              "",
 		ReferralFee:              5.0,
 		ReferralMinimumPostCount: 30,
+		ReferralDeadlineDays:     30,
+		ReferralMinimumAgeDays:   7,
+		ReferralMonthlyCap:       5,
+		ReferralSecret:           "",
+		ReferralLinkDays:         90,
 		MaximumOpenedVotes:       3,
 		PostingInterval:          480,
+		MaximumUserOpenedVotes:   2,
+		TrustedScore:             0.9,
+		MinimumVoteValue:         0.01,
 		MinimumPostLength:        1000,
 		Developer:                "@babin",
+		Admins:                   []int{},
 		GroupID:                  -1001143551951,
 		GroupLink:                "https://t.me/joinchat/AlKeQUQpN8-9oShtaTcY7Q",
+		GroupGraceMinutes:        60,
+		GroupAllowlist:           []int{},
 		DatabasePath:             "./db/database.db",
+		DatabaseDriver:           "sqlite3",
+		DatabaseURL:              "",
 		Domains:                  []string{"golos.io", "golos.blog", "goldvoice.club", "golosd.com", "golosdb.com", "mapala.net", "newbie.goloses.ru", "cpeda.space"},
 		Chain:                    "golos",
 		Rpc:                      []string{"wss://ws.golos.io", "wss://api.golos.cf"},
 		Repository:               "https://github.com/GolosTools/golos-vote-bot",
+		TemplatesDir:             "",
 		IgnoreVP:                 true,
 		BannedTags:               []string{"test", "test1"},
+		AllowedTags:              []string{},
+		TagQuotas:                map[string]int{},
 		Censorship:               false,
+		ShowSubmitter:            false,
 		ReportTags:               []string{"тест", "тест1"},
 		CurationRules:            "Правила курирования. Здесь нужно написать описание правил курирования!",
 	}
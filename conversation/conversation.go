@@ -0,0 +1,99 @@
+// Package conversation описывает диалоги с пользователем как конечный автомат:
+// в каком состоянии бот ждёт ответа, куда переходит и когда перестаёт ждать
+package conversation
+
+import (
+	"fmt"
+	"time"
+)
+
+// State — чего бот ждёт от пользователя
+type State string
+
+const (
+	Idle          State = ""
+	AwaitingLogin State = "awaiting_login"
+	AwaitingClaim State = "awaiting_claim"
+	AwaitingPower State = "awaiting_power"
+)
+
+// Event — то, что произошло в диалоге
+type Event string
+
+const (
+	// AddKey — пользователь собирается привязать аккаунт
+	AddKey Event = "add_key"
+	// Claim — аккаунт найден, ждём подтверждения владения
+	Claim Event = "claim"
+	// Linked — аккаунт привязан
+	Linked Event = "linked"
+	// SetPower — пользователь выбрал аккаунт для настройки силы, Payload — его логин
+	SetPower Event = "set_power"
+	// PowerUpdated — настройка закончена
+	PowerUpdated Event = "power_updated"
+	// Cancel прерывает диалог из любого состояния
+	Cancel Event = "cancel"
+)
+
+// Timeouts — сколько бот ждёт ответа в каждом состоянии
+var Timeouts = map[State]time.Duration{
+	AwaitingLogin: 15 * time.Minute,
+	AwaitingClaim: time.Hour,
+	AwaitingPower: 15 * time.Minute,
+}
+
+var transitions = map[State]map[Event]State{
+	Idle: {
+		AddKey:   AwaitingLogin,
+		SetPower: AwaitingPower,
+	},
+	AwaitingLogin: {
+		AddKey:   AwaitingLogin,
+		Claim:    AwaitingClaim,
+		Linked:   Idle,
+		SetPower: AwaitingPower,
+	},
+	AwaitingClaim: {
+		AddKey:   AwaitingLogin,
+		Linked:   Idle,
+		SetPower: AwaitingPower,
+	},
+	AwaitingPower: {
+		AddKey:       AwaitingLogin,
+		SetPower:     AwaitingPower,
+		PowerUpdated: Idle,
+	},
+}
+
+// Conversation — диалог с одним пользователем
+type Conversation struct {
+	State   State
+	Payload string
+	Expires time.Time
+}
+
+// Current возвращает состояние диалога. Просроченный диалог считается завершённым,
+// чтобы давно забытое ожидание логина не перехватило следующее сообщение
+func (conversation Conversation) Current(now time.Time) State {
+	if conversation.State != Idle && !now.Before(conversation.Expires) {
+		return Idle
+	}
+	return conversation.State
+}
+
+// Fire переводит диалог в следующее состояние. Payload сохраняется до выхода из него
+func (conversation Conversation) Fire(event Event, payload string, now time.Time) (Conversation, error) {
+	current := conversation.Current(now)
+	next := Idle
+	if event != Cancel {
+		var ok bool
+		next, ok = transitions[current][event]
+		if !ok {
+			return conversation, fmt.Errorf("событие %s невозможно в состоянии %q", event, current)
+		}
+	}
+	if next == Idle {
+		return Conversation{}, nil
+	}
+	return Conversation{State: next, Payload: payload, Expires: now.Add(Timeouts[next])}, nil
+}
@@ -0,0 +1,71 @@
+package conversation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFire(t *testing.T) {
+	now := time.Now()
+	conversation, err := Conversation{}.Fire(SetPower, "chiliec", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversation.Current(now) != AwaitingPower || conversation.Payload != "chiliec" {
+		t.Fatalf("ожидали настройку силы для chiliec, получили %+v", conversation)
+	}
+	conversation, err = conversation.Fire(PowerUpdated, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversation != (Conversation{}) {
+		t.Errorf("после настройки диалог должен завершиться, получили %+v", conversation)
+	}
+}
+
+func TestFire_Forbidden(t *testing.T) {
+	now := time.Now()
+	conversation, err := Conversation{}.Fire(AddKey, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := conversation.Fire(PowerUpdated, "", now)
+	if err == nil {
+		t.Fatal("настройку силы нельзя закончить, не начав")
+	}
+	if next != conversation {
+		t.Error("недопустимое событие не должно менять диалог")
+	}
+}
+
+func TestFire_Cancel(t *testing.T) {
+	now := time.Now()
+	for _, state := range []State{Idle, AwaitingLogin, AwaitingClaim, AwaitingPower} {
+		conversation := Conversation{State: state, Payload: "chiliec", Expires: now.Add(time.Minute)}
+		conversation, err := conversation.Fire(Cancel, "", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conversation.Current(now) != Idle || conversation.Payload != "" {
+			t.Errorf("отмена из состояния %q должна завершать диалог", state)
+		}
+	}
+}
+
+func TestCurrent_Expired(t *testing.T) {
+	now := time.Now()
+	conversation, err := Conversation{}.Fire(AddKey, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversation.Current(now.Add(Timeouts[AwaitingLogin]-time.Second)) != AwaitingLogin {
+		t.Error("до истечения срока бот должен ждать логин")
+	}
+	expired := now.Add(Timeouts[AwaitingLogin])
+	if conversation.Current(expired) != Idle {
+		t.Error("просроченное ожидание логина не должно перехватывать сообщения")
+	}
+	if _, err = conversation.Fire(Claim, "", expired); err == nil {
+		t.Error("из просроченного диалога нельзя перейти к подтверждению")
+	}
+}
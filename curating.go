@@ -0,0 +1,647 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	golosClient "github.com/asuleymanov/golos-go/client"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/grokify/html-strip-tags-go"
+
+	"github.com/GolosTools/golos-vote-bot/helpers"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/router"
+)
+
+func handlePostLink(ctx *router.Context, domainRegexp *regexp.Regexp) error {
+	update, userID, chatID, lang := ctx.Update, ctx.UserID, ctx.ChatID, ctx.Lang
+	msg := tgbotapi.NewMessage(chatID, "")
+	msg.ReplyToMessageID = update.Message.MessageID
+
+	matched := domainRegexp.FindStringSubmatch(update.Message.Text)
+	author, permalink := matched[1], matched[2]
+
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+	post, err := golos.Rpc.Database.GetContent(author, permalink)
+	if err != nil {
+		return err
+	}
+	// check post exists in blockchain
+	if post.Author != author || post.Permlink != permalink {
+		return nil
+	}
+
+	trust, err := refreshTrust(userID, golos)
+	if err != nil {
+		return err
+	}
+
+	lastVote := store.GetLastVoteForUserID(userID)
+	if time.Since(lastVote.Date) < trust.Interval(config.PostingInterval) && !config.DebugMode {
+		msg.Text = i18n.T(lang, "post.too_early")
+		return reply(ctx, msg)
+	}
+
+	tags := helpers.NormalizeTags(append([]string{post.Category}, post.JsonMetadata.Tags...))
+	if config.Censorship {
+		if bannedTag, found := helpers.FindTag(tags, config.BannedTags); found {
+			msg.Text = i18n.Markdown(lang, "post.banned_tag", bannedTag)
+			return reply(ctx, msg)
+		}
+		if len(config.AllowedTags) > 0 {
+			if _, found := helpers.FindTag(tags, config.AllowedTags); !found {
+				msg.Text = i18n.Markdown(lang, "post.allowed_tags", strings.Join(config.AllowedTags, ", "))
+				return reply(ctx, msg)
+			}
+		}
+		if quotedTag, exceeded := exceededTagQuota(tags); exceeded {
+			msg.Text = i18n.Markdown(lang, "post.tag_quota", quotedTag)
+			return reply(ctx, msg)
+		}
+	}
+
+	if store.IsBanned(models.BanUser, strconv.Itoa(userID)) {
+		msg.Text = i18n.T(lang, "post.banned_user")
+		return reply(ctx, msg)
+	}
+	if store.IsBanned(models.BanAuthor, author) {
+		msg.Text = i18n.T(lang, "post.banned_author")
+		return reply(ctx, msg)
+	}
+
+	isActive := store.IsActiveCredential(userID)
+	if !isActive {
+		msg.Text = i18n.T(lang, "post.not_delegator")
+		return reply(ctx, msg)
+	}
+
+	if post.Mode != "first_payout" {
+		msg.Text = i18n.T(lang, "post.paid_out")
+		return reply(ctx, msg)
+	}
+
+	if post.MaxAcceptedPayout == "0.000 GBG" {
+		msg.Text = i18n.T(lang, "post.payout_declined")
+		return reply(ctx, msg)
+	}
+
+	if store.GetOpenedVotesCount() >= config.MaximumOpenedVotes {
+		msg.Text = i18n.T(lang, "post.too_many_votes")
+		return reply(ctx, msg)
+	}
+
+	if config.MaximumUserOpenedVotes > 0 &&
+		store.GetOpenedVotesCountForUserID(userID) >= trust.MaximumOpenedVotes(config.MaximumUserOpenedVotes) {
+		msg.Text = i18n.T(lang, "post.too_many_user_votes")
+		return reply(ctx, msg)
+	}
+
+	if helpers.IsVoxPopuli(author) && config.IgnoreVP {
+		msg.Text = i18n.T(lang, "post.vox_populi")
+		return reply(ctx, msg)
+	}
+
+	if len(post.Body) < config.MinimumPostLength {
+		msg.Text = i18n.T(lang, "post.too_short")
+		return reply(ctx, msg)
+	}
+
+	percent := 100
+
+	voteModel := models.Vote{
+		UserID:    userID,
+		Author:    author,
+		Permalink: permalink,
+		Percent:   percent,
+		Completed: false,
+		Rejected:  false,
+		Addled:    false,
+		Date:      time.Now(),
+	}
+
+	if store.VoteExists(voteModel) {
+		msg.Text = i18n.T(lang, "post.duplicate")
+		return reply(ctx, msg)
+	}
+
+	voteID, err := store.SaveVote(voteModel, models.UserActor(ctx.UserID))
+	if err != nil {
+		return err
+	}
+	err = store.SaveVoteTags(voteID, tags)
+	if err != nil {
+		log.Println("не сохранили теги поста: " + err.Error())
+	}
+
+	log.Printf("Вкинули статью \"%s\" автора \"%s\" в чате %d", permalink, author, chatID)
+
+	msg.Text = i18n.T(lang, "post.accepted")
+
+	card := helpers.NewPostCard(post)
+	if config.ShowSubmitter {
+		card.Submitter = submitterName(userID)
+	}
+	if trust.SkipsUniqueness(config.TrustedScore) {
+		go newPost(voteID, card, chatID)
+	} else if uniqueness, ok := checkUniqueness(post.Body, voteModel); ok {
+		card.Uniqueness = uniqueness
+		go newPost(voteID, card, chatID)
+	}
+
+	return reply(ctx, msg)
+}
+
+func handleCurating(ctx *router.Context) error {
+	userID, chatID, lang := ctx.UserID, ctx.ChatID, ctx.Lang
+	msg := tgbotapi.NewEditMessageText(chatID, ctx.Callback().Message.MessageID, "")
+	switch ctx.Arg(0) {
+	case "start":
+		if store.IsActiveCurator(userID) {
+			bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "curating.already")))
+			return nil
+		}
+		credential, err := store.GetCredentialByUserID(userID)
+		if err == nil && credential.ChatID == 0 {
+			credential.ChatID = chatID
+			store.SaveCredential(credential, models.UserActor(userID))
+		}
+		msg.Text = config.CurationRules
+		approveButton := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.curating_approve"), "curating_approve")
+		declineButton := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.curating_decline"), "curating_decline")
+		keyboard := []tgbotapi.InlineKeyboardButton{approveButton, declineButton}
+		markup := tgbotapi.NewInlineKeyboardMarkup(keyboard)
+		msg.ReplyMarkup = &markup
+	case "approve":
+		err := store.ActivateCurator(userID, models.UserActor(userID))
+		if err != nil {
+			return err
+		}
+		msg.Text = i18n.T(lang, "curating.approved")
+	case "decline":
+		msg.Text = i18n.T(lang, "curating.declined")
+	case "stop":
+		if store.IsActiveCurator(userID) {
+			err := store.DeactivateCurator(userID, models.UserActor(userID))
+			if err != nil {
+				return nil
+			}
+			msg.Text = i18n.T(lang, "curating.stopped")
+		} else {
+			msg.Text = i18n.T(lang, "curating.not_curator")
+		}
+	default:
+		return errors.New("неподдерживаемое действие: " + ctx.Arg(0))
+	}
+	bot.Send(msg)
+	return nil
+}
+
+func handleVoteCallback(ctx *router.Context) error {
+	userID, chatID, lang := ctx.UserID, ctx.ChatID, ctx.Lang
+	voteID, err := strconv.ParseInt(router.CallbackPrefix(ctx.Callback().Data), 10, 64)
+	if err != nil {
+		return err
+	}
+	if !store.IsActiveCurator(userID) || store.IsBanned(models.BanUser, strconv.Itoa(userID)) {
+		config := tgbotapi.CallbackConfig{
+			CallbackQueryID: ctx.Callback().ID,
+			Text:            i18n.T(lang, "vote.not_curator"),
+		}
+		bot.AnswerCallbackQuery(config)
+		return nil
+	}
+
+	voteModel := store.GetVote(voteID)
+	if voteModel.Completed {
+		return nil
+	}
+
+	isGood := ctx.Arg(0) == "good"
+	response := models.Response{
+		UserID: userID,
+		VoteID: voteID,
+		Result: isGood,
+		Date:   time.Now(),
+	}
+	text := i18n.T(lang, "vote.repeated")
+	responseExists := store.ResponseExists(response)
+	if !responseExists {
+		text = i18n.T(lang, "vote.accepted")
+		messageID, err := helpers.GetMessageID(ctx.Update)
+		if err != nil {
+			return err
+		}
+		msg := tgbotapi.NewEditMessageText(chatID, messageID, "")
+		msg.Text = text
+		_, err = bot.Send(msg)
+		if err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	callbackConfig := tgbotapi.CallbackConfig{
+		CallbackQueryID: ctx.Callback().ID,
+		Text:            text,
+	}
+	bot.AnswerCallbackQuery(callbackConfig)
+
+	if !responseExists {
+		err := store.SaveResponse(response, models.UserActor(ctx.UserID))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceededTagQuota ищет среди тегов поста тот, для которого уже исчерпана квота открытых голосований
+func exceededTagQuota(tags []string) (string, bool) {
+	for quotedTag, quota := range config.TagQuotas {
+		tag := helpers.NormalizeTag(quotedTag)
+		if !helpers.Contains(tags, tag) {
+			continue
+		}
+		if store.GetOpenedVotesCountForTag(tag) >= quota {
+			return quotedTag, true
+		}
+	}
+	return "", false
+}
+
+// refreshTrust пересчитывает доверие к пользователю и сохраняет его, если оно изменилось
+func refreshTrust(userID int, golos *golosClient.Client) (models.Trust, error) {
+	trust, err := currentTrust(userID, golos)
+	if err != nil {
+		return trust, err
+	}
+	_, err = store.SaveTrust(trust, models.SystemActor("trust"))
+	return trust, err
+}
+
+// currentTrust считает доверие к пользователю, не сохраняя его, при необходимости узнавая возраст его аккаунта
+func currentTrust(userID int, golos *golosClient.Client) (models.Trust, error) {
+	trust, err := store.GetTrustByUserID(userID)
+	if err != nil {
+		return trust, err
+	}
+	accountCreated := trust.AccountCreated
+	if accountCreated.IsZero() {
+		credential, err := store.GetCredentialByUserID(userID)
+		if err == nil {
+			accounts, err := golos.Rpc.Database.GetAccounts([]string{credential.UserName})
+			if err != nil {
+				return trust, err
+			}
+			if len(accounts) == 1 && accounts[0].Created != nil {
+				accountCreated = *accounts[0].Created.Time
+			}
+		}
+	}
+	votes, err := store.GetLastVotesForUserID(userID, 10)
+	if err != nil {
+		return trust, err
+	}
+	return models.ComputeTrust(userID, votes, accountCreated, time.Now()), nil
+}
+
+// checkUniqueness проверяет текст на text.ru и возвращает процент уникальности,
+// если пост можно выставлять на голосование. https://text.ru/api-check/manual
+func checkUniqueness(text string, voteModel models.Vote) (string, bool) {
+	token := config.TextRuToken
+	if len(config.TextRuToken) == 0 {
+		return "", false
+	}
+
+	text = strip.StripTags(text)
+
+	if len(text) < config.MinimumPostLength {
+		return "", false
+	}
+
+	cut := func(text string, to int) string {
+		runes := []rune(text)
+		if len(runes) > to {
+			return string(runes[:to])
+		}
+		return text
+	}
+	maxSymbolCount := 2000
+	text = cut(text, maxSymbolCount)
+
+	httpClient := http.Client{}
+	form := url.Values{}
+	form.Add("text", text)
+	form.Add("userkey", token)
+	domainList := strings.Join(config.Domains, ",")
+	form.Add("exceptdomain", domainList)
+	form.Add("visible", "vis_on")
+	req, err := http.NewRequest("POST", "http://api.text.ru/post", strings.NewReader(form.Encode()))
+	if err != nil {
+		log.Println(err.Error())
+		return "", false
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Println(err.Error())
+		return "", false
+	}
+	if resp.StatusCode != 200 {
+		log.Println("статус не 200")
+		return "", false
+	}
+	type Uid struct {
+		TextUid string `json:"text_uid"`
+	}
+	var uid Uid
+	jsonParser := json.NewDecoder(resp.Body)
+	jsonParser.Decode(&uid)
+	if len(uid.TextUid) == 0 {
+		log.Println("Не распарсили text_uid")
+		return "", false
+	}
+	step := 0
+	for step < 50 {
+		step += 1
+		time.Sleep(time.Second * 15)
+		log.Printf("step %d", step)
+		client := http.Client{}
+		form := url.Values{}
+		form.Add("uid", uid.TextUid)
+		form.Add("userkey", token)
+		//form.Add("jsonvisible", "detail")
+		req, err := http.NewRequest("POST", "http://api.text.ru/post", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Println(err.Error())
+			return "", false
+		}
+		type Result struct {
+			TextUnique string `json:"text_unique"`
+			ResultJson string `json:"result_json"`
+		}
+		var result Result
+		jsonParser := json.NewDecoder(resp.Body)
+		jsonParser.Decode(&result)
+		if len(result.TextUnique) == 0 {
+			continue
+		}
+		textUnique, err := strconv.ParseFloat(result.TextUnique, 32)
+		if err != nil {
+			log.Println(err.Error())
+			return "", false
+		}
+		log.Println(textUnique)
+		if textUnique < 20 {
+			voteModel.Completed = true
+			voteModel.Plagiarism = true
+			_, err := store.SaveVote(voteModel, models.SystemActor("uniqueness"))
+			if err != nil {
+				log.Println(err.Error())
+				return "", false
+			}
+			return "", false
+		} else {
+			random := func(min, max int) int {
+				rand.Seed(time.Now().Unix())
+				return rand.Intn(max-min) + min
+			}
+			imageNumber := random(1, 18)
+			report := fmt.Sprintf("[![Уникальность проверена через TEXT.RU](https://text.ru/image/get/%s/%d)](https://text.ru/antiplagiat/%s)",
+				uid.TextUid, imageNumber, uid.TextUid)
+			err = helpers.SendComment(voteModel.Author, voteModel.Permalink, report, config)
+			if err != nil {
+				log.Println(err.Error())
+			}
+			return result.TextUnique, true
+		}
+	}
+	return "", false
+}
+
+// onChatBlocked перестаёт присылать посты куратору, который заблокировал бота
+func onChatBlocked(chatID int64) {
+	// у личного чата тот же ID, что и у пользователя
+	if chatID <= 0 || !store.IsActiveCurator(int(chatID)) {
+		return
+	}
+	err := store.DeactivateCurator(int(chatID), models.SystemActor("outbox"))
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	log.Printf("Пользователь %d заблокировал бота и больше не куратор", chatID)
+}
+
+// submitterName возвращает логин, под которым показывать кураторам предложившего пост
+func submitterName(userID int) string {
+	credentials, err := store.GetActiveCredentialsByUserID(userID)
+	if err != nil || len(credentials) == 0 {
+		return ""
+	}
+	return credentials[0].UserName
+}
+
+// postCardText — карточка нового поста для куратора
+func postCardText(card helpers.PostCard, lang string) string {
+	title := card.Title
+	if len(title) == 0 {
+		title = card.Permalink
+	}
+	uniqueness := ""
+	if len(card.Uniqueness) > 0 {
+		uniqueness = i18n.Markdown(lang, "post.card.uniqueness", card.Uniqueness)
+	}
+	submitter := i18n.T(lang, "post.card.anonymous")
+	if len(card.Submitter) > 0 {
+		submitter = i18n.Markdown(lang, "post.card.submitter", card.Submitter)
+	}
+	return i18n.Markdown(lang, "post.new", title, card.Author, card.Reputation, strings.Join(card.Tags, ", "),
+		card.Length, card.Images, card.Payout, card.Votes, i18n.Raw(uniqueness), i18n.Raw(submitter),
+		helpers.GetInstantViewLink(card.Author, card.Permalink))
+}
+
+func newPost(voteID int64, card helpers.PostCard, chatID int64) {
+	curatorChatIDs, err := store.GetAllActiveCuratorsChatID()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	for _, curatorChatID := range curatorChatIDs {
+		if curatorChatID == chatID || store.IsBanned(models.BanUser, strconv.FormatInt(curatorChatID, 10)) {
+			continue
+		}
+		// у кураторов личные чаты, поэтому ID чата совпадает с ID пользователя
+		lang := userLanguage(int(curatorChatID), nil)
+		msg := tgbotapi.NewMessage(curatorChatID, postCardText(card, lang))
+		msg.ParseMode = "Markdown"
+		markup := helpers.GetVoteMarkup(voteID, lang)
+		msg.ReplyMarkup = markup
+		msg.DisableWebPagePreview = false
+
+		err := outbox.Send(msg)
+		if err != nil {
+			log.Printf("Не смогли отправить сообщение куратору %d: %s", curatorChatID, err.Error())
+		}
+	}
+}
+
+func queueProcessor() {
+	for {
+		// TODO: вынести минуты в настройки
+		time.Sleep(36 * time.Minute)
+		log.Println("Начинаю голосование за лучший пост")
+		votes, err := store.GetAllOpenedVotes()
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		if len(votes) == 0 {
+			log.Println("Нет открытых голосований")
+			continue
+		}
+		maxDiff := 0
+		mostLikedPost := votes[0]
+		for _, vote := range votes {
+			var positives, negatives int
+			positives, negatives = store.GetNumResponsesVoteID(vote.VoteID)
+			diff := positives - negatives
+			if diff > maxDiff {
+				maxDiff = diff
+				mostLikedPost = vote
+			}
+		}
+		log.Printf("Лучший пост определен: %s/%s", mostLikedPost.Author, mostLikedPost.Permalink)
+		voteForPost(mostLikedPost, models.SystemActor("queue"))
+	}
+}
+
+// voteForPost голосует за пост всеми аккаунтами и сообщает об этом в группу
+func voteForPost(vote models.Vote, actor models.Actor) {
+	successVotesCount, err := helpers.Vote(vote, actor, store, config)
+	text := i18n.T(i18n.Default, "vote.success", successVotesCount,
+		helpers.GetInstantViewLink(vote.Author, vote.Permalink))
+	if err != nil {
+		log.Println(err.Error())
+		text = i18n.T(i18n.Default, "vote.error", config.Developer,
+			helpers.GetInstantViewLink(vote.Author, vote.Permalink))
+	}
+	err = outbox.Send(tgbotapi.NewMessage(config.GroupID, text))
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func freshnessPolice() {
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	votes, err := store.GetAllOpenedVotes()
+	log.Printf("Загружено %d постов для проверки", len(votes))
+	if err != nil {
+		log.Panic(err.Error())
+	}
+	for _, vote := range votes {
+		post, err := golos.Rpc.Database.GetContent(vote.Author, vote.Permalink)
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		if post.Mode != "first_payout" {
+			vote.Completed = true
+			vote.Addled = true
+			store.SaveVote(vote, models.SystemActor("freshness"))
+			go excuseUs(vote)
+		}
+	}
+	golos.Rpc.Close()
+	time.Sleep(1 * time.Hour)
+	freshnessPolice()
+}
+
+func excuseUs(vote models.Vote) {
+	positives, negatives := store.GetNumResponsesVoteID(vote.VoteID)
+	var msg tgbotapi.MessageConfig
+	if positives >= negatives {
+		text := i18n.T(i18n.Default, "vote.addled", vote.Author, vote.Author, vote.Permalink)
+		msg = tgbotapi.NewMessage(config.GroupID, text)
+	} else {
+		vote.Rejected = true
+		store.SaveVote(vote, models.SystemActor("freshness"))
+		text := i18n.T(i18n.Default, "vote.rejected", vote.Author, vote.Permalink)
+		msg = tgbotapi.NewMessage(config.GroupID, text)
+	}
+	err := outbox.Send(msg)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func supportedPostsReporter() {
+	time.Sleep(models.WannaSleepOneDay(12, 0)) // Спать до 12:00 следующего дня
+	for {
+		//supportedPosts, err:= store.GetTrulyCompletedVotesSince(store.GetLastReportDate())
+		//if err != nil {
+		//	log.Println(err)
+		//} else {
+		//Я понятия не имею, как постить пост
+		//err := golos.Post(config.Account, title, body, permlink, "", post_image string, config.ReportTags, v *PC_Vote, o *PC_Options)
+		//if err != nil {
+		//	log.Println(err)
+		//} else {
+		//	models.NewReportPosted(database)
+		//}
+
+		//}
+		time.Sleep(24 * time.Hour)
+	}
+}
+
+func curationMotivator() {
+	time.Sleep(models.WannaSleepTill(0, 20, 0)) // Спать до 20:00 ближайшего воскресенья
+	for {
+		lastRewardDate := store.GetLastRewardDate()
+		allResponses := store.GetNumResponsesForMotivation(lastRewardDate)
+		var needResponsesToBeRewarded int
+
+		golos := golosClient.NewApi(config.Rpc, config.Chain)
+		defer golos.Rpc.Close()
+		accounts, err := golos.Rpc.Database.GetAccounts([]string{config.Account})
+		if err != nil {
+			log.Println(err)
+		} else {
+			gold, _ := strconv.Atoi(strings.Replace(strings.Replace(accounts[0].SbdBalance, ".", "", 1), " GBG", "", 1))
+			if gold < allResponses {
+				needResponsesToBeRewarded = allResponses / gold
+			} else {
+				needResponsesToBeRewarded = 1
+			}
+			curatorIDs, err := store.GetUserIDsForMotivation(lastRewardDate)
+			if err != nil {
+				log.Println(err)
+			} else {
+				for _, userID := range curatorIDs {
+					credential, err := store.GetCredentialByUserID(userID)
+					if !credential.Active || err != nil {
+						continue
+					}
+					curatorResponses := store.GetNumResponsesForMotivationForUserID(userID, lastRewardDate)
+					goldForCurator := curatorResponses / needResponsesToBeRewarded
+					amount := fmt.Sprintf("%d.%.3d GBG", goldForCurator/1000, goldForCurator%1000)
+					err = golos.Transfer(config.Account, credential.UserName, "Вознаграждение для кураторов", amount)
+				}
+			}
+		}
+		time.Sleep(7 * 24 * time.Hour)
+	}
+}
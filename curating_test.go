@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/helpers"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestExceededTagQuota(t *testing.T) {
+	setUp(t)
+	config.TagQuotas = map[string]int{"golos": 1}
+	if _, exceeded := exceededTagQuota([]string{"golos"}); exceeded {
+		t.Error("без открытых голосований квота не превышена")
+	}
+	voteID, err := store.SaveVote(models.Vote{UserID: 2, Author: "chiliec", Permalink: "post", Date: time.Now()}, models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SaveVoteTags(voteID, []string{"golos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag, exceeded := exceededTagQuota([]string{"golos"}); !exceeded || tag != "golos" {
+		t.Error("квота тега должна быть исчерпана")
+	}
+	if _, exceeded := exceededTagQuota([]string{"life"}); exceeded {
+		t.Error("квота действует только на свой тег")
+	}
+}
+
+func TestSubmitterName(t *testing.T) {
+	setUp(t)
+	if name := submitterName(2); name != "" {
+		t.Errorf("без аккаунта имени нет, а не %q", name)
+	}
+	err := store.SaveCredential(models.Credential{UserID: 2, UserName: "chiliec", Active: true}, models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := submitterName(2); name != "chiliec" {
+		t.Errorf("неожиданное имя %q", name)
+	}
+}
+
+func TestPostCardText(t *testing.T) {
+	text := postCardText(helpers.PostCard{Author: "some_author", Permalink: "post", Tags: []string{"golos"}}, "ru")
+	// без заголовка карточка показывает адрес поста
+	if !strings.Contains(text, "*post*") || !strings.Contains(text, i18n.T("ru", "post.card.anonymous")) {
+		t.Errorf("неожиданная карточка %q", text)
+	}
+}
+
+func TestPostCardSubmitter(t *testing.T) {
+	text := postCardText(helpers.PostCard{Author: "chiliec", Permalink: "post", Submitter: "some_user"}, "ru")
+	// логин Голоса с @ Telegram принял бы за упоминание чужого пользователя
+	if !strings.Contains(text, "Предложил: [some_user](https://golos.io/@some_user)") {
+		t.Errorf("неожиданная карточка %q", text)
+	}
+}
@@ -0,0 +1,377 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Файлы миграций называются <номер>_<название>.up.sql и <номер>_<название>.down.sql.
+// Применённую миграцию менять нельзя: её контрольная сумма записана в базе
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration — одна миграция схемы. Пустой Down означает, что откатить её нельзя
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus — миграция и сведения о её применении к базе
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Modified — файл миграции изменился после того, как её применили
+	Modified bool
+}
+
+// Migrations возвращает все встроенные миграции по возрастанию номера
+func Migrations() ([]Migration, error) {
+	return LoadMigrations(migrationFiles, "migrations")
+}
+
+// LoadMigrations читает миграции из каталога dir и возвращает их по возрастанию номера.
+// Им пользуются и хранилища других баз со своими файлами миграций
+func LoadMigrations(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		matched := migrationNameRegexp.FindStringSubmatch(entry.Name())
+		if matched == nil {
+			return nil, errors.New("неверное имя файла миграции: " + entry.Name())
+		}
+		version, _ := strconv.Atoi(matched[1])
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matched[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matched[2] {
+			return nil, fmt.Errorf("у миграции %d два названия: %s и %s", version, migration.Name, matched[2])
+		}
+		if matched[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if len(migration.Up) == 0 {
+			return nil, fmt.Errorf("у миграции %d нет файла up", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("миграции должны идти подряд, а после %d идёт %d", i, migration.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Status сообщает, какие миграции уже применены к базе. База при этом не меняется
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	exists, err := hasMigrationsTable(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	if !exists {
+		// база ещё отмечает версию схемы в PRAGMA user_version
+		var version int
+		err = db.QueryRow("PRAGMA user_version").Scan(&version)
+		if err != nil {
+			return nil, err
+		}
+		if version > len(migrations) {
+			return nil, fmt.Errorf("версия базы %d новее известных миграций", version)
+		}
+		for _, migration := range migrations {
+			statuses = append(statuses, MigrationStatus{Migration: migration, Applied: migration.Version <= version})
+		}
+		return statuses, nil
+	}
+	rows, err := db.Query("SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type applied struct {
+		checksum string
+		date     time.Time
+	}
+	appliedVersions := make(map[int]applied)
+	for rows.Next() {
+		var version int
+		var row applied
+		err = rows.Scan(&version, &row.checksum, &row.date)
+		if err != nil {
+			return nil, err
+		}
+		appliedVersions[version] = row
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if row, ok := appliedVersions[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.date
+			status.Modified = row.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func hasMigrationsTable(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&count)
+	return count > 0, err
+}
+
+// Pending возвращает миграции, которые ещё не применены к базе
+func Pending(db *sql.DB) (pending []Migration, err error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up применяет все недостающие миграции, каждую в своей транзакции.
+// Если применённая миграция изменилась, не применяет ничего
+func Up(db *sql.DB) (applied []Migration, err error) {
+	err = prepareMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Modified {
+			return nil, fmt.Errorf("миграция %d_%s изменилась после применения", status.Version, status.Name)
+		}
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+		err = applyMigration(db, status.Migration)
+		if err != nil {
+			return applied, fmt.Errorf("миграция %d_%s: %s", status.Version, status.Name, err.Error())
+		}
+		applied = append(applied, status.Migration)
+	}
+	return applied, nil
+}
+
+// Down откатывает последнюю применённую миграцию
+func Down(db *sql.DB) (Migration, error) {
+	err := prepareMigrations(db)
+	if err != nil {
+		return Migration{}, err
+	}
+	statuses, err := Status(db)
+	if err != nil {
+		return Migration{}, err
+	}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+		if len(status.Down) == 0 {
+			return status.Migration, fmt.Errorf("миграцию %d_%s нельзя откатить", status.Version, status.Name)
+		}
+		err = revertMigration(db, status.Migration)
+		if err != nil {
+			return status.Migration, fmt.Errorf("миграция %d_%s: %s", status.Version, status.Name, err.Error())
+		}
+		return status.Migration, nil
+	}
+	return Migration{}, errors.New("нет применённых миграций")
+}
+
+func applyMigration(db *sql.DB, migration Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(migration.Up)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES(?, ?, ?, ?)",
+		migration.Version, migration.Name, migration.Checksum, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = setUserVersion(tx, migration.Version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func revertMigration(db *sql.DB, migration Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(migration.Down)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = setUserVersion(tx, migration.Version-1)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// prepareMigrations создаёт таблицу schema_migrations. Базы, которые раньше отмечали
+// версию схемы в PRAGMA user_version, получают записи об уже применённых миграциях
+func prepareMigrations(db *sql.DB) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&count)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		return tx.Commit()
+	}
+	_, err = tx.Exec(`
+	CREATE TABLE schema_migrations(
+		version INTEGER PRIMARY KEY NOT NULL,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var version int
+	err = tx.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if version > len(migrations) {
+		tx.Rollback()
+		return fmt.Errorf("версия базы %d новее известных миграций", version)
+	}
+	for _, migration := range migrations[:version] {
+		_, err = tx.Exec("INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES(?, ?, ?, ?)",
+			migration.Version, migration.Name, migration.Checksum, time.Now())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// setUserVersion поддерживает PRAGMA user_version, чтобы базу понимали и прежние версии бота
+func setUserVersion(tx *sql.Tx, version int) error {
+	_, err := tx.Exec("PRAGMA user_version = " + strconv.Itoa(version))
+	return err
+}
+
+// Backup копирует файл базы рядом с ним и возвращает путь к копии.
+// Для базы в памяти и ещё не созданного или пустого файла копировать нечего
+func Backup(path string) (string, error) {
+	if len(path) == 0 || path == ":memory:" {
+		return "", nil
+	}
+	source, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+	info, err := source.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() == 0 {
+		return "", nil
+	}
+	backupPath := path + "." + time.Now().Format("20060102-150405.000000") + ".bak"
+	target, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(target, source)
+	if err != nil {
+		target.Close()
+		os.Remove(backupPath)
+		return "", err
+	}
+	return backupPath, target.Close()
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) < 19 {
+		t.Fatalf("всего %d миграций", len(migrations))
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 || len(migration.Checksum) != 64 {
+			t.Errorf("неверная миграция %d: %#v", i+1, migration)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	db, err := InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	pending, err := Pending(db)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("остались неприменённые миграции %v, ошибка %v", pending, err)
+	}
+	migrations, _ := Migrations()
+	last := migrations[len(migrations)-1]
+	reverted, err := Down(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Version != last.Version {
+		t.Errorf("откатили миграцию %d вместо %d", reverted.Version, last.Version)
+	}
+	pending, err = Pending(db)
+	if err != nil || len(pending) != 1 || pending[0].Version != last.Version {
+		t.Fatalf("после отката ждут применения %v, ошибка %v", pending, err)
+	}
+	var version int
+	db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version != last.Version-1 {
+		t.Errorf("user_version %d вместо %d", version, last.Version-1)
+	}
+	applied, err := Up(db)
+	if err != nil || len(applied) != 1 {
+		t.Fatalf("применили %v, ошибка %v", applied, err)
+	}
+}
+
+func TestIrreversibleMigration(t *testing.T) {
+	db, err := InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrations, _ := Migrations()
+	for i := len(migrations) - 1; i >= 0; i-- {
+		_, err = Down(db)
+		if len(migrations[i].Down) == 0 {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "нельзя откатить") {
+		t.Errorf("необратимую миграцию откатили: %v", err)
+	}
+}
+
+func TestModifiedMigration(t *testing.T) {
+	db, err := InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	Down(db)
+	_, err = db.Exec("UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := Status(db)
+	if err != nil || !statuses[0].Modified {
+		t.Fatalf("не заметили изменения миграции, ошибка %v", err)
+	}
+	if applied, err := Up(db); err == nil || len(applied) != 0 {
+		t.Error("после изменения применённой миграции новые применять нельзя")
+	}
+}
+
+// TestUserVersion проверяет переход баз, которые отмечали версию в PRAGMA user_version
+func TestUserVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrations, _ := Migrations()
+	legacy := 10
+	for _, migration := range migrations[:legacy] {
+		_, err = db.Exec(migration.Up)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = db.Exec("PRAGMA user_version = 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := Pending(db)
+	if err != nil || len(pending) != len(migrations)-legacy || pending[0].Version != legacy+1 {
+		t.Fatalf("ждут применения %d миграций, ошибка %v", len(pending), err)
+	}
+	db.Close()
+	// статус смотрит на базу только для чтения и ничего в ней не меняет
+	readOnly, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := Status(readOnly)
+	if err != nil || !statuses[legacy-1].Applied || statuses[legacy].Applied {
+		t.Errorf("неверный статус %v, ошибка %v", statuses, err)
+	}
+	if exists, _ := hasMigrationsTable(readOnly); exists {
+		t.Error("статус не должен создавать schema_migrations")
+	}
+	readOnly.Close()
+
+	db, err = InitDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ = Pending(db); len(pending) != 0 {
+		t.Errorf("остались неприменённые миграции %v", pending)
+	}
+	backups, _ := filepath.Glob(path + ".*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("%d копий базы вместо 1", len(backups))
+	}
+	// копия снята до того, как миграции тронули базу
+	backup, err := OpenReadOnly(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	if exists, err := hasMigrationsTable(backup); exists || err != nil {
+		t.Errorf("в копии уже есть schema_migrations, ошибка %v", err)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	if backup, err := Backup(""); err != nil || backup != "" {
+		t.Errorf("базу в памяти скопировали в %s, ошибка %v", backup, err)
+	}
+	path := filepath.Join(t.TempDir(), "database.db")
+	if backup, err := Backup(path); err != nil || backup != "" {
+		t.Errorf("несуществующую базу скопировали в %s, ошибка %v", backup, err)
+	}
+	err := ioutil.WriteFile(path, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup, err := Backup(path); err != nil || backup != "" {
+		t.Errorf("пустую базу скопировали в %s, ошибка %v", backup, err)
+	}
+	err = ioutil.WriteFile(path, []byte("content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup, err := Backup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(backup)
+	if err != nil || string(content) != "content" {
+		t.Errorf("копия не совпадает с базой: %q, ошибка %v", content, err)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE responses;
+DROP TABLE credentials;
+DROP TABLE votes;
+DROP TABLE states;
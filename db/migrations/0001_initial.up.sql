@@ -0,0 +1,29 @@
+CREATE TABLE states(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	action TEXT
+);
+CREATE TABLE votes(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	user_id INTEGER,
+	author TEXT,
+	permalink TEXT,
+	percent INTEGER,
+	completed BOOLEAN NOT NULL CHECK (completed IN (0,1)) DEFAULT 0,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE credentials(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	user_name TEXT,
+	power INTEGER NOT NULL DEFAULT 100,
+	rating INTEGER NOT NULL DEFAULT 10,
+	active BOOLEAN NOT NULL CHECK (active IN (0,1)) DEFAULT 0
+);
+CREATE TABLE responses(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	user_id INTEGER,
+	vote_id INTEGER,
+	result BOOLEAN NOT NULL CHECK (result IN (0,1)),
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_user_vote ON responses(user_id, vote_id);
+CREATE UNIQUE INDEX idx_author_permalink ON votes(author, permalink);
@@ -0,0 +1,6 @@
+CREATE TABLE referrals(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	user_id INTEGER UNIQUE NOT NULL,
+	referrer TEXT NOT NULL,
+	completed BOOLEAN NOT NULL CHECK (completed IN (0,1))
+);
@@ -0,0 +1,3 @@
+CREATE TABLE cred_temp AS SELECT user_id, user_name, power, active FROM credentials;
+DROP TABLE credentials;
+ALTER TABLE cred_temp RENAME TO credentials;
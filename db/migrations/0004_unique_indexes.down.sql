@@ -0,0 +1,3 @@
+DROP INDEX idx_credentials_user_id;
+DROP INDEX idx_votes_id;
+DROP INDEX idx_responses_id;
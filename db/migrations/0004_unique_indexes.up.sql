@@ -0,0 +1,3 @@
+CREATE UNIQUE INDEX idx_credentials_user_id ON credentials(user_id);
+CREATE UNIQUE INDEX idx_votes_id ON votes(id);
+CREATE UNIQUE INDEX idx_responses_id ON responses(id);
@@ -0,0 +1,12 @@
+ALTER TABLE credentials ADD curates BOOLEAN NOT NULL CHECK (curates IN (0,1)) DEFAULT 0;
+ALTER TABLE credentials ADD chat_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE referrals ADD referral TEXT DEFAULT '';
+ALTER TABLE votes ADD rejected BOOLEAN NOT NULL CHECK (rejected IN (0,1)) DEFAULT 0;
+ALTER TABLE votes ADD addled BOOLEAN NOT NULL CHECK (addled IN (0,1)) DEFAULT 0;
+CREATE TABLE events(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	type TEXT NOT NULL,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO events(type) VALUES('POST');
+INSERT INTO events(type) VALUES('REWARD');
@@ -0,0 +1,10 @@
+ALTER TABLE votes ADD plagiarism BOOLEAN NOT NULL CHECK (plagiarism IN (0,1)) DEFAULT 0;
+CREATE TABLE trusts(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	score REAL NOT NULL DEFAULT 0,
+	approval_rate REAL NOT NULL DEFAULT 0,
+	addled_rate REAL NOT NULL DEFAULT 0,
+	plagiarism_count INTEGER NOT NULL DEFAULT 0,
+	account_created DATETIME,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
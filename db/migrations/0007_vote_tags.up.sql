@@ -0,0 +1,5 @@
+CREATE TABLE vote_tags(
+	vote_id INTEGER NOT NULL,
+	tag TEXT NOT NULL
+);
+CREATE UNIQUE INDEX idx_vote_tags ON vote_tags(vote_id, tag);
@@ -0,0 +1,5 @@
+CREATE TABLE cursors(
+	name TEXT PRIMARY KEY NOT NULL,
+	block_num INTEGER NOT NULL DEFAULT 0,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,16 @@
+-- несколько аккаунтов Голоса на одного пользователя: ключом становится логин
+CREATE TABLE cred_temp(
+	user_name TEXT PRIMARY KEY NOT NULL,
+	user_id INTEGER NOT NULL,
+	chat_id BIGINT NOT NULL DEFAULT 0,
+	power INTEGER NOT NULL DEFAULT 100,
+	active BOOLEAN NOT NULL CHECK (active IN (0,1)) DEFAULT 0,
+	curates BOOLEAN NOT NULL CHECK (curates IN (0,1)) DEFAULT 0
+);
+INSERT OR REPLACE INTO cred_temp(user_name, user_id, chat_id, power, active, curates)
+	SELECT user_name, user_id, chat_id, power, active, curates FROM credentials
+	WHERE user_name IS NOT NULL AND user_name != '' ORDER BY active;
+DROP TABLE credentials;
+ALTER TABLE cred_temp RENAME TO credentials;
+CREATE INDEX idx_credentials_user_id ON credentials(user_id);
+ALTER TABLE states ADD payload TEXT NOT NULL DEFAULT '';
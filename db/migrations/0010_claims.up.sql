@@ -0,0 +1,8 @@
+CREATE TABLE claims(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	chat_id BIGINT NOT NULL DEFAULT 0,
+	user_name TEXT NOT NULL,
+	code TEXT NOT NULL,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_claims_user_name ON claims(user_name);
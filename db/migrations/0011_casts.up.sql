@@ -0,0 +1,10 @@
+CREATE TABLE casts(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	vote_id INTEGER NOT NULL,
+	user_name TEXT NOT NULL,
+	author TEXT NOT NULL,
+	permalink TEXT NOT NULL,
+	weight INTEGER NOT NULL,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_casts_user_name ON casts(user_name, date);
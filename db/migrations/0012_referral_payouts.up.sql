@@ -0,0 +1,7 @@
+-- выплаты по старым завершённым рефералам считаем уже состоявшимися
+ALTER TABLE referrals ADD status TEXT NOT NULL DEFAULT '';
+ALTER TABLE referrals ADD deadline DATETIME NOT NULL DEFAULT '0001-01-01 00:00:00+00:00';
+ALTER TABLE referrals ADD attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE referrals ADD referrer_paid BOOLEAN NOT NULL CHECK (referrer_paid IN (0,1)) DEFAULT 0;
+ALTER TABLE referrals ADD referral_paid BOOLEAN NOT NULL CHECK (referral_paid IN (0,1)) DEFAULT 0;
+UPDATE referrals SET status = 'paid', referrer_paid = 1, referral_paid = 1 WHERE completed = 1;
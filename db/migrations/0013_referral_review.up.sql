@@ -0,0 +1,2 @@
+ALTER TABLE referrals ADD approved BOOLEAN NOT NULL CHECK (approved IN (0,1)) DEFAULT 0;
+ALTER TABLE referrals ADD paid_at DATETIME NOT NULL DEFAULT '0001-01-01 00:00:00+00:00';
@@ -0,0 +1 @@
+ALTER TABLE referrals ADD campaign TEXT NOT NULL DEFAULT '';
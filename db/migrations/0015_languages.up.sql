@@ -0,0 +1,5 @@
+CREATE TABLE languages(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	code TEXT NOT NULL,
+	manual BOOLEAN NOT NULL DEFAULT 0
+);
@@ -0,0 +1,2 @@
+DROP TABLE admin_actions;
+DROP TABLE bans;
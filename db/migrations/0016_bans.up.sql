@@ -0,0 +1,16 @@
+CREATE TABLE bans(
+	kind TEXT NOT NULL,
+	target TEXT NOT NULL,
+	admin_id INTEGER NOT NULL,
+	date DATETIME NOT NULL,
+	PRIMARY KEY (kind, target)
+);
+CREATE TABLE admin_actions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	admin_id INTEGER NOT NULL,
+	command TEXT NOT NULL,
+	arguments TEXT NOT NULL DEFAULT '',
+	result TEXT NOT NULL DEFAULT '',
+	date DATETIME NOT NULL
+);
+CREATE INDEX idx_admin_actions_date ON admin_actions(date);
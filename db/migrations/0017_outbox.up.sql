@@ -0,0 +1,6 @@
+CREATE TABLE outbox(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	chat_id INTEGER NOT NULL,
+	config TEXT NOT NULL,
+	date DATETIME NOT NULL
+);
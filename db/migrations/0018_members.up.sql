@@ -0,0 +1,11 @@
+CREATE TABLE members(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	verified BOOLEAN NOT NULL,
+	since DATETIME NOT NULL
+);
+CREATE TABLE removals(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	user_id INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	date DATETIME NOT NULL
+);
@@ -0,0 +1,9 @@
+-- SQLite не умеет удалять столбцы, поэтому таблица пересобирается без expires
+CREATE TABLE states_temp(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	action TEXT,
+	payload TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO states_temp(user_id, action, payload) SELECT user_id, action, payload FROM states;
+DROP TABLE states;
+ALTER TABLE states_temp RENAME TO states;
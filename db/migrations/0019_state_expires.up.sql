@@ -0,0 +1,4 @@
+-- прежние состояния хранили надписи кнопок и названия команд; незаконченные
+-- диалоги проще начать заново, чем переводить в новые состояния
+ALTER TABLE states ADD expires DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE states SET action = '', payload = '';
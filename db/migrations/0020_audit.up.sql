@@ -0,0 +1,20 @@
+CREATE TABLE audit(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	actor TEXT NOT NULL,
+	entity TEXT NOT NULL,
+	entity_id TEXT NOT NULL,
+	old_value TEXT NOT NULL,
+	new_value TEXT NOT NULL,
+	date DATETIME NOT NULL
+);
+CREATE INDEX audit_entity ON audit(entity, entity_id);
+CREATE INDEX audit_actor ON audit(actor);
+-- журнал изменений только дописывается
+CREATE TRIGGER audit_no_update BEFORE UPDATE ON audit
+BEGIN
+	SELECT RAISE(ABORT, 'журнал изменений нельзя менять');
+END;
+CREATE TRIGGER audit_no_delete BEFORE DELETE ON audit
+BEGIN
+	SELECT RAISE(ABORT, 'журнал изменений нельзя менять');
+END;
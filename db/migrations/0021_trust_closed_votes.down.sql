@@ -0,0 +1,14 @@
+-- SQLite не умеет удалять столбцы, поэтому таблица пересобирается без closed_votes
+CREATE TABLE trusts_temp(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	score REAL NOT NULL DEFAULT 0,
+	approval_rate REAL NOT NULL DEFAULT 0,
+	addled_rate REAL NOT NULL DEFAULT 0,
+	plagiarism_count INTEGER NOT NULL DEFAULT 0,
+	account_created DATETIME,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO trusts_temp(user_id, score, approval_rate, addled_rate, plagiarism_count, account_created, date)
+	SELECT user_id, score, approval_rate, addled_rate, plagiarism_count, account_created, date FROM trusts;
+DROP TABLE trusts;
+ALTER TABLE trusts_temp RENAME TO trusts;
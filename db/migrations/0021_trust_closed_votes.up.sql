@@ -0,0 +1,3 @@
+-- доверие пересчитывается при каждом предложении поста, так что старые записи
+-- получат число закрытых голосований при первом же пересчёте
+ALTER TABLE trusts ADD closed_votes INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,19 @@
+-- SQLite не умеет удалять столбцы, поэтому таблица пересобирается без closed.
+-- Закрытые администратором голосования остаются завершёнными
+CREATE TABLE votes_temp(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	user_id INTEGER,
+	author TEXT,
+	permalink TEXT,
+	percent INTEGER,
+	completed BOOLEAN NOT NULL CHECK (completed IN (0,1)) DEFAULT 0,
+	date DATETIME DEFAULT CURRENT_TIMESTAMP,
+	rejected BOOLEAN NOT NULL CHECK (rejected IN (0,1)) DEFAULT 0,
+	addled BOOLEAN NOT NULL CHECK (addled IN (0,1)) DEFAULT 0,
+	plagiarism BOOLEAN NOT NULL CHECK (plagiarism IN (0,1)) DEFAULT 0
+);
+INSERT INTO votes_temp(id, user_id, author, permalink, percent, completed, date, rejected, addled, plagiarism)
+	SELECT id, user_id, author, permalink, percent, completed, date, rejected, addled, plagiarism FROM votes;
+DROP TABLE votes;
+ALTER TABLE votes_temp RENAME TO votes;
+CREATE UNIQUE INDEX idx_votes_id ON votes(id);
@@ -0,0 +1,2 @@
+-- голосования, закрытые администратором: у них нет итога и они не влияют на доверие
+ALTER TABLE votes ADD closed BOOLEAN NOT NULL CHECK (closed IN (0,1)) DEFAULT 0;
@@ -0,0 +1,9 @@
+-- SQLite не умеет удалять столбцы, поэтому таблица пересобирается без attempts и retry_at
+CREATE TABLE members_temp(
+	user_id INTEGER PRIMARY KEY NOT NULL,
+	verified BOOLEAN NOT NULL,
+	since DATETIME NOT NULL
+);
+INSERT INTO members_temp(user_id, verified, since) SELECT user_id, verified, since FROM members;
+DROP TABLE members;
+ALTER TABLE members_temp RENAME TO members;
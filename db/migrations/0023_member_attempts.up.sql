@@ -0,0 +1,3 @@
+-- неудачные попытки удалить участника из группы, чтобы повторять их всё реже, а не каждую минуту
+ALTER TABLE members ADD attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE members ADD retry_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
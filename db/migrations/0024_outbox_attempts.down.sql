@@ -0,0 +1,11 @@
+-- SQLite не умеет удалять столбцы, поэтому таблица пересобирается без попыток.
+-- Недоставленные сообщения при этом удаляются, иначе они снова попали бы в очередь
+CREATE TABLE outbox_temp(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	chat_id INTEGER NOT NULL,
+	config TEXT NOT NULL,
+	date DATETIME NOT NULL
+);
+INSERT INTO outbox_temp(id, chat_id, config, date) SELECT id, chat_id, config, date FROM outbox WHERE dead = 0;
+DROP TABLE outbox;
+ALTER TABLE outbox_temp RENAME TO outbox;
@@ -0,0 +1,6 @@
+-- неудачные попытки отправки переживают перезапуск, а сообщения, от которых бот отказался,
+-- остаются в таблице недоставленными вместе с последней ошибкой
+ALTER TABLE outbox ADD attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE outbox ADD retry_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE outbox ADD dead BOOLEAN NOT NULL CHECK (dead IN (0,1)) DEFAULT 0;
+ALTER TABLE outbox ADD error TEXT NOT NULL DEFAULT '';
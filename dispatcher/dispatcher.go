@@ -0,0 +1,265 @@
+// Package dispatcher отправляет сообщения в Telegram через общую очередь,
+// не превышая ограничений на частоту отправки
+package dispatcher
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/helpers"
+)
+
+// Sender — то, через что сообщения уходят в Telegram, ему удовлетворяет *tgbotapi.BotAPI
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+}
+
+// Message — сообщение в очереди. Attempts — сколько раз Telegram его отклонил,
+// раньше RetryAt его снова не отправляют
+type Message struct {
+	ID       int64
+	Config   tgbotapi.MessageConfig
+	Attempts int
+	RetryAt  time.Time
+}
+
+// Dispatcher отправляет сообщения по очереди. Telegram разрешает боту около 30 сообщений
+// в секунду, не больше одного в секунду в личный чат и 20 в минуту в группу
+type Dispatcher struct {
+	Sender         Sender
+	Store          Store
+	GlobalInterval time.Duration
+	ChatInterval   time.Duration
+	GroupInterval  time.Duration
+	// MaxAttempts — сколько раз пытаться отправить сообщение, которое Telegram отклоняет.
+	// Потом сообщение остаётся в хранилище недоставленным
+	MaxAttempts int
+	// RetryDelay — пауза после первой неудачи, с каждой следующей она удваивается до MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// OnBlocked вызывается, когда пользователь заблокировал бота и писать ему бесполезно
+	OnBlocked func(chatID int64)
+
+	mutex       sync.Mutex
+	pending     []Message
+	lastSent    time.Time
+	chatSent    map[int64]time.Time
+	pausedUntil time.Time
+	wake        chan struct{}
+}
+
+func New(sender Sender, store Store) *Dispatcher {
+	return &Dispatcher{
+		Sender:         sender,
+		Store:          store,
+		GlobalInterval: time.Second / 25,
+		ChatInterval:   time.Second,
+		GroupInterval:  3 * time.Second,
+		MaxAttempts:    5,
+		RetryDelay:     30 * time.Second,
+		MaxRetryDelay:  time.Hour,
+		chatSent:       make(map[int64]time.Time),
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Restore возвращает в очередь сообщения, не отправленные до перезапуска
+func (dispatcher *Dispatcher) Restore() error {
+	messages, err := dispatcher.Store.Load()
+	if err != nil {
+		return err
+	}
+	dispatcher.mutex.Lock()
+	dispatcher.pending = append(messages, dispatcher.pending...)
+	dispatcher.mutex.Unlock()
+	dispatcher.notify()
+	return nil
+}
+
+// Send ставит сообщение в очередь. Сообщение сохраняется и будет отправлено даже после перезапуска
+func (dispatcher *Dispatcher) Send(config tgbotapi.MessageConfig) error {
+	id, err := dispatcher.Store.Add(config)
+	if err != nil {
+		return err
+	}
+	dispatcher.mutex.Lock()
+	dispatcher.pending = append(dispatcher.pending, Message{ID: id, Config: config})
+	dispatcher.mutex.Unlock()
+	dispatcher.notify()
+	return nil
+}
+
+// Pending возвращает количество сообщений в очереди
+func (dispatcher *Dispatcher) Pending() int {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	return len(dispatcher.pending)
+}
+
+func (dispatcher *Dispatcher) notify() {
+	select {
+	case dispatcher.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run бесконечно отправляет сообщения из очереди
+func (dispatcher *Dispatcher) Run() {
+	for {
+		sent, wait := dispatcher.Step(time.Now())
+		if sent {
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-dispatcher.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// Step отправляет первое сообщение, которое уже можно отправить. Если такого нет,
+// возвращает, сколько стоит подождать
+func (dispatcher *Dispatcher) Step(now time.Time) (bool, time.Duration) {
+	dispatcher.mutex.Lock()
+	index, wait := dispatcher.next(now)
+	if index < 0 {
+		dispatcher.mutex.Unlock()
+		return false, wait
+	}
+	message := dispatcher.pending[index]
+	// пока сообщение в пути, его чат считается занятым
+	dispatcher.lastSent = now
+	dispatcher.chatSent[message.Config.ChatID] = now
+	dispatcher.mutex.Unlock()
+
+	_, err := dispatcher.Sender.Send(message.Config)
+
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	index = dispatcher.indexOf(message.ID)
+	if err == nil {
+		dispatcher.remove(index, message)
+		return true, 0
+	}
+	if retryAfter, ok := RetryAfter(err); ok {
+		log.Printf("Telegram просит подождать %s", retryAfter)
+		dispatcher.pausedUntil = now.Add(retryAfter)
+		return false, retryAfter
+	}
+	if IsBlocked(err) {
+		log.Printf("Чат %d недоступен: %s", message.Config.ChatID, err.Error())
+		dispatcher.remove(index, message)
+		if dispatcher.OnBlocked != nil {
+			go dispatcher.OnBlocked(message.Config.ChatID)
+		}
+		return true, 0
+	}
+	message.Attempts++
+	if message.Attempts >= dispatcher.MaxAttempts {
+		log.Printf("Не отправили сообщение в чат %d за %d попыток: %s", message.Config.ChatID, message.Attempts, err.Error())
+		dispatcher.bury(index, message, err)
+		return true, 0
+	}
+	message.RetryAt = now.Add(helpers.RetryDelay(message.Attempts, dispatcher.RetryDelay, dispatcher.MaxRetryDelay))
+	if index >= 0 {
+		dispatcher.pending[index] = message
+	}
+	err = dispatcher.Store.Retry(message, err)
+	if err != nil {
+		log.Println("не сохранили попытку отправки: " + err.Error())
+	}
+	return true, 0
+}
+
+// next ищет первое сообщение в очереди, чат которого не отправлял сообщений слишком недавно
+func (dispatcher *Dispatcher) next(now time.Time) (int, time.Duration) {
+	wait := time.Minute
+	if len(dispatcher.pending) == 0 {
+		return -1, wait
+	}
+	if pause := dispatcher.pausedUntil.Sub(now); pause > 0 {
+		return -1, pause
+	}
+	if pause := dispatcher.lastSent.Add(dispatcher.GlobalInterval).Sub(now); pause > 0 {
+		return -1, pause
+	}
+	for index, message := range dispatcher.pending {
+		if pause := message.RetryAt.Sub(now); pause > 0 {
+			if pause < wait {
+				wait = pause
+			}
+			continue
+		}
+		interval := dispatcher.ChatInterval
+		if message.Config.ChatID < 0 {
+			interval = dispatcher.GroupInterval
+		}
+		pause := dispatcher.chatSent[message.Config.ChatID].Add(interval).Sub(now)
+		if pause <= 0 {
+			return index, 0
+		}
+		if pause < wait {
+			wait = pause
+		}
+	}
+	return -1, wait
+}
+
+func (dispatcher *Dispatcher) indexOf(id int64) int {
+	for index, message := range dispatcher.pending {
+		if message.ID == id {
+			return index
+		}
+	}
+	return -1
+}
+
+func (dispatcher *Dispatcher) remove(index int, message Message) {
+	if index >= 0 {
+		dispatcher.pending = append(dispatcher.pending[:index], dispatcher.pending[index+1:]...)
+	}
+	err := dispatcher.Store.Remove(message.ID)
+	if err != nil {
+		log.Println("не удалили сообщение из очереди: " + err.Error())
+	}
+}
+
+// bury убирает из очереди сообщение, от которого бот отказался, оставляя его в хранилище недоставленным
+func (dispatcher *Dispatcher) bury(index int, message Message, lastError error) {
+	if index >= 0 {
+		dispatcher.pending = append(dispatcher.pending[:index], dispatcher.pending[index+1:]...)
+	}
+	err := dispatcher.Store.Bury(message, lastError)
+	if err != nil {
+		log.Println("не отметили сообщение недоставленным: " + err.Error())
+	}
+}
+
+var retryAfterRegexp = regexp.MustCompile(`retry after (\d+)`)
+
+// RetryAfter достаёт из ошибки 429 время, которое Telegram просит подождать
+func RetryAfter(err error) (time.Duration, bool) {
+	matched := retryAfterRegexp.FindStringSubmatch(err.Error())
+	if matched == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(matched[1])
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// IsBlocked сообщает, что ошибка — 403: бота заблокировали, удалили из группы
+// или пользователь удалил аккаунт
+func IsBlocked(err error) bool {
+	return strings.HasPrefix(err.Error(), "Forbidden")
+}
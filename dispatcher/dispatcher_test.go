@@ -0,0 +1,208 @@
+package dispatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+type fakeSender struct {
+	sent   []int64
+	errors map[int64][]error
+}
+
+func (sender *fakeSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	chatID := c.(tgbotapi.MessageConfig).ChatID
+	if queued := sender.errors[chatID]; len(queued) > 0 {
+		sender.errors[chatID] = queued[1:]
+		return tgbotapi.Message{}, queued[0]
+	}
+	sender.sent = append(sender.sent, chatID)
+	return tgbotapi.Message{}, nil
+}
+
+type memoryStore struct {
+	lastID   int64
+	messages map[int64]tgbotapi.MessageConfig
+	attempts map[int64]Message
+	dead     map[int64]Message
+}
+
+func (store *memoryStore) Add(config tgbotapi.MessageConfig) (int64, error) {
+	store.lastID++
+	store.messages[store.lastID] = config
+	return store.lastID, nil
+}
+
+func (store *memoryStore) Remove(id int64) error {
+	delete(store.messages, id)
+	return nil
+}
+
+func (store *memoryStore) Retry(message Message, lastError error) error {
+	store.attempts[message.ID] = message
+	return nil
+}
+
+func (store *memoryStore) Bury(message Message, lastError error) error {
+	delete(store.messages, message.ID)
+	store.dead[message.ID] = message
+	return nil
+}
+
+func (store *memoryStore) Load() (messages []Message, err error) {
+	for id := int64(1); id <= store.lastID; id++ {
+		if config, ok := store.messages[id]; ok {
+			message := Message{ID: id, Config: config}
+			if retried, ok := store.attempts[id]; ok {
+				message = retried
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func newTestDispatcher(sender *fakeSender) (*Dispatcher, *memoryStore) {
+	store := &memoryStore{
+		messages: make(map[int64]tgbotapi.MessageConfig),
+		attempts: make(map[int64]Message),
+		dead:     make(map[int64]Message),
+	}
+	return New(sender, store), store
+}
+
+func TestDispatcher_ChatInterval(t *testing.T) {
+	sender := &fakeSender{}
+	dispatcher, store := newTestDispatcher(sender)
+	for _, chatID := range []int64{1, 1, 2} {
+		dispatcher.Send(tgbotapi.NewMessage(chatID, "text"))
+	}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		now = now.Add(dispatcher.GlobalInterval)
+		if sent, _ := dispatcher.Step(now); !sent {
+			t.Fatalf("шаг %d должен был отправить сообщение", i)
+		}
+	}
+	// второе сообщение в первый чат придётся подождать
+	now = now.Add(dispatcher.GlobalInterval)
+	sent, wait := dispatcher.Step(now)
+	if sent || wait <= 0 || wait > dispatcher.ChatInterval {
+		t.Fatalf("ожидали паузу, получили %t и %s", sent, wait)
+	}
+	if sent, _ = dispatcher.Step(now.Add(dispatcher.ChatInterval)); !sent {
+		t.Fatal("после паузы сообщение должно уйти")
+	}
+	if len(sender.sent) != 3 || sender.sent[0] != 1 || sender.sent[1] != 2 || sender.sent[2] != 1 {
+		t.Errorf("неожиданный порядок отправки %v", sender.sent)
+	}
+	if len(store.messages) != 0 || dispatcher.Pending() != 0 {
+		t.Error("отправленные сообщения должны покинуть очередь")
+	}
+}
+
+func TestDispatcher_RetryAfter(t *testing.T) {
+	sender := &fakeSender{errors: map[int64][]error{
+		1: {errors.New("Too Many Requests: retry after 7")},
+	}}
+	dispatcher, _ := newTestDispatcher(sender)
+	dispatcher.Send(tgbotapi.NewMessage(1, "text"))
+	now := time.Now()
+	sent, wait := dispatcher.Step(now)
+	if sent || wait != 7*time.Second {
+		t.Fatalf("ожидали паузу в 7 секунд, получили %t и %s", sent, wait)
+	}
+	if sent, _ = dispatcher.Step(now.Add(6 * time.Second)); sent {
+		t.Fatal("во время паузы отправлять нельзя")
+	}
+	if sent, _ = dispatcher.Step(now.Add(7 * time.Second)); !sent || len(sender.sent) != 1 {
+		t.Fatal("после паузы сообщение должно уйти")
+	}
+}
+
+func TestDispatcher_Blocked(t *testing.T) {
+	sender := &fakeSender{errors: map[int64][]error{
+		1: {errors.New("Forbidden: bot was blocked by the user")},
+	}}
+	dispatcher, store := newTestDispatcher(sender)
+	blocked := make(chan int64, 1)
+	dispatcher.OnBlocked = func(chatID int64) {
+		blocked <- chatID
+	}
+	dispatcher.Send(tgbotapi.NewMessage(1, "text"))
+	dispatcher.Step(time.Now())
+	select {
+	case chatID := <-blocked:
+		if chatID != 1 {
+			t.Errorf("заблокирован не тот чат: %d", chatID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnBlocked не вызван")
+	}
+	if len(store.messages) != 0 || dispatcher.Pending() != 0 {
+		t.Error("сообщение заблокировавшему бота пользователю не должно оставаться в очереди")
+	}
+}
+
+func TestDispatcher_Restore(t *testing.T) {
+	sender := &fakeSender{}
+	dispatcher, store := newTestDispatcher(sender)
+	dispatcher.Send(tgbotapi.NewMessage(1, "text"))
+	restarted := New(sender, store)
+	err := restarted.Restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent, _ := restarted.Step(time.Now()); !sent || len(sender.sent) != 1 {
+		t.Fatal("сохранённое сообщение должно уйти после перезапуска")
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	failure := errors.New("Internal Server Error")
+	sender := &fakeSender{errors: map[int64][]error{1: {failure, failure, failure}}}
+	dispatcher, store := newTestDispatcher(sender)
+	dispatcher.MaxAttempts = 3
+	dispatcher.Send(tgbotapi.NewMessage(1, "text"))
+	now := time.Now()
+	dispatcher.Step(now)
+	if store.attempts[1].Attempts != 1 {
+		t.Fatal("неудачная попытка должна сохраниться")
+	}
+	sent, wait := dispatcher.Step(now.Add(dispatcher.ChatInterval))
+	if sent || wait != dispatcher.RetryDelay-dispatcher.ChatInterval {
+		t.Fatalf("до повтора нужно подождать, получили %t и %s", sent, wait)
+	}
+
+	// после перезапуска пауза и число попыток сохраняются
+	restarted := New(sender, store)
+	restarted.MaxAttempts = 3
+	err := restarted.Restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(dispatcher.RetryDelay)
+	restarted.Step(now)
+	if store.attempts[1].Attempts != 2 || !store.attempts[1].RetryAt.Equal(now.Add(2*dispatcher.RetryDelay)) {
+		t.Fatalf("пауза должна удвоиться: %#v", store.attempts[1])
+	}
+	restarted.Step(now.Add(2 * dispatcher.RetryDelay))
+	if _, ok := store.dead[1]; !ok || restarted.Pending() != 0 {
+		t.Error("после последней попытки сообщение должно стать недоставленным")
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("неожиданно отправили %v", sender.sent)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if _, ok := RetryAfter(errors.New("Bad Request: chat not found")); ok {
+		t.Error("в ошибке нет времени ожидания")
+	}
+	if wait, ok := RetryAfter(errors.New("Too Many Requests: retry after 35")); !ok || wait != 35*time.Second {
+		t.Errorf("неверно разобрали время ожидания: %s", wait)
+	}
+}
@@ -0,0 +1,68 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/storage"
+)
+
+// Store хранит неотправленные сообщения, чтобы они пережили перезапуск
+type Store interface {
+	Add(config tgbotapi.MessageConfig) (int64, error)
+	Remove(id int64) error
+	// Retry запоминает число попыток и время следующей
+	Retry(message Message, lastError error) error
+	// Bury оставляет сообщение недоставленным: Load его больше не вернёт
+	Bury(message Message, lastError error) error
+	Load() ([]Message, error)
+}
+
+type databaseStore struct {
+	outbox storage.Outbox
+}
+
+// NewDatabaseStore создаёт хранилище очереди в таблице outbox хранилища бота
+func NewDatabaseStore(outbox storage.Outbox) Store {
+	return databaseStore{outbox: outbox}
+}
+
+func (store databaseStore) Add(config tgbotapi.MessageConfig) (int64, error) {
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return 0, err
+	}
+	return store.outbox.AddOutboxMessage(models.OutboxMessage{ChatID: config.ChatID, Config: string(encoded), Date: time.Now()})
+}
+
+func (store databaseStore) Remove(id int64) error {
+	return store.outbox.DeleteOutboxMessage(id)
+}
+
+func (store databaseStore) Retry(message Message, lastError error) error {
+	return store.outbox.RetryOutboxMessage(message.ID, message.Attempts, message.RetryAt, lastError.Error())
+}
+
+func (store databaseStore) Bury(message Message, lastError error) error {
+	return store.outbox.BuryOutboxMessage(message.ID, message.Attempts, lastError.Error())
+}
+
+func (store databaseStore) Load() ([]Message, error) {
+	stored, err := store.outbox.GetOutboxMessages()
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(stored))
+	for _, message := range stored {
+		var config tgbotapi.MessageConfig
+		err = json.Unmarshal([]byte(message.Config), &config)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, Message{ID: message.ID, Config: config, Attempts: message.Attempts, RetryAt: message.RetryAt})
+	}
+	return messages, nil
+}
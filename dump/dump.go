@@ -0,0 +1,495 @@
+// Package dump выгружает историю кураторства в CSV и JSON Lines и загружает
+// выгрузку JSON Lines в пустую базу, сохраняя id голосований и связи с ними
+package dump
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/storage"
+)
+
+// Форматы выгрузки
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Что можно выгрузить. В CSV каждая сущность выгружается в свой файл
+const (
+	EntityVotes       = "votes"
+	EntityResponses   = "responses"
+	EntityCredentials = "credentials"
+	EntityReferrals   = "referrals"
+)
+
+// Entities — все сущности в порядке, в котором их нужно загружать
+var Entities = []string{EntityCredentials, EntityReferrals, EntityVotes, EntityResponses}
+
+// DateLayout — формат дат в фильтрах выгрузки
+const DateLayout = "2006-01-02"
+
+// forever заменяет отсутствующую верхнюю границу периода
+var forever = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options — что и как выгружать. Since и Until ограничивают голосования и ответы
+// кураторов по дате: Since включительно, Until не включая. Нулевая дата не ограничивает
+type Options struct {
+	Format   string
+	Entities []string
+	Since    time.Time
+	Until    time.Time
+}
+
+// ParseDate разбирает дату фильтра; пустая строка означает отсутствие ограничения
+func ParseDate(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return date, fmt.Errorf("дата %q не в формате ГГГГ-ММ-ДД", value)
+	}
+	return date, nil
+}
+
+// Строки выгрузки. Секретов в них нет: бот голосует собственным ключом,
+// а у аккаунтов хранит только логин и доверенную Силу Голоса
+
+type voteRecord struct {
+	Type       string    `json:"type"`
+	ID         int64     `json:"id"`
+	UserID     int       `json:"user_id"`
+	Author     string    `json:"author"`
+	Permalink  string    `json:"permalink"`
+	Percent    int       `json:"percent"`
+	Completed  bool      `json:"completed"`
+	Rejected   bool      `json:"rejected"`
+	Addled     bool      `json:"addled"`
+	Plagiarism bool      `json:"plagiarism"`
+	Closed     bool      `json:"closed"`
+	Date       time.Time `json:"date"`
+	Tags       []string  `json:"tags"`
+}
+
+type responseRecord struct {
+	Type   string    `json:"type"`
+	UserID int       `json:"user_id"`
+	VoteID int64     `json:"vote_id"`
+	Result bool      `json:"result"`
+	Date   time.Time `json:"date"`
+}
+
+type credentialRecord struct {
+	Type     string `json:"type"`
+	UserID   int    `json:"user_id"`
+	ChatID   int64  `json:"chat_id"`
+	UserName string `json:"user_name"`
+	Power    int    `json:"power"`
+	Active   bool   `json:"active"`
+	Curates  bool   `json:"curates"`
+}
+
+type referralRecord struct {
+	Type         string    `json:"type"`
+	UserID       int       `json:"user_id"`
+	Referrer     string    `json:"referrer"`
+	Referral     string    `json:"referral"`
+	Campaign     string    `json:"campaign"`
+	Completed    bool      `json:"completed"`
+	Status       string    `json:"status"`
+	Deadline     time.Time `json:"deadline"`
+	Attempts     int       `json:"attempts"`
+	ReferrerPaid bool      `json:"referrer_paid"`
+	ReferralPaid bool      `json:"referral_paid"`
+	Approved     bool      `json:"approved"`
+	PaidAt       time.Time `json:"paid_at"`
+}
+
+// типы строк JSON Lines
+const (
+	typeVote       = "vote"
+	typeResponse   = "response"
+	typeCredential = "credential"
+	typeReferral   = "referral"
+)
+
+var csvHeaders = map[string][]string{
+	EntityVotes: {"id", "user_id", "author", "permalink", "percent", "completed", "rejected",
+		"addled", "plagiarism", "closed", "date", "tags"},
+	EntityResponses:   {"user_id", "vote_id", "result", "date"},
+	EntityCredentials: {"user_id", "chat_id", "user_name", "power", "active", "curates"},
+	EntityReferrals: {"user_id", "referrer", "referral", "campaign", "completed", "status", "deadline",
+		"attempts", "referrer_paid", "referral_paid", "approved", "paid_at"},
+}
+
+// Export выгружает выбранные сущности в w и возвращает число выгруженных строк
+func Export(w io.Writer, store storage.Storage, options Options) (count int, err error) {
+	entities := options.Entities
+	if len(entities) == 0 {
+		entities = Entities
+	}
+	for _, entity := range entities {
+		if _, ok := csvHeaders[entity]; !ok {
+			return 0, errors.New("неизвестная сущность: " + entity)
+		}
+	}
+	until := options.Until
+	if until.IsZero() {
+		until = forever
+	}
+	switch options.Format {
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, entity := range entities {
+			records, err := load(store, entity, options.Since, until)
+			if err != nil {
+				return count, err
+			}
+			for _, record := range records {
+				err = encoder.Encode(record)
+				if err != nil {
+					return count, err
+				}
+				count++
+			}
+		}
+		return count, nil
+	case FormatCSV:
+		if len(entities) != 1 {
+			return 0, errors.New("в CSV выгружается одна сущность: " + EntityVotes + ", " +
+				EntityResponses + ", " + EntityCredentials + " или " + EntityReferrals)
+		}
+		records, err := load(store, entities[0], options.Since, until)
+		if err != nil {
+			return 0, err
+		}
+		writer := csv.NewWriter(w)
+		writer.Write(csvHeaders[entities[0]])
+		for _, record := range records {
+			writer.Write(csvRow(record))
+			count++
+		}
+		writer.Flush()
+		return count, writer.Error()
+	}
+	return 0, errors.New("неизвестный формат выгрузки: " + options.Format)
+}
+
+func load(store storage.Storage, entity string, since time.Time, until time.Time) (records []interface{}, err error) {
+	switch entity {
+	case EntityVotes:
+		votes, err := store.GetVotesBetween(since, until)
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			tags, err := store.GetVoteTags(vote.VoteID)
+			if err != nil {
+				return nil, err
+			}
+			if tags == nil {
+				tags = []string{}
+			}
+			records = append(records, voteRecord{
+				Type:       typeVote,
+				ID:         vote.VoteID,
+				UserID:     vote.UserID,
+				Author:     vote.Author,
+				Permalink:  vote.Permalink,
+				Percent:    vote.Percent,
+				Completed:  vote.Completed,
+				Rejected:   vote.Rejected,
+				Addled:     vote.Addled,
+				Plagiarism: vote.Plagiarism,
+				Closed:     vote.Closed,
+				Date:       vote.Date,
+				Tags:       tags,
+			})
+		}
+	case EntityResponses:
+		responses, err := store.GetResponsesBetween(since, until)
+		if err != nil {
+			return nil, err
+		}
+		for _, response := range responses {
+			records = append(records, responseRecord{
+				Type:   typeResponse,
+				UserID: response.UserID,
+				VoteID: response.VoteID,
+				Result: response.Result,
+				Date:   response.Date,
+			})
+		}
+	case EntityCredentials:
+		credentials, err := store.GetAllCredentials()
+		if err != nil {
+			return nil, err
+		}
+		for _, credential := range credentials {
+			records = append(records, credentialRecord{
+				Type:     typeCredential,
+				UserID:   credential.UserID,
+				ChatID:   credential.ChatID,
+				UserName: credential.UserName,
+				Power:    credential.Power,
+				Active:   credential.Active,
+				Curates:  credential.Curates,
+			})
+		}
+	case EntityReferrals:
+		referrals, err := store.GetAllReferrals()
+		if err != nil {
+			return nil, err
+		}
+		for _, referral := range referrals {
+			records = append(records, referralRecord{
+				Type:         typeReferral,
+				UserID:       referral.UserID,
+				Referrer:     referral.Referrer,
+				Referral:     referral.UserName,
+				Campaign:     referral.Campaign,
+				Completed:    referral.Completed,
+				Status:       referral.Status,
+				Deadline:     referral.Deadline,
+				Attempts:     referral.Attempts,
+				ReferrerPaid: referral.ReferrerPaid,
+				ReferralPaid: referral.ReferralPaid,
+				Approved:     referral.Approved,
+				PaidAt:       referral.PaidAt,
+			})
+		}
+	}
+	return records, nil
+}
+
+func csvRow(record interface{}) []string {
+	switch record := record.(type) {
+	case voteRecord:
+		tags, _ := json.Marshal(record.Tags)
+		return []string{formatInt(record.ID), strconv.Itoa(record.UserID), record.Author, record.Permalink,
+			strconv.Itoa(record.Percent), formatBool(record.Completed), formatBool(record.Rejected),
+			formatBool(record.Addled), formatBool(record.Plagiarism), formatBool(record.Closed), formatTime(record.Date), string(tags)}
+	case responseRecord:
+		return []string{strconv.Itoa(record.UserID), formatInt(record.VoteID), formatBool(record.Result),
+			formatTime(record.Date)}
+	case credentialRecord:
+		return []string{strconv.Itoa(record.UserID), formatInt(record.ChatID), record.UserName,
+			strconv.Itoa(record.Power), formatBool(record.Active), formatBool(record.Curates)}
+	case referralRecord:
+		return []string{strconv.Itoa(record.UserID), record.Referrer, record.Referral, record.Campaign,
+			formatBool(record.Completed), record.Status, formatTime(record.Deadline), strconv.Itoa(record.Attempts),
+			formatBool(record.ReferrerPaid), formatBool(record.ReferralPaid), formatBool(record.Approved),
+			formatTime(record.PaidAt)}
+	}
+	return nil
+}
+
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+func formatBool(value bool) string {
+	return strconv.FormatBool(value)
+}
+
+func formatTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+// Counts — сколько строк каждого вида загружено
+type Counts struct {
+	Credentials int
+	Referrals   int
+	Votes       int
+	Responses   int
+}
+
+// Import загружает выгрузку JSON Lines в пустую базу. Сначала проверяется вся выгрузка:
+// неизвестные строки, повторы голосований и ответы на отсутствующие в ней голосования
+// останавливают загрузку до записи в базу. Записывается выгрузка одной транзакцией. Загруженные записи попадают в журнал изменений от имени actor
+func Import(r io.Reader, store storage.Storage, actor models.Actor) (counts Counts, err error) {
+	var credentials []models.Credential
+	var referrals []models.Referral
+	var votes []voteRecord
+	var responses []models.Response
+	voteIDs := make(map[int64]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		var header struct {
+			Type string `json:"type"`
+		}
+		err = json.Unmarshal(data, &header)
+		if err != nil {
+			return counts, fmt.Errorf("строка %d: %s", line, err.Error())
+		}
+		switch header.Type {
+		case typeCredential:
+			var record credentialRecord
+			err = json.Unmarshal(data, &record)
+			credentials = append(credentials, models.Credential{
+				UserID:   record.UserID,
+				ChatID:   record.ChatID,
+				UserName: record.UserName,
+				Power:    record.Power,
+				Active:   record.Active,
+				Curates:  record.Curates,
+			})
+		case typeReferral:
+			var record referralRecord
+			err = json.Unmarshal(data, &record)
+			referrals = append(referrals, models.Referral{
+				UserID:       record.UserID,
+				Referrer:     record.Referrer,
+				UserName:     record.Referral,
+				Campaign:     record.Campaign,
+				Completed:    record.Completed,
+				Status:       record.Status,
+				Deadline:     record.Deadline,
+				Attempts:     record.Attempts,
+				ReferrerPaid: record.ReferrerPaid,
+				ReferralPaid: record.ReferralPaid,
+				Approved:     record.Approved,
+				PaidAt:       record.PaidAt,
+			})
+		case typeVote:
+			var record voteRecord
+			err = json.Unmarshal(data, &record)
+			if err == nil && record.ID <= 0 {
+				err = errors.New("у голосования нет id")
+			}
+			if err == nil && voteIDs[record.ID] {
+				err = fmt.Errorf("голосование %d встречается дважды", record.ID)
+			}
+			voteIDs[record.ID] = true
+			votes = append(votes, record)
+		case typeResponse:
+			var record responseRecord
+			err = json.Unmarshal(data, &record)
+			responses = append(responses, models.Response{
+				UserID: record.UserID,
+				VoteID: record.VoteID,
+				Result: record.Result,
+				Date:   record.Date,
+			})
+		default:
+			err = fmt.Errorf("неизвестный тип строки %q", header.Type)
+		}
+		if err != nil {
+			return counts, fmt.Errorf("строка %d: %s", line, err.Error())
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return counts, err
+	}
+	for _, response := range responses {
+		if !voteIDs[response.VoteID] {
+			return counts, fmt.Errorf("ответ куратора %d на голосование %d, которого нет в выгрузке",
+				response.UserID, response.VoteID)
+		}
+	}
+	// выгрузка пишется одной транзакцией: при ошибке в базе не остаётся её части
+	err = store.Transaction(func(store storage.Storage) error {
+		return save(store, credentials, referrals, votes, responses, actor, &counts)
+	})
+	if err != nil {
+		return Counts{}, err
+	}
+	return counts, nil
+}
+
+// save записывает проверенную выгрузку в пустое хранилище и считает записанные строки
+func save(store storage.Storage, credentials []models.Credential, referrals []models.Referral, votes []voteRecord,
+	responses []models.Response, actor models.Actor, counts *Counts) error {
+	empty, err := isEmpty(store)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return errors.New("загружать выгрузку можно только в пустую базу")
+	}
+
+	for _, credential := range credentials {
+		err = store.SaveCredential(credential, actor)
+		if err != nil {
+			return err
+		}
+		counts.Credentials++
+	}
+	for _, referral := range referrals {
+		err = store.SaveReferral(referral, actor)
+		if err != nil {
+			return err
+		}
+		counts.Referrals++
+	}
+	for _, record := range votes {
+		_, err = store.SaveVote(models.Vote{
+			VoteID:     record.ID,
+			UserID:     record.UserID,
+			Author:     record.Author,
+			Permalink:  record.Permalink,
+			Percent:    record.Percent,
+			Completed:  record.Completed,
+			Rejected:   record.Rejected,
+			Addled:     record.Addled,
+			Plagiarism: record.Plagiarism,
+			Closed:     record.Closed,
+			Date:       record.Date,
+		}, actor)
+		if err != nil {
+			return err
+		}
+		err = store.SaveVoteTags(record.ID, record.Tags)
+		if err != nil {
+			return err
+		}
+		counts.Votes++
+	}
+	for _, response := range responses {
+		err = store.SaveResponse(response, actor)
+		if err != nil {
+			return err
+		}
+		counts.Responses++
+	}
+	return nil
+}
+
+func isEmpty(store storage.Storage) (bool, error) {
+	credentials, err := store.GetAllCredentials()
+	if err != nil || len(credentials) > 0 {
+		return false, err
+	}
+	referrals, err := store.GetAllReferrals()
+	if err != nil || len(referrals) > 0 {
+		return false, err
+	}
+	votes, err := store.GetVotesBetween(time.Time{}, forever)
+	if err != nil || len(votes) > 0 {
+		return false, err
+	}
+	responses, err := store.GetResponsesBetween(time.Time{}, forever)
+	if err != nil || len(responses) > 0 {
+		return false, err
+	}
+	return true, nil
+}
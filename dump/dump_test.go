@@ -0,0 +1,196 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/storage"
+)
+
+var testActor = models.SystemActor("test")
+
+func newStore(t *testing.T) storage.Storage {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return storage.NewSQLite(database)
+}
+
+func fill(t *testing.T, store storage.Storage, now time.Time) {
+	store.SaveCredential(models.Credential{UserID: 1, ChatID: 10, UserName: "chiliec", Power: 100, Active: true, Curates: true}, testActor)
+	store.SaveCredential(models.Credential{UserID: 2, ChatID: 20, UserName: "babin", Power: 50}, testActor)
+	store.SaveReferral(models.Referral{UserID: 2, Referrer: "chiliec", UserName: "babin",
+		Status: models.ReferralPending, Deadline: now.Add(time.Hour)}, testActor)
+	// голосования с пропуском в id: после загрузки ответы должны указывать на те же голосования
+	for _, vote := range []models.Vote{
+		{VoteID: 3, UserID: 2, Author: "babin", Permalink: "old", Percent: 100, Completed: true, Date: now.Add(-48 * time.Hour)},
+		{VoteID: 7, UserID: 2, Author: "babin", Permalink: "new", Percent: 100, Date: now},
+	} {
+		_, err := store.SaveVote(vote, testActor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SaveVoteTags(7, []string{"golos", "art"})
+	store.SaveResponse(models.Response{UserID: 1, VoteID: 3, Result: true, Date: now.Add(-47 * time.Hour)}, testActor)
+	store.SaveResponse(models.Response{UserID: 1, VoteID: 7, Result: false, Date: now}, testActor)
+}
+
+func TestExportImport(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	source := newStore(t)
+	fill(t, source, now)
+
+	var buffer bytes.Buffer
+	count, err := Export(&buffer, source, Options{Format: FormatJSONL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 7 {
+		t.Errorf("выгружено %d строк вместо 7", count)
+	}
+	if strings.Contains(buffer.String(), "posting") {
+		t.Error("в выгрузку попали ключи")
+	}
+
+	target := newStore(t)
+	counts, err := Import(bytes.NewReader(buffer.Bytes()), target, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Counts{Credentials: 2, Referrals: 1, Votes: 2, Responses: 2}
+	if counts != expected {
+		t.Errorf("загружено %#v вместо %#v", counts, expected)
+	}
+	vote := target.GetVote(7)
+	if vote.Permalink != "new" || !vote.Date.Equal(now) {
+		t.Errorf("голосование сменило id или дату: %#v", vote)
+	}
+	if tags, _ := target.GetVoteTags(7); len(tags) != 2 {
+		t.Errorf("теги %v вместо двух", tags)
+	}
+	responses, _ := target.GetAllResponsesForVoteID(3)
+	if len(responses) != 1 || !responses[0].Result {
+		t.Errorf("неожиданные ответы %#v", responses)
+	}
+	if credential, _ := target.GetCredentialByUserName("chiliec"); !credential.Curates || credential.ChatID != 10 {
+		t.Errorf("неожиданный аккаунт %#v", credential)
+	}
+	if referral, _ := target.GetReferralByUserID(2); !referral.Deadline.Equal(now.Add(time.Hour)) {
+		t.Errorf("неожиданный реферал %#v", referral)
+	}
+	// новые голосования не должны занимать id загруженных
+	id, err := target.SaveVote(models.Vote{UserID: 1, Author: "chiliec", Permalink: "next", Date: now}, testActor)
+	if err != nil || id <= 7 {
+		t.Errorf("новое голосование получило id %d, ошибка %v", id, err)
+	}
+
+	_, err = Import(bytes.NewReader(buffer.Bytes()), target, testActor)
+	if err == nil {
+		t.Error("выгрузку загрузили в непустую базу")
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	store := newStore(t)
+	fill(t, store, now)
+
+	var buffer bytes.Buffer
+	options := Options{Format: FormatCSV, Entities: []string{EntityVotes}, Since: now.Add(-24 * time.Hour)}
+	count, err := Export(&buffer, store, options)
+	if err != nil || count != 1 {
+		t.Fatalf("выгружено %d голосований вместо 1, ошибка %v", count, err)
+	}
+	rows, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][0] != "id" || rows[1][0] != "7" || len(rows[1][11]) != len(`["golos","art"]`) {
+		t.Errorf("неожиданный CSV %v", rows)
+	}
+
+	options = Options{Format: FormatCSV, Entities: []string{EntityResponses}, Until: now.Add(-24 * time.Hour)}
+	buffer.Reset()
+	count, err = Export(&buffer, store, options)
+	if err != nil || count != 1 {
+		t.Errorf("выгружено %d ответов вместо 1, ошибка %v", count, err)
+	}
+
+	_, err = Export(&buffer, store, Options{Format: FormatCSV})
+	if err == nil {
+		t.Error("в CSV выгрузили несколько сущностей сразу")
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dumps := map[string]string{
+		"неизвестный тип":       `{"type":"cast"}`,
+		"повтор голосования":    `{"type":"vote","id":1}` + "\n" + `{"type":"vote","id":1}`,
+		"голосование без id":    `{"type":"vote"}`,
+		"ответ без голосования": `{"type":"vote","id":1}` + "\n" + `{"type":"response","user_id":1,"vote_id":2}`,
+		"не JSON": `vote,1`,
+	}
+	for name, dump := range dumps {
+		store := newStore(t)
+		if _, err := Import(strings.NewReader(dump), store, testActor); err == nil {
+			t.Errorf("%s: выгрузку загрузили без ошибки", name)
+		}
+		if votes, _ := store.GetAllOpenedVotes(); len(votes) != 0 {
+			t.Errorf("%s: ошибочная выгрузка частично записана", name)
+		}
+	}
+}
+
+// failingStore отказывается записывать ответы кураторов, чтобы загрузка сорвалась посередине
+type failingStore struct {
+	storage.Storage
+}
+
+func (store failingStore) Transaction(do func(store storage.Storage) error) error {
+	return store.Storage.Transaction(func(tx storage.Storage) error {
+		return do(failingStore{tx})
+	})
+}
+
+func (store failingStore) SaveResponse(response models.Response, actor models.Actor) error {
+	return errors.New("сбой записи")
+}
+
+func TestImportRollback(t *testing.T) {
+	source := newStore(t)
+	fill(t, source, time.Now().UTC().Truncate(time.Second))
+	var buffer bytes.Buffer
+	_, err := Export(&buffer, source, Options{Format: FormatJSONL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := newStore(t)
+	if _, err = Import(&buffer, failingStore{target}, testActor); err == nil {
+		t.Fatal("сбой записи должен прервать загрузку")
+	}
+	if empty, err := isEmpty(target); !empty || err != nil {
+		t.Errorf("после сбоя в базе осталась часть выгрузки, ошибка %v", err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := ParseDate("2017-12-31")
+	if err != nil || !date.Equal(time.Date(2017, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("дата %s, ошибка %v", date, err)
+	}
+	if date, err = ParseDate(""); err != nil || !date.IsZero() {
+		t.Errorf("пустая дата %s, ошибка %v", date, err)
+	}
+	if _, err = ParseDate("31.12.2017"); err == nil {
+		t.Error("дату не в формате ГГГГ-ММ-ДД приняли")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/helpers"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func removeUser(bot *tgbotapi.BotAPI, chatID int64, userID int) error {
+	memberConfig := tgbotapi.KickChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: chatID,
+			UserID: userID,
+		},
+		UntilDate: 0,
+	}
+	_, err := bot.KickChatMember(memberConfig)
+	if err != nil {
+		return err
+	}
+	// исключение из группы не должно быть вечным: делегировав права, можно вернуться
+	_, err = bot.UnbanChatMember(memberConfig.ChatMemberConfig)
+	return err
+}
+
+// processGroupMessage следит за составом группы кураторов
+func processGroupMessage(message *tgbotapi.Message) error {
+	if message.NewChatMembers != nil {
+		for _, user := range *message.NewChatMembers {
+			if user.IsBot {
+				continue
+			}
+			err := trackMember(user, "group.welcome")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if message.LeftChatMember != nil {
+		return store.DeleteMember(message.LeftChatMember.ID, models.SystemActor("members"))
+	}
+	if message.From == nil || message.From.IsBot {
+		return nil
+	}
+	// участники, вступившие до появления проверки, попадают в список, когда что-нибудь напишут
+	_, err := store.GetMemberByUserID(message.From.ID)
+	if err != sql.ErrNoRows {
+		return err
+	}
+	return trackMember(*message.From, "group.verify")
+}
+
+// trackMember запоминает участника группы. Без активного аккаунта у него есть
+// config.GroupGraceMinutes минут, чтобы делегировать права, иначе его удалит membershipPolice,
+// поэтому бот сразу присылает в группу текст key с инструкцией
+func trackMember(user tgbotapi.User, key string) error {
+	member := models.Member{UserID: user.ID, Verified: isAllowedMember(user.ID), Since: time.Now()}
+	err := store.SaveMember(member, models.SystemActor("members"))
+	if err != nil || member.Verified {
+		return err
+	}
+	text := i18n.T(i18n.Default, key, user.FirstName, config.TelegramBotName, config.GroupGraceMinutes)
+	return outbox.Send(tgbotapi.NewMessage(config.GroupID, text))
+}
+
+// trackRevokedMember начинает следить за участником группы, который лишился доступа, ещё не попав
+// в список: Telegram не даёт боту перечислить участников, но позволяет спросить про одного
+func trackRevokedMember(userID int) {
+	if config.GroupID == 0 {
+		return
+	}
+	if _, err := store.GetMemberByUserID(userID); err != sql.ErrNoRows {
+		return
+	}
+	chatMember, err := bot.GetChatMember(tgbotapi.ChatConfigWithUser{ChatID: config.GroupID, UserID: userID})
+	if err != nil {
+		log.Printf("Не узнали, состоит ли пользователь %d в группе: %s", userID, err.Error())
+		return
+	}
+	if !chatMember.IsMember() || chatMember.User == nil {
+		return
+	}
+	err = trackMember(*chatMember.User, "group.verify")
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// isAllowedMember сообщает, может ли пользователь оставаться в группе кураторов
+func isAllowedMember(userID int) bool {
+	if isAdmin(userID) || store.IsActiveCredential(userID) {
+		return true
+	}
+	for _, allowed := range config.GroupAllowlist {
+		if allowed == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Неудавшееся удаление из группы повторяется всё реже, а после maxRemovalAttempts попыток
+// бот сдаётся и просит администраторов удалить участника вручную
+const (
+	maxRemovalAttempts = 8
+	removalRetryDelay  = time.Minute
+	removalMaxDelay    = 24 * time.Hour
+)
+
+// membershipPolice удаляет из группы тех, у кого дольше отведённого времени нет активного аккаунта
+func membershipPolice() {
+	if config.GroupID == 0 {
+		return
+	}
+	grace := time.Duration(config.GroupGraceMinutes) * time.Minute
+	for {
+		members, err := store.GetAllMembers()
+		if err != nil {
+			log.Println(err.Error())
+		}
+		now := time.Now()
+		for _, member := range members {
+			verified := isAllowedMember(member.UserID)
+			if verified != member.Verified {
+				// отсрочка отсчитывается с момента, когда пользователь лишился доступа
+				member.Verified = verified
+				member.Since = now
+				member.Attempts = 0
+				member.RetryAt = time.Time{}
+				err = store.SaveMember(member, models.SystemActor("members"))
+				if err != nil {
+					log.Println(err.Error())
+				}
+				continue
+			}
+			if verified || now.Sub(member.Since) < grace {
+				continue
+			}
+			if member.Attempts < maxRemovalAttempts && !now.Before(member.RetryAt) {
+				removeMember(member, now)
+			}
+		}
+		time.Sleep(time.Minute)
+	}
+}
+
+func removeMember(member models.Member, now time.Time) {
+	userID := member.UserID
+	reason := "нет активного аккаунта"
+	if credentials, err := store.GetCredentialsByUserID(userID); err == nil && len(credentials) > 0 {
+		reason = "делегирование отозвано"
+	}
+	err := removeUser(bot, config.GroupID, userID)
+	if err != nil {
+		failedRemoval(member, err, now)
+		return
+	}
+	log.Printf("Удалили пользователя %d из группы: %s", userID, reason)
+	err = store.DeleteMember(userID, models.SystemActor("members"))
+	if err != nil {
+		log.Println(err.Error())
+	}
+	err = store.SaveRemoval(models.Removal{UserID: userID, Reason: reason, Date: time.Now()})
+	if err != nil {
+		log.Println("не записали удаление из группы: " + err.Error())
+	}
+}
+
+// failedRemoval запоминает неудачную попытку удалить участника и откладывает следующую.
+// Если Telegram не даст удалить его никогда, бот сразу сдаётся
+func failedRemoval(member models.Member, removalErr error, now time.Time) {
+	member.Attempts++
+	member.RetryAt = now.Add(helpers.RetryDelay(member.Attempts, removalRetryDelay, removalMaxDelay))
+	if helpers.CannotRemoveMember(removalErr) || member.Attempts >= maxRemovalAttempts {
+		member.Attempts = maxRemovalAttempts
+		notifyAdmins("admin.removal_failed", member.UserID, removalErr.Error())
+	} else {
+		log.Printf("Не удалили пользователя %d из группы, попробуем снова после %s: %s",
+			member.UserID, member.RetryAt.Format("15:04"), removalErr.Error())
+	}
+	err := store.SaveMember(member, models.SystemActor("members"))
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
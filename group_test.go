@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/dispatcher"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestIsAllowedMember(t *testing.T) {
+	setUp(t)
+	config.GroupAllowlist = []int{3}
+	err := store.SaveCredential(models.Credential{UserID: 2, UserName: "chiliec", Active: true}, models.UserActor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []int{1, 2, 3} {
+		if !isAllowedMember(userID) {
+			t.Errorf("пользователь %d может быть в группе", userID)
+		}
+	}
+	if isAllowedMember(4) {
+		t.Error("пользователю без аккаунта не место в группе")
+	}
+}
+
+func TestFailedRemoval(t *testing.T) {
+	setUp(t)
+	outbox = dispatcher.New(nil, dispatcher.NewDatabaseStore(store))
+	now := time.Now()
+	member := models.Member{UserID: 4, Since: now.Add(-time.Hour)}
+	failedRemoval(member, errors.New("Internal Server Error"), now)
+	member, err := store.GetMemberByUserID(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Attempts != 1 || !member.RetryAt.After(now) {
+		t.Errorf("следующую попытку нужно отложить: %#v", member)
+	}
+	failedRemoval(member, errors.New("Bad Request: not enough rights to restrict/unrestrict chat member"), now)
+	member, err = store.GetMemberByUserID(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Attempts != maxRemovalAttempts {
+		t.Errorf("без прав администратора пытаться дальше бесполезно: %#v", member)
+	}
+	if outbox.Pending() != len(config.Admins) {
+		t.Errorf("администраторов нужно предупредить один раз, сообщений %d", outbox.Pending())
+	}
+}
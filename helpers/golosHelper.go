@@ -1,14 +1,19 @@
 package helpers
 
 import (
-	"database/sql"
+	"encoding/json"
 	"log"
+	"math"
+	"strings"
 	"sync"
+	"time"
 
 	golosClient "github.com/asuleymanov/golos-go/client"
+	"github.com/asuleymanov/golos-go/types"
 
 	configuration "github.com/GolosTools/golos-vote-bot/config"
 	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/storage"
 )
 
 func SendComment(author, permalink, text string, config configuration.Config) error {
@@ -26,8 +31,8 @@ func SendComment(author, permalink, text string, config configuration.Config) er
 	return err
 }
 
-func Vote(vote models.Vote, database *sql.DB, config configuration.Config) (successVotesCount int, err error) {
-	credentials, err := models.GetAllActiveCredentials(database)
+func Vote(vote models.Vote, actor models.Actor, store storage.Storage, config configuration.Config) (successVotesCount int, err error) {
+	credentials, err := store.GetAllActiveCredentials()
 	if err != nil {
 		return 0, err
 	}
@@ -37,7 +42,8 @@ func Vote(vote models.Vote, database *sql.DB, config configuration.Config) (succ
 		}
 	}
 	log.Printf("Голосую за пост %s/%s, загружено %d аккаунтов", vote.Author, vote.Permalink, len(credentials))
-	var errors []error
+	var casts []models.Cast
+	var mutex sync.Mutex
 	var wg sync.WaitGroup
 	wg.Add(len(credentials))
 	for _, credential := range credentials {
@@ -49,16 +55,144 @@ func Vote(vote models.Vote, database *sql.DB, config configuration.Config) (succ
 			err := golos.Vote(credential.UserName, vote.Author, vote.Permalink, weight)
 			if err != nil {
 				log.Println("Ошибка при голосовании: " + err.Error())
-				errors = append(errors, err)
+				return
 			}
+			mutex.Lock()
+			casts = append(casts, models.Cast{
+				VoteID:    vote.VoteID,
+				UserName:  credential.UserName,
+				Author:    vote.Author,
+				Permalink: vote.Permalink,
+				Weight:    weight,
+				Date:      time.Now(),
+			})
+			mutex.Unlock()
 		}(credential)
 	}
 	wg.Wait()
-	successVotesCount = len(credentials) - len(errors)
+	successVotesCount = len(casts)
+	for _, cast := range casts {
+		err = store.SaveCast(cast)
+		if err != nil {
+			log.Println("не сохранили голос аккаунта: " + err.Error())
+		}
+	}
 	vote.Completed = true
-	_, err = vote.Save(database)
+	_, err = store.SaveVote(vote, actor)
 	if err != nil {
 		return successVotesCount, err
 	}
 	return successVotesCount, nil
 }
+
+// HasPostingAuthority проверяет, что account может голосовать от имени владельца authority
+func HasPostingAuthority(authority *types.Authority, account string) bool {
+	if authority == nil {
+		return false
+	}
+	weight, ok := authority.AccountAuths[account]
+	return ok && weight >= int64(authority.WeightThreshold)
+}
+
+// historyPageSize — сколько операций истории аккаунта запрашивать за раз
+const historyPageSize = 1000
+
+// GetAccountHistorySince листает историю аккаунта от новых операций к старым, пока
+// не дойдёт до операций старше since или до начала истории. Нулевой since означает всю историю
+func GetAccountHistorySince(golos *golosClient.Client, account string, since time.Time) (history []*types.OperationObject, err error) {
+	from := uint64(math.MaxUint64)
+	for {
+		limit := uint64(historyPageSize)
+		if from < limit {
+			limit = from
+		}
+		raw, err := golos.Rpc.Database.Raw("get_account_history", []interface{}{account, from, limit})
+		if err != nil {
+			return history, err
+		}
+		first, page, err := decodeAccountHistory(*raw)
+		if err != nil || len(page) == 0 {
+			return history, err
+		}
+		history = append(page, history...)
+		oldest := page[0].Timestamp
+		if first == 0 || (oldest != nil && oldest.Time != nil && oldest.Before(since)) {
+			return history, nil
+		}
+		from = first - 1
+	}
+}
+
+// decodeAccountHistory разбирает ответ get_account_history — пары [номер, операция] —
+// и возвращает номер самой старой операции вместе с операциями
+func decodeAccountHistory(raw []byte) (first uint64, history []*types.OperationObject, err error) {
+	var entries [][2]json.RawMessage
+	err = json.Unmarshal(raw, &entries)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i, entry := range entries {
+		var number uint64
+		err = json.Unmarshal(entry[0], &number)
+		if err != nil {
+			return 0, nil, err
+		}
+		if i == 0 || number < first {
+			first = number
+		}
+		var object *types.OperationObject
+		err = json.Unmarshal(entry[1], &object)
+		if err != nil {
+			return 0, nil, err
+		}
+		history = append(history, object)
+	}
+	return first, history, nil
+}
+
+// HasOwnershipProof ищет в истории аккаунта перевод на recipient с кодом в заметке
+// или custom_json с кодом, подписанный активным ключом. Постинг-ключ не годится:
+// им владеет и сам бот
+func HasOwnershipProof(history []*types.OperationObject, account, recipient, code string) bool {
+	for _, object := range history {
+		if object == nil {
+			continue
+		}
+		switch op := object.Operation.(type) {
+		case *types.TransferOperation:
+			if op.From == account && op.To == recipient && strings.TrimSpace(op.Memo) == code {
+				return true
+			}
+		case *types.CustomJSONOperation:
+			if Contains(op.RequiredAuths, account) && strings.Contains(op.JSON, code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SumCurationRewards суммирует кураторские награды curator в VESTS
+// за посты, за которые голосовал бот
+func SumCurationRewards(history []*types.OperationObject, curator string, casts []models.Cast) (float64, error) {
+	posts := make(map[string]bool)
+	for _, cast := range casts {
+		posts[cast.Author+"/"+cast.Permalink] = true
+	}
+	var sum float64
+	for _, object := range history {
+		if object == nil {
+			continue
+		}
+		op, ok := object.Operation.(*types.CurationRewardOperation)
+		if !ok || op.Curator != curator || !posts[op.CommentAuthor+"/"+op.CommentPermlink] {
+			continue
+		}
+		reward, err := ParseAsset(op.Reward)
+		if err != nil {
+			return sum, err
+		}
+		sum += reward
+	}
+	return sum, nil
+}
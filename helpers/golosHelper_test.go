@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/asuleymanov/golos-go/types"
+
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestHasPostingAuthority(t *testing.T) {
+	authority := &types.Authority{
+		AccountAuths:    types.StringInt64Map{"golosovalochka": 1},
+		WeightThreshold: 1,
+	}
+	if !HasPostingAuthority(authority, "golosovalochka") {
+		t.Error("доступ должен быть")
+	}
+	if HasPostingAuthority(authority, "someone") {
+		t.Error("доступа не должно быть")
+	}
+	authority.WeightThreshold = 2
+	if HasPostingAuthority(authority, "golosovalochka") {
+		t.Error("веса недостаточно для доступа")
+	}
+	if HasPostingAuthority(nil, "golosovalochka") {
+		t.Error("доступа не должно быть")
+	}
+}
+
+func TestHasOwnershipProof(t *testing.T) {
+	code := "gvb-0123456789abcdef"
+	history := []*types.OperationObject{
+		{Operation: &types.TransferOperation{From: "chiliec", To: "golosovalochka", Memo: "привет"}},
+		{Operation: &types.CustomJSONOperation{RequiredPostingAuths: []string{"chiliec"}, JSON: `["claim","` + code + `"]`}},
+	}
+	if HasOwnershipProof(history, "chiliec", "golosovalochka", code) {
+		t.Error("подписи постинг-ключом недостаточно")
+	}
+	transfer := &types.OperationObject{Operation: &types.TransferOperation{From: "chiliec", To: "golosovalochka", Memo: code + " "}}
+	if !HasOwnershipProof(append(history, transfer), "chiliec", "golosovalochka", code) {
+		t.Error("перевод с кодом должен подтверждать владение")
+	}
+	if HasOwnershipProof(append(history, transfer), "someone", "golosovalochka", code) {
+		t.Error("перевод с чужого аккаунта не подтверждает владение")
+	}
+	customJSON := &types.OperationObject{Operation: &types.CustomJSONOperation{RequiredAuths: []string{"chiliec"}, JSON: `["claim","` + code + `"]`}}
+	if !HasOwnershipProof(append(history, customJSON, nil), "chiliec", "golosovalochka", code) {
+		t.Error("custom_json с активной подписью должен подтверждать владение")
+	}
+}
+
+func TestSumCurationRewards(t *testing.T) {
+	casts := []models.Cast{{UserName: "chiliec", Author: "babin", Permalink: "post"}}
+	history := []*types.OperationObject{
+		{Operation: &types.CurationRewardOperation{Curator: "chiliec", Reward: "1.500000 GESTS", CommentAuthor: "babin", CommentPermlink: "post"}},
+		{Operation: &types.CurationRewardOperation{Curator: "chiliec", Reward: "7.000000 GESTS", CommentAuthor: "babin", CommentPermlink: "other"}},
+		{Operation: &types.CurationRewardOperation{Curator: "someone", Reward: "3.000000 GESTS", CommentAuthor: "babin", CommentPermlink: "post"}},
+		{Operation: &types.TransferOperation{From: "chiliec", To: "babin"}},
+		nil,
+	}
+	sum, err := SumCurationRewards(history, "chiliec", casts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 1.5 {
+		t.Errorf("%f вместо 1.5", sum)
+	}
+}
+
+func TestDecodeAccountHistory(t *testing.T) {
+	raw := `[[41,{"block":1,"trx_id":"","trx_in_block":0,"op_in_trx":0,"virtual_op":0,"timestamp":"2018-01-02T03:04:05",` +
+		`"op":["transfer",{"from":"chiliec","to":"golosovalochka","amount":"0.001 GOLOS","memo":"gvb-1"}]}],` +
+		`[42,{"block":2,"trx_id":"","trx_in_block":0,"op_in_trx":0,"virtual_op":0,"timestamp":"2018-01-02T03:04:08",` +
+		`"op":["vote",{"voter":"chiliec","author":"babin","permlink":"post","weight":10000}]}]]`
+	first, history, err := decodeAccountHistory([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 41 || len(history) != 2 {
+		t.Fatalf("первая операция %d, всего %d", first, len(history))
+	}
+	if !HasOwnershipProof(history, "chiliec", "golosovalochka", "gvb-1") {
+		t.Error("перевод из истории не разобран")
+	}
+	if history[0].Timestamp == nil || history[0].Timestamp.Year() != 2018 {
+		t.Errorf("неожиданное время операции %v", history[0].Timestamp)
+	}
+	if _, _, err = decodeAccountHistory([]byte(`{"error":1}`)); err == nil {
+		t.Error("ошибочный ответ разобран")
+	}
+}
@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/asuleymanov/golos-go/apis/database"
+	"github.com/grokify/html-strip-tags-go"
+)
+
+// PostCard — сведения о посте, по которым куратор решает, стоит ли его читать
+type PostCard struct {
+	Author     string
+	Permalink  string
+	Title      string
+	Reputation int
+	Tags       []string
+	Length     int
+	Images     int
+	Payout     string
+	Votes      int
+	// Uniqueness — результат проверки на text.ru в процентах, пустой, если пост не проверяли
+	Uniqueness string
+	// Submitter — логин предложившего пост, пустой, если его не раскрывают
+	Submitter string
+}
+
+// NewPostCard собирает карточку из ответа GetContent
+func NewPostCard(post *database.Content) PostCard {
+	card := PostCard{
+		Author:    post.Author,
+		Permalink: post.Permlink,
+		Title:     strings.TrimSpace(post.Title),
+		Length:    TextLength(post.Body),
+		Images:    CountImages(post.Body),
+		Payout:    post.PendingPayoutValue,
+		Votes:     len(post.ActiveVotes),
+	}
+	if post.AuthorReputation != nil && post.AuthorReputation.Int != nil {
+		card.Reputation = Reputation(post.AuthorReputation.Int)
+	} else {
+		card.Reputation = Reputation(new(big.Int))
+	}
+	tags := []string{post.Category}
+	if post.JsonMetadata != nil {
+		tags = append(tags, post.JsonMetadata.Tags...)
+		if len(post.JsonMetadata.Image) > card.Images {
+			card.Images = len(post.JsonMetadata.Image)
+		}
+	}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if len(tag) > 0 && !Contains(card.Tags, tag) {
+			card.Tags = append(card.Tags, tag)
+		}
+	}
+	return card
+}
+
+// Reputation переводит сырую репутацию из блокчейна в привычную шкалу, где у новичка 25
+func Reputation(raw *big.Int) int {
+	if raw.Sign() == 0 {
+		return 25
+	}
+	value, _ := new(big.Float).SetInt(new(big.Int).Abs(raw)).Float64()
+	level := math.Max(math.Log10(value)-9, 0)
+	if raw.Sign() < 0 {
+		level = -level
+	}
+	return int(math.Floor(level*9 + 25))
+}
+
+var imageRegexp = regexp.MustCompile(`(?i)!\[[^\]]*\]\([^)]+\)|<img\s`)
+
+// CountImages считает картинки в тексте поста, вставленные через Markdown или HTML
+func CountImages(body string) int {
+	return len(imageRegexp.FindAllStringIndex(body, -1))
+}
+
+// TextLength возвращает длину текста поста в символах без HTML-разметки
+func TextLength(body string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(strip.StripTags(body)))
+}
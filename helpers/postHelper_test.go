@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/asuleymanov/golos-go/apis/database"
+	"github.com/asuleymanov/golos-go/types"
+)
+
+func TestReputation(t *testing.T) {
+	cases := map[int64]int{
+		0:                25,
+		1000000000:       25,
+		10000000000000:   61,
+		-10000000000000:  -11,
+		2510306004138452: 82,
+	}
+	for raw, expected := range cases {
+		if reputation := Reputation(big.NewInt(raw)); reputation != expected {
+			t.Errorf("репутация %d вместо %d для %d", reputation, expected, raw)
+		}
+	}
+}
+
+func TestCountImages(t *testing.T) {
+	body := "![первая](https://example.com/1.jpg) текст <img src=\"https://example.com/2.png\"> " +
+		"<IMG\nsrc=\"https://example.com/3.png\"> [ссылка](https://example.com)"
+	if count := CountImages(body); count != 3 {
+		t.Errorf("%d картинок вместо 3", count)
+	}
+}
+
+func TestNewPostCard(t *testing.T) {
+	post := &database.Content{
+		Author:             "chiliec",
+		Permlink:           "post",
+		Category:           "golos",
+		Title:              " Заголовок ",
+		Body:               "<p>Привет</p>",
+		PendingPayoutValue: "1.234 GBG",
+		ActiveVotes:        []*database.VoteState{{}, {}},
+		AuthorReputation:   &types.Int{Int: big.NewInt(10000000000000)},
+		JsonMetadata:       &database.ContentMetadata{Tags: []string{"golos", "путешествия"}, Image: []string{"https://example.com/1.jpg"}},
+	}
+	card := NewPostCard(post)
+	expected := PostCard{
+		Author:     "chiliec",
+		Permalink:  "post",
+		Title:      "Заголовок",
+		Reputation: 61,
+		Tags:       []string{"golos", "путешествия"},
+		Length:     6,
+		Images:     1,
+		Payout:     "1.234 GBG",
+		Votes:      2,
+	}
+	if !reflect.DeepEqual(card, expected) {
+		t.Errorf("\n%#v\n%#v\nНе равны!", card, expected)
+	}
+}
@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/asuleymanov/golos-go/types"
+
+	"github.com/GolosTools/golos-vote-bot/i18n"
+)
+
+// ReferralCheck — сведения о пригласившем и приглашённом для поиска накруток
+type ReferralCheck struct {
+	Referrer         string
+	Invitee          string
+	Created          time.Time
+	Creator          string
+	ReferrerRecovery string
+	InviteeRecovery  string
+	// History — последние операции приглашённого
+	History       []*types.OperationObject
+	PaidThisMonth int
+	MonthlyCap    int
+	MinimumAge    int
+	Now           time.Time
+}
+
+// Suspicion — причина проверить выплату вручную: ключ текста в каталоге и его параметры
+type Suspicion struct {
+	Key    string
+	Params []interface{}
+}
+
+// Text описывает причину на языке lang
+func (suspicion Suspicion) Text(lang string) string {
+	return i18n.T(lang, suspicion.Key, suspicion.Params...)
+}
+
+// Suspicions возвращает причины, по которым выплату стоит проверить вручную
+func (check ReferralCheck) Suspicions() (reasons []Suspicion) {
+	if !check.Created.IsZero() && check.Now.Sub(check.Created) < time.Duration(check.MinimumAge)*24*time.Hour {
+		reasons = append(reasons, Suspicion{"suspicion.young", []interface{}{check.Created.Format("02.01.2006")}})
+	}
+	if check.Creator == check.Referrer {
+		reasons = append(reasons, Suspicion{"suspicion.created_by_referrer", nil})
+	}
+	if len(check.InviteeRecovery) > 0 && check.InviteeRecovery == check.ReferrerRecovery &&
+		check.InviteeRecovery != "golos" {
+		reasons = append(reasons, Suspicion{"suspicion.recovery", []interface{}{check.InviteeRecovery}})
+	}
+	if HasTransfersBetween(check.History, check.Referrer, check.Invitee) {
+		reasons = append(reasons, Suspicion{"suspicion.transfers", nil})
+	}
+	if check.MonthlyCap > 0 && check.PaidThisMonth >= check.MonthlyCap {
+		reasons = append(reasons, Suspicion{"suspicion.monthly_cap", []interface{}{check.PaidThisMonth}})
+	}
+	return reasons
+}
+
+// AccountCreator ищет в истории аккаунта операцию его создания
+func AccountCreator(history []*types.OperationObject) string {
+	for _, object := range history {
+		if object == nil {
+			continue
+		}
+		switch op := object.Operation.(type) {
+		case *types.AccountCreateOperation:
+			return op.Creator
+		case *types.AccountCreateWithDelegationOperation:
+			return op.Creator
+		}
+	}
+	return ""
+}
+
+// HasTransfersBetween сообщает о переводах между двумя аккаунтами в любую сторону
+func HasTransfersBetween(history []*types.OperationObject, first, second string) bool {
+	for _, object := range history {
+		if object == nil {
+			continue
+		}
+		op, ok := object.Operation.(*types.TransferOperation)
+		if !ok {
+			continue
+		}
+		if (op.From == first && op.To == second) || (op.From == second && op.To == first) {
+			return true
+		}
+	}
+	return false
+}
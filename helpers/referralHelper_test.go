@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asuleymanov/golos-go/types"
+)
+
+func TestReferralCheck_Suspicions(t *testing.T) {
+	now := time.Now()
+	honest := ReferralCheck{
+		Referrer:         "chiliec",
+		Invitee:          "newbie",
+		Created:          now.AddDate(0, -1, 0),
+		Creator:          "golos",
+		ReferrerRecovery: "golos",
+		InviteeRecovery:  "golos",
+		MonthlyCap:       5,
+		MinimumAge:       7,
+		Now:              now,
+	}
+	if reasons := honest.Suspicions(); len(reasons) != 0 {
+		t.Errorf("честный реферал под подозрением: %v", reasons)
+	}
+	sockpuppet := honest
+	sockpuppet.Created = now.Add(-time.Hour)
+	sockpuppet.Creator = "chiliec"
+	sockpuppet.ReferrerRecovery = "friend"
+	sockpuppet.InviteeRecovery = "friend"
+	sockpuppet.History = []*types.OperationObject{
+		{Operation: &types.TransferOperation{From: "chiliec", To: "newbie"}},
+	}
+	sockpuppet.PaidThisMonth = 5
+	reasons := sockpuppet.Suspicions()
+	if len(reasons) != 5 {
+		t.Errorf("найдено %d подозрений вместо 5: %v", len(reasons), reasons)
+	}
+	for _, reason := range reasons {
+		if text := reason.Text("en"); text == reason.Key {
+			t.Errorf("нет текста причины %s", reason.Key)
+		}
+	}
+}
+
+func TestAccountCreator(t *testing.T) {
+	history := []*types.OperationObject{
+		{Operation: &types.AccountCreateWithDelegationOperation{Creator: "chiliec", NewAccountName: "newbie"}},
+		{Operation: &types.TransferOperation{From: "chiliec", To: "newbie"}},
+	}
+	if creator := AccountCreator(history); creator != "chiliec" {
+		t.Errorf("создатель %s вместо chiliec", creator)
+	}
+	if creator := AccountCreator(history[1:]); creator != "" {
+		t.Errorf("создатель не должен быть найден, получили %s", creator)
+	}
+}
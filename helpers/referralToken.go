@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Telegram принимает в параметре start не больше 64 символов
+	maxReferralTokenLength = 64
+	referralSignatureSize  = 6
+	MaxCampaignLength      = 12
+)
+
+var campaignRegexp = regexp.MustCompile("^[a-z0-9-]*$")
+
+// ErrNoReferralSecret — без секрета подпись ничего не защищает, поэтому ссылки не создаются и не принимаются
+var ErrNoReferralSecret = errors.New("реферальная программа отключена: не задан referral_secret")
+
+// ReferralToken — содержимое подписанной реферальной ссылки
+type ReferralToken struct {
+	Referrer string
+	Campaign string
+	// Expires — нулевое значение означает бессрочную ссылку
+	Expires time.Time
+}
+
+func referralSignature(payload []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)[:referralSignatureSize]
+}
+
+// NormalizeCampaign приводит название кампании к виду, допустимому в ссылке
+func NormalizeCampaign(campaign string) (string, error) {
+	campaign = strings.ToLower(strings.TrimSpace(campaign))
+	if len(campaign) > MaxCampaignLength || !campaignRegexp.MatchString(campaign) {
+		return "", errors.New("название кампании может содержать до 12 латинских букв, цифр и дефисов")
+	}
+	return campaign, nil
+}
+
+// SignReferralToken упаковывает токен в строку для параметра start
+func SignReferralToken(token ReferralToken, secret string) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrNoReferralSecret
+	}
+	campaign, err := NormalizeCampaign(token.Campaign)
+	if err != nil {
+		return "", err
+	}
+	expires := ""
+	if !token.Expires.IsZero() {
+		expires = strconv.FormatInt(token.Expires.Unix(), 36)
+	}
+	payload := []byte(token.Referrer + "|" + campaign + "|" + expires)
+	encoded := base64.RawURLEncoding.EncodeToString(append(payload, referralSignature(payload, secret)...))
+	if len(encoded) > maxReferralTokenLength {
+		return "", errors.New("реферальная ссылка получается слишком длинной")
+	}
+	return encoded, nil
+}
+
+// ParseReferralToken проверяет подпись и срок действия токена
+func ParseReferralToken(encoded string, secret string, now time.Time) (token ReferralToken, err error) {
+	if len(secret) == 0 {
+		return token, ErrNoReferralSecret
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return token, err
+	}
+	if len(raw) <= referralSignatureSize {
+		return token, errors.New("слишком короткий реферальный токен")
+	}
+	payload, signature := raw[:len(raw)-referralSignatureSize], raw[len(raw)-referralSignatureSize:]
+	if !hmac.Equal(signature, referralSignature(payload, secret)) {
+		return token, errors.New("неверная подпись реферального токена")
+	}
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 {
+		return token, errors.New("повреждённый реферальный токен")
+	}
+	token.Referrer, token.Campaign = parts[0], parts[1]
+	if len(parts[2]) > 0 {
+		expires, err := strconv.ParseInt(parts[2], 36, 64)
+		if err != nil {
+			return token, err
+		}
+		token.Expires = time.Unix(expires, 0)
+		if now.After(token.Expires) {
+			return token, errors.New("срок действия реферальной ссылки истёк")
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReferralToken(t *testing.T) {
+	now := time.Now()
+	token := ReferralToken{
+		Referrer: "sixteen-chars-ok",
+		Campaign: "Summer-2018",
+		Expires:  now.Add(time.Hour),
+	}
+	encoded, err := SignReferralToken(token, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) > 64 {
+		t.Errorf("токен длиной %d не поместится в ссылку", len(encoded))
+	}
+	parsed, err := ParseReferralToken(encoded, "secret", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Referrer != token.Referrer || parsed.Campaign != "summer-2018" || parsed.Expires.Unix() != token.Expires.Unix() {
+		t.Errorf("\n%#v\n%#v\nНе равны!", token, parsed)
+	}
+	if _, err = ParseReferralToken(encoded, "other", now); err == nil {
+		t.Error("токен с чужой подписью не должен приниматься")
+	}
+	if _, err = ParseReferralToken(encoded, "secret", now.Add(2*time.Hour)); err == nil {
+		t.Error("просроченный токен не должен приниматься")
+	}
+	forged := []byte(encoded)
+	forged[0] ^= 1
+	if _, err = ParseReferralToken(string(forged), "secret", now); err == nil {
+		t.Error("изменённый токен не должен приниматься")
+	}
+	if _, err = SignReferralToken(ReferralToken{Referrer: "chiliec", Campaign: "кампания"}, "secret"); err == nil {
+		t.Error("кириллица в названии кампании недопустима")
+	}
+	endless, err := SignReferralToken(ReferralToken{Referrer: "chiliec"}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = ParseReferralToken(endless, "secret", now.AddDate(10, 0, 0))
+	if err != nil || !parsed.Expires.IsZero() {
+		t.Error("бессрочный токен должен действовать всегда")
+	}
+}
+
+func TestReferralTokenWithoutSecret(t *testing.T) {
+	if _, err := SignReferralToken(ReferralToken{Referrer: "chiliec"}, ""); err != ErrNoReferralSecret {
+		t.Errorf("ссылку подписали пустым секретом, ошибка %v", err)
+	}
+	// токен, подписанный пустым ключом, подделает кто угодно
+	forged, err := SignReferralToken(ReferralToken{Referrer: "chiliec"}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseReferralToken(forged, "", time.Now()); err != ErrNoReferralSecret {
+		t.Errorf("токен принят без секрета, ошибка %v", err)
+	}
+}
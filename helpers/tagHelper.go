@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/asuleymanov/golos-go/translit"
+)
+
+// NormalizeTag приводит тег к виду, в котором он хранится в блокчейне:
+// кириллица транслитерируется и получает префикс "ru--"
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tag = strings.TrimPrefix(tag, "#")
+	return translit.EncodeTag(tag)
+}
+
+// NormalizeTags нормализует теги, убирая пустые и повторяющиеся
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if len(tag) == 0 || Contains(normalized, tag) {
+			continue
+		}
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// FindTag ищет среди тегов поста первый, входящий в список (в любом написании)
+func FindTag(postTags []string, list []string) (string, bool) {
+	normalizedList := NormalizeTags(list)
+	for _, tag := range NormalizeTags(postTags) {
+		if Contains(normalizedList, tag) {
+			return tag, true
+		}
+	}
+	return "", false
+}
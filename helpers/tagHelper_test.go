@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"путешествия":       "ru--puteshestviya",
+		"#Путешествия ":     "ru--puteshestviya",
+		"ru--puteshestviya": "ru--puteshestviya",
+		"Art":               "art",
+	}
+	for tag, expected := range cases {
+		if normalized := NormalizeTag(tag); normalized != expected {
+			t.Errorf("тег %s нормализован в %s вместо %s", tag, normalized, expected)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags([]string{"golos", "", "путешествия", "ru--puteshestviya", "GOLOS"})
+	expected := []string{"golos", "ru--puteshestviya"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("%#v вместо %#v", tags, expected)
+	}
+}
+
+func TestFindTag(t *testing.T) {
+	postTags := []string{"ru--puteshestviya", "ru--foto"}
+	tag, found := FindTag(postTags, []string{"фото"})
+	if !found || tag != "ru--foto" {
+		t.Errorf("не нашли тег, получили %s", tag)
+	}
+	_, found = FindTag(postTags, []string{"test"})
+	if found {
+		t.Error("тег не должен быть найден")
+	}
+}
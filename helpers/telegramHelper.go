@@ -3,14 +3,18 @@ package helpers
 import (
 	"errors"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/i18n"
 )
 
-func GetVoteMarkup(voteID int64) tgbotapi.InlineKeyboardMarkup {
+func GetVoteMarkup(voteID int64, lang string) tgbotapi.InlineKeyboardMarkup {
 	stringVoteID := strconv.FormatInt(voteID, 10)
-	goodButton := tgbotapi.NewInlineKeyboardButtonData("👍Лайк", stringVoteID+"_good")
-	badButton := tgbotapi.NewInlineKeyboardButtonData("👎Дизлайк", stringVoteID+"_bad")
+	goodButton := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.like"), stringVoteID+"_good")
+	badButton := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.dislike"), stringVoteID+"_bad")
 	row := []tgbotapi.InlineKeyboardButton{badButton, goodButton}
 	markup := tgbotapi.InlineKeyboardMarkup{}
 	markup.InlineKeyboard = append(markup.InlineKeyboard, row)
@@ -50,3 +54,29 @@ func GetMessageID(update tgbotapi.Update) (int, error) {
 func GetInstantViewLink(author string, permalink string) string {
 	return "https://t.me/iv?url=https://goldvoice.club/" + "@" + author + "/" + permalink + "&rhash=70f46c6616076d"
 }
+
+// CannotRemoveMember сообщает, что Telegram никогда не даст удалить участника: у бота нет прав
+// администратора группы или участник сам её администратор. Повторять такую попытку бесполезно
+func CannotRemoveMember(err error) bool {
+	text := err.Error()
+	for _, reason := range []string{"not enough rights", "CHAT_ADMIN_REQUIRED", "can't remove chat owner",
+		"user is an administrator"} {
+		if strings.Contains(text, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryDelay — пауза перед следующей попыткой после attempts неудачных: base, затем вдвое дольше
+// с каждой неудачей, но не дольше max
+func RetryDelay(attempts int, base time.Duration, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
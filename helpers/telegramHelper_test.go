@@ -1,6 +1,10 @@
 package helpers
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestGetInstantViewLink(t *testing.T) {
 	author := "some-author"
@@ -11,3 +15,26 @@ func TestGetInstantViewLink(t *testing.T) {
 		t.Fatal("Неожиданная ссылка")
 	}
 }
+
+func TestCannotRemoveMember(t *testing.T) {
+	if !CannotRemoveMember(errors.New("Bad Request: not enough rights to restrict/unrestrict chat member")) {
+		t.Error("без прав администратора удалить участника нельзя")
+	}
+	if CannotRemoveMember(errors.New("Too Many Requests: retry after 5")) {
+		t.Error("попытку после паузы стоит повторить")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		20: time.Hour,
+	}
+	for attempts, expected := range cases {
+		if delay := RetryDelay(attempts, time.Minute, time.Hour); delay != expected {
+			t.Errorf("после %d попыток пауза %s вместо %s", attempts, delay, expected)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	golosClient "github.com/asuleymanov/golos-go/client"
+)
+
+const (
+	// contentConstant — константа кривой вознаграждений Голоса (n² + 2ns)
+	contentConstant = 2000000000000.0
+	// maxVoteDenom — голос силой 100% расходует пятидесятую часть заряда
+	maxVoteDenom = 50.0
+	fullPercent  = 10000.0
+)
+
+// VotingAccount — сведения об аккаунте, влияющие на вес его голоса
+type VotingAccount struct {
+	VestingShares          string `json:"vesting_shares"`
+	DelegatedVestingShares string `json:"delegated_vesting_shares"`
+	ReceivedVestingShares  string `json:"received_vesting_shares"`
+	VotingPower            int    `json:"voting_power"`
+}
+
+// RewardFund — состояние фонда вознаграждений, по которому оценивается стоимость голоса
+type RewardFund struct {
+	SteemPerMvest float64
+	RewardFund    float64
+	RewardShares2 float64
+	// Price — цена одного GOLOS в GBG по медианному курсу
+	Price float64
+}
+
+// ParseAsset возвращает числовую часть строки вида "123.456 GOLOS"
+func ParseAsset(asset string) (float64, error) {
+	if len(asset) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.Split(asset, " ")[0], 64)
+}
+
+// EffectiveVests — собственная Сила Голоса аккаунта в VESTS с учётом делегирования
+func (account VotingAccount) EffectiveVests() (float64, error) {
+	vesting, err := ParseAsset(account.VestingShares)
+	if err != nil {
+		return 0, err
+	}
+	delegated, err := ParseAsset(account.DelegatedVestingShares)
+	if err != nil {
+		return 0, err
+	}
+	received, err := ParseAsset(account.ReceivedVestingShares)
+	if err != nil {
+		return 0, err
+	}
+	return vesting - delegated + received, nil
+}
+
+// GolosPower переводит VESTS в Силу Голоса
+func (fund RewardFund) GolosPower(vests float64) float64 {
+	return vests * fund.SteemPerMvest / 1000000
+}
+
+// EstimateVoteValue оценивает в GBG голос с силой vests при заряде votingPower
+// и весе weight (оба в сотых долях процента) за пост без других голосов
+func EstimateVoteValue(vests float64, votingPower int, weight int, fund RewardFund) float64 {
+	if fund.RewardShares2 <= 0 || vests <= 0 {
+		return 0
+	}
+	usedPower := float64(votingPower) * float64(weight) / fullPercent / maxVoteDenom
+	rshares := vests * 1000000 * usedPower / fullPercent
+	claim := rshares * (rshares + 2*contentConstant)
+	return claim / fund.RewardShares2 * fund.RewardFund * fund.Price
+}
+
+// GetVotingAccount загружает аккаунт вместе с делегированием,
+// которого нет в структуре аккаунта golos-go
+func GetVotingAccount(golos *golosClient.Client, login string) (account VotingAccount, err error) {
+	raw, err := golos.Rpc.Database.Raw("get_accounts", []interface{}{[]string{login}})
+	if err != nil {
+		return account, err
+	}
+	var accounts []VotingAccount
+	err = json.Unmarshal([]byte(*raw), &accounts)
+	if err != nil {
+		return account, err
+	}
+	if len(accounts) != 1 {
+		return account, errors.New("не найден аккаунт " + login)
+	}
+	return accounts[0], nil
+}
+
+// GetRewardFund загружает параметры фонда вознаграждений и медианный курс
+func GetRewardFund(golos *golosClient.Client) (fund RewardFund, err error) {
+	properties, err := golos.Rpc.Database.GetDynamicGlobalProperties()
+	if err != nil {
+		return fund, err
+	}
+	fund.SteemPerMvest, err = golos.SteemPerMvest()
+	if err != nil {
+		return fund, err
+	}
+	fund.RewardFund, err = ParseAsset(properties.TotalRewardFundSteem)
+	if err != nil {
+		return fund, err
+	}
+	if properties.TotalRewardShares2 != nil && properties.TotalRewardShares2.Int != nil {
+		fund.RewardShares2, _ = new(big.Float).SetInt(properties.TotalRewardShares2.Int).Float64()
+	}
+	price, err := golos.Rpc.Database.GetCurrentMedianHistoryPrice()
+	if err != nil {
+		return fund, err
+	}
+	base, err := ParseAsset(price.Base)
+	if err != nil {
+		return fund, err
+	}
+	quote, err := ParseAsset(price.Quote)
+	if err != nil {
+		return fund, err
+	}
+	if quote > 0 {
+		fund.Price = base / quote
+	}
+	return fund, nil
+}
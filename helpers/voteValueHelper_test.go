@@ -0,0 +1,49 @@
+package helpers
+
+import "testing"
+
+func TestVotingAccount_EffectiveVests(t *testing.T) {
+	account := VotingAccount{
+		VestingShares:          "1000.000000 GESTS",
+		DelegatedVestingShares: "300.000000 GESTS",
+		ReceivedVestingShares:  "50.500000 GESTS",
+	}
+	vests, err := account.EffectiveVests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vests != 750.5 {
+		t.Errorf("%f вместо 750.5", vests)
+	}
+	_, err = VotingAccount{VestingShares: "abc GESTS"}.EffectiveVests()
+	if err == nil {
+		t.Error("ожидали ошибку разбора")
+	}
+}
+
+func TestEstimateVoteValue(t *testing.T) {
+	fund := RewardFund{
+		SteemPerMvest: 2000,
+		RewardFund:    100000,
+		RewardShares2: 1e30,
+		Price:         0.5,
+	}
+	if fund.GolosPower(1000000) != 2000 {
+		t.Errorf("неожиданная Сила Голоса %f", fund.GolosPower(1000000))
+	}
+	full := EstimateVoteValue(1000000, 10000, 10000, fund)
+	if full <= 0 {
+		t.Fatal("голос должен чего-то стоить")
+	}
+	half := EstimateVoteValue(1000000, 10000, 5000, fund)
+	if half >= full {
+		t.Error("голос с меньшим весом должен стоить меньше")
+	}
+	tired := EstimateVoteValue(1000000, 5000, 10000, fund)
+	if tired != half {
+		t.Errorf("заряд и вес должны влиять одинаково: %f и %f", tired, half)
+	}
+	if EstimateVoteValue(1000000, 10000, 10000, RewardFund{}) != 0 {
+		t.Error("без фонда голос ничего не стоит")
+	}
+}
@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	configuration "github.com/GolosTools/golos-vote-bot/config"
+)
+
+// WebhookPath — путь, по которому Telegram присылает обновления. Секрет в пути
+// не даёт посторонним подсовывать боту поддельные обновления
+func WebhookPath(secret string) string {
+	return "/" + secret
+}
+
+// CheckWebhookConfig проверяет настройки вебхука до запуска бота. Сертификат без ключа нельзя:
+// Telegram получил бы сертификат, а сервер слушал бы без TLS, и обновления не доходили бы
+func CheckWebhookConfig(config configuration.Config) error {
+	if len(config.WebhookURL) == 0 {
+		return nil
+	}
+	if len(config.WebhookSecret) == 0 {
+		return errors.New("для вебхука нужно задать webhook_secret")
+	}
+	if (len(config.WebhookCert) > 0) != (len(config.WebhookKey) > 0) {
+		return errors.New("webhook_cert и webhook_key задаются только вместе")
+	}
+	return nil
+}
+
+// WebhookHandler принимает обновления Telegram по секретному пути и передаёт их в updates
+func WebhookHandler(secret string, updates chan<- tgbotapi.Update) http.Handler {
+	path := []byte(WebhookPath(secret))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.URL.Path), path) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		var update tgbotapi.Update
+		err := json.NewDecoder(r.Body).Decode(&update)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates <- update
+	})
+}
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	configuration "github.com/GolosTools/golos-vote-bot/config"
+)
+
+func TestCheckWebhookConfig(t *testing.T) {
+	cases := []struct {
+		config configuration.Config
+		valid  bool
+	}{
+		{configuration.Config{}, true},
+		{configuration.Config{WebhookURL: "https://bot.example.com"}, false},
+		{configuration.Config{WebhookURL: "https://bot.example.com", WebhookSecret: "secret"}, true},
+		{configuration.Config{WebhookURL: "https://bot.example.com", WebhookSecret: "secret", WebhookCert: "cert.pem"}, false},
+		{configuration.Config{WebhookURL: "https://bot.example.com", WebhookSecret: "secret", WebhookKey: "key.pem"}, false},
+		{configuration.Config{WebhookURL: "https://bot.example.com", WebhookSecret: "secret",
+			WebhookCert: "cert.pem", WebhookKey: "key.pem"}, true},
+	}
+	for i, c := range cases {
+		if err := CheckWebhookConfig(c.config); (err == nil) != c.valid {
+			t.Errorf("случай %d: неожиданный результат %v", i, err)
+		}
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	updates := make(chan tgbotapi.Update, 1)
+	handler := WebhookHandler("secret", updates)
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"POST", "/wrong", `{"update_id": 1}`, http.StatusNotFound},
+		{"POST", "/", `{"update_id": 1}`, http.StatusNotFound},
+		{"GET", "/secret", "", http.StatusMethodNotAllowed},
+		{"POST", "/secret", "not json", http.StatusBadRequest},
+		{"POST", "/secret", `{"update_id": 42}`, http.StatusOK},
+	}
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if recorder.Code != c.status {
+			t.Errorf("%s %s: ожидали статус %d, получили %d", c.method, c.path, c.status, recorder.Code)
+		}
+	}
+	if len(updates) != 1 {
+		t.Fatalf("ожидали одно обновление, получили %d", len(updates))
+	}
+	if update := <-updates; update.UpdateID != 42 {
+		t.Errorf("неожиданное обновление %d", update.UpdateID)
+	}
+}
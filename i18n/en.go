@@ -0,0 +1,234 @@
+package i18n
+
+var english = map[string]string{
+	"button.add_key":          "🐬Delegate",
+	"button.remove_key":       "🦀Stop",
+	"button.power":            "💪Settings",
+	"button.information":      "⚓️Information",
+	"button.all_accounts":     "All accounts",
+	"button.start_curating":   "Become a curator",
+	"button.stop_curating":    "Stop curating",
+	"button.curating_approve": "🐬‍️I can do it",
+	"button.curating_decline": "🐡‍Too hard",
+	"button.claim_check":      "✅Check",
+	"button.restore_access":   "🐬Restore access",
+	"button.like":             "👍Like",
+	"button.dislike":          "👎Dislike",
+	"button.week":             "Week",
+	"button.month":            "Month",
+	"button.all_time":         "All time",
+	"button.language":         "English",
+	"button.previous":         "⬅️Back",
+	"button.next":             "Next➡️",
+
+	"start": "Hi, {0}! \n\n" +
+		"I am a bot for collective curation in the [Golos blockchain social network](https://golos.io).\n\n" +
+		"My code is fully open and lives here: {1}\n\n" +
+		"Start by pressing the \"{2}\" button, " +
+		"after that I will give you a link to the group where posts are suggested.\n\n" +
+		"If you have any questions, write to my master — {3}",
+	"start.username":  "%username%",
+	"not_understood":  "I don't understand",
+	"admins_only":     "This command is available to administrators only",
+	"rate_limited":    "Too many requests, wait a minute",
+	"delegate_first":  "First delegate me the rights with the {0} button",
+	"error.developer": "Something went wrong, contact the developer - {0}",
+	"error.try_later": "Something went wrong. Try again later " +
+		"or contact the developer: {0}",
+
+	"cancel.done":    "OK, let's forget about it",
+	"cancel.nothing": "There is nothing to cancel",
+
+	"language.choose": "Choose a language",
+	"language.set":    "Now I speak English",
+
+	"add_key.instructions": "Add *{0}* as a trusted account in " +
+		"[{1}]({2}) " +
+		"(or via [vik's form](https://golos.cf/multi/)), " +
+		"and then tell me your Golos login",
+	"add_key.no_access": "I have no access to this account. " +
+		"You can grant it at https://golos.cf/multi/ for the account *{0}*",
+	"add_key.success": "Congratulations, you are almost a full member now! " +
+		"To get all the perks you can become a curator as well. " +
+		"Join our group, it's fun there: {0}",
+	"claim.instructions": "Now prove that the account *{0}* is yours. " +
+		"Transfer 0.001 GOLOS to *{1}* with the memo `{2}` " +
+		"or broadcast a custom\\_json with this code signed by your active key. " +
+		"Then press the button below. The code is valid for 24 hours",
+	"claim.not_found": "Claim not found, add the account again with the {0} button",
+	"claim.expired":   "The confirmation code has expired, add the account again with the {0} button",
+	"claim.not_yet":   "I don't see the proof yet, try again in a minute",
+	"claim.lost": "The owner of the account {0} has confirmed it " +
+		"from another Telegram profile, so I unlinked it from you",
+
+	"account.not_active": "The account is not activated",
+	"remove_key.choose":  "Which account should I stop using?",
+	"remove_key.done": "Fine, I won't use this account for curation anymore. " +
+		"You can also remove all third-party keys from your account here: " +
+		"https://golos.cf/multi/off.html",
+	"revoke": "The account *{0}* no longer trusts me with its Golos Power, " +
+		"so I stopped voting with it.\n\n" +
+		"If this was an accident, add *{1}* as a trusted account again and tell me your login " +
+		"after pressing the \"{2}\" button.",
+	"revoke.curator": " Curation is paused and will come back together with the access.",
+
+	"power.prompt": "Enter the share of delegated Golos Power from 1 to 100%",
+	"power.prompt_account": "Enter the share of delegated Golos Power from 1 to 100% " +
+		"for the account *{0}*",
+	"power.prompt_choose": "Enter the share of delegated Golos Power from 1 to 100% " +
+		"for the account *{0}* or choose another account",
+	"power.not_understood": "I didn't get it. Enter the share of delegated Golos Power from 1 to 100%",
+	"power.updated": "The Golos Power at my disposal " +
+		"for the account *{0}* is now *{1}%*\n" +
+		"Account Golos Power including delegations: *{2} GOLOS*, " +
+		"with the current voting power a vote is worth about *{3} GBG*",
+	"power.too_small": "Your Golos Power is too small for this yet: " +
+		"a full vote is worth about {0} GBG, and at least {1} GBG is required",
+
+	"info.no_info":  "I have no information for you yet",
+	"info.account":  "Account: *{0}*, delegated power: *{1}%*{2}\n",
+	"info.disabled": " (disabled)",
+	"info": "{0}" +
+		"Trust: *{1}* (approved by curators: {2}%, expired: {3}%, " +
+		"plagiarism: {4}, account age: {5} days)",
+	"info.referral": "\nReferral link: [{0}]({1})\n" +
+		"(gives both of you {2} Golos Power, " +
+		"the invitee must have at least {3} posts " +
+		"and must not have interacted with the bot before the invitation)",
+
+	"post.too_early":    "Too little time has passed since your last post. Be patient!",
+	"post.banned_tag":   "Posts with the tag {0} are not allowed",
+	"post.allowed_tags": "I only accept posts with the tags: {0}",
+	"post.tag_quota": "There are too many open votes with the tag {0} already. " +
+		"Wait until they finish or suggest a post on another topic.",
+	"post.not_delegator":   "Only voting users can suggest posts. No cheating!",
+	"post.paid_out":        "This post has already been paid out! Got anything fresher?",
+	"post.payout_declined": "I'm not interested in voting for a post with declined payouts",
+	"post.too_many_votes": "There are too many open votes already. " +
+		"Wait until another post gets its votes or the freshness police removes expired posts.",
+	"post.too_many_user_votes": "You already have enough posts being voted on. Wait until curators rate them!",
+	"post.banned_user":         "You are not allowed to suggest posts",
+	"post.banned_author":       "Posts by this author are not accepted",
+	"post.vox_populi":          "Vox-populi communities can support themselves",
+	"post.too_short":           "Too little text, don't be stingy with letters!",
+	"post.duplicate":           "I have already voted for this post!",
+	"post.accepted":            "The post is up for voting.",
+	"post.new": "A new post means a new rating. Curate, curator\n\n" +
+		"*{0}*\n" +
+		"Author: [@{1}](https://golos.io/@{1}), reputation {2}\n" +
+		"Tags: {3}\n" +
+		"Length: {4} chars, images: {5}\n" +
+		"Payout: {6}, votes: {7}\n" +
+		"{8}{9}\n\n" +
+		"[Read]({10})",
+	"post.card.uniqueness": "Uniqueness: {0}%\n",
+	"post.card.submitter":  "Submitted by [{0}](https://golos.io/@{0})",
+	"post.card.anonymous":  "Submitted anonymously",
+
+	"group.welcome": "{0}, welcome! Only those who have delegated their Golos Power to me stay in this group. " +
+		"Message me privately at @{1} and press \"Delegate\", otherwise I will have to remove you in {2} min.",
+	"group.verify": "{0}, only those who have delegated their Golos Power to me stay in this group, and you have no active account. " +
+		"Message me privately at @{1} and press \"Delegate\", otherwise I will have to remove you in {2} min.",
+
+	"curating.already": "You are already a curator",
+	"curating.approved": "Great, now you will take part in curation. " +
+		"I will start sending you links soon, wait a bit",
+	"curating.declined": "Good choice. Curating other people's posts is a hard and thankless job. " +
+		"Better write your own posts and send me links to them, and let the curators do their work!",
+	"curating.stopped":     "The burden of curation has left you. When you have enjoyed your freedom, come back!",
+	"curating.not_curator": "What is dead may never die. You can't quit curation without being a curator",
+
+	"vote.not_curator": "Check your privileges. You are not a curator!",
+	"vote.repeated":    "May the Admin's punishment fall upon everyone who abuses their power and votes several times! Admen",
+	"vote.accepted":    "Vote accepted",
+	"vote.success":     "Successfully voted with {0} accounts for the post\n{1}",
+	"vote.error":       "An error occurred while voting, contact the developer - {0}\n{1}",
+	"vote.addled": "Sorry, {0}, your post ({1}/{2}) never got its votes. Next time write something " +
+		"better and the curators will surely appreciate it",
+	"vote.rejected": "The post {0}/{1} was rejected by the curators",
+
+	"my.empty":             "You haven't suggested any posts yet",
+	"my.title":             "Your posts {0}–{1} of {2}:\n\n",
+	"my.line":              "{0}. [{1}/{2}](https://golos.io/@{1}/{2}) — {3}, 👍 {4} 👎 {5}, accounts voted: {6}\n",
+	"my.status.open":       "voting in progress",
+	"my.status.supported":  "supported",
+	"my.status.rejected":   "rejected",
+	"my.status.addled":     "expired",
+	"my.status.plagiarism": "plagiarism",
+	"my.status.closed":     "closed by an admin",
+	"my.next_now":          "\nYou can suggest the next post right now",
+	"my.next_at":           "\nYou can suggest the next post after {0}",
+
+	"stats.week":  "for the week",
+	"stats.month": "for the month",
+	"stats.all":   "for all time",
+	"stats.title": "Statistics {0}:\n",
+	"stats.line":  "*{0}*: votes — {1}, total weight — {2}%, curation rewards — {3} GOLOS\n",
+
+	"invite": "Referral link: [{0}]({1})\n" +
+		"You can name a campaign after the command: /invite summer\n\n{2}",
+	"invite.error":              "Could not create the link: {0}",
+	"invite.disabled":           "The referral program is off at the moment",
+	"referral.stats_empty":      "Nobody has come via your links yet",
+	"referral.stats_title":      "Invited / registered / rewarded:\n",
+	"referral.stats_line":       "{0}, {1}: {2} / {3} / {4}\n",
+	"referral.no_campaign":      "no campaign",
+	"referral.already_reviewed": "The reward for {0} has already been reviewed",
+	"referral.approved":         "The reward for {0} is approved and will be paid",
+	"referral.rejected":         "The reward for {0} is rejected",
+	"referral.paid": "The referrer [@{0}](https://golos.io/@{0}/transfers) " +
+		"and the invitee [@{1}](https://golos.io/@{1}/transfers) get {2} Golos Power each under the referral program",
+
+	"button.referral_approve":       "Pay",
+	"button.referral_reject":        "Reject",
+	"admin.referral_review":         "The referral reward for {0} (invited by {1}) needs a review:\n- {2}",
+	"suspicion.young":               "the account was created on {0}",
+	"suspicion.created_by_referrer": "the account was created by the referrer",
+	"suspicion.recovery":            "shared recovery account {0}",
+	"suspicion.transfers":           "transfers between the referrer and the invitee",
+	"suspicion.monthly_cap":         "the referrer has already received {0} rewards this month",
+
+	"admin.claim_moved":    "The account {0} moved from user {1} to user {2} after ownership was proven",
+	"admin.claim_rejected": "The claim of user {0} to the account {1} is rejected: user {2} proved ownership",
+	"admin.claim_taken":    "User {0} is trying to link the account {1} already linked to user {2}",
+	"admin.claim_rivals":   "Users {1} and {2} both claim the account {0}",
+
+	"admin.failed":          "Failed: {0}",
+	"admin.unknown_command": "unknown command: {0}",
+	"admin.not_linked":      "the account {0} is not linked",
+	"admin.usage.ban":       "usage: /{0} user|author <login or ID>",
+	"admin.usage.vote":      "specify the vote number",
+	"admin.usage.broadcast": "usage: /broadcast <text>",
+	"admin.usage.user":      "usage: /user <login or ID>",
+	"admin.usage.audit":     "usage: /audit [{0} [key]] or /audit user:<ID>|admin:<ID>|system:<job>",
+	"admin.usage.export":    "usage: /export csv|jsonl [votes|responses|credentials|referrals|all] [from YYYY-MM-DD] [to YYYY-MM-DD]",
+	"admin.banned":          "{0} {1} is banned",
+	"admin.unbanned":        "{0} {1} is unbanned",
+	"admin.not_banned":      "{0} {1} was not banned",
+	"admin.vote_not_found":  "vote {0} not found",
+	"admin.closed":          "Vote {0} for the post {1}/{2} is closed",
+	"admin.reopened":        "Vote {0} for the post {1}/{2} is open again",
+	"admin.forcevote":       "Voting for the post {0}/{1}",
+	"admin.vote_completed":  "vote {0} is already completed",
+	"admin.vote_cast":       "accounts have already voted for the post of vote {0}, it cannot be reopened",
+	"admin.author_banned":   "the author {0} is banned",
+	"admin.broadcast":       "A broadcast is queued for {0} chats, failed for {1}",
+	"admin.curators":        "Active curators: {0} (ratings this month)\n",
+	"admin.curator":         "{0} ({1}): {2}\n",
+	"admin.queue_empty":     "No open votes",
+	"admin.queue":           "Open votes: {0}\n",
+	"admin.queue_line":      "{0}. {1}/{2}: +{3} −{4}, submitted by {5} {6}\n",
+	"admin.user":            "User {0}, trust {1}, open posts: {2}, banned: {3}\n",
+	"admin.user_account":    "{0}: chat {1}, power {2}%, active: {3}, curator: {4}\n",
+	"admin.removal_failed":  "Cannot remove user {0} from the group: {1}. I will stop trying, please remove them manually",
+	"admin.removals_empty":  "Nobody has been removed from the group yet",
+	"admin.removals":        "Latest removals from the group:\n",
+	"admin.removal":         "{0}: {1}, {2}\n",
+	"admin.audit_empty":     "Nothing found in the change log",
+	"admin.audit":           "Latest changes:\n",
+	"admin.change_created":  "created",
+	"admin.change_deleted":  "deleted",
+	"admin.exported":        "Rows exported: {0}",
+	"admin.import_hint":     "Send a JSON Lines export file with the caption /import",
+	"admin.imported":        "Imported accounts: {0}, referrals: {1}, votes: {2}, curator responses: {3}",
+}
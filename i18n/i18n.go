@@ -0,0 +1,231 @@
+// Package i18n хранит тексты бота на всех поддерживаемых языках.
+// Тексты — шаблоны text/template, их можно переопределить файлами из каталога шаблонов
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Default — язык, на котором бот говорит, если язык собеседника не поддерживается
+const Default = "ru"
+
+var catalogs = map[string]map[string]string{
+	"ru": russian,
+	"en": english,
+}
+
+var templates = make(map[string]map[string]*template.Template)
+
+// Raw — уже размеченный текст: Markdown подставляет его без экранирования
+type Raw string
+
+var functions = template.FuncMap{
+	"markdown": EscapeMarkdown,
+	"html":     EscapeHTML,
+}
+
+func init() {
+	for lang, catalog := range catalogs {
+		templates[lang] = make(map[string]*template.Template)
+		for key, text := range catalog {
+			parsed, err := parse(key, text)
+			if err != nil {
+				log.Panic(err)
+			}
+			templates[lang][key] = parsed
+		}
+	}
+}
+
+// LoadTemplates переопределяет тексты файлами dir/<язык>/<ключ>.tmpl, например
+// templates/ru/post.accepted.tmpl. Последний перевод строки в файле отбрасывается
+func LoadTemplates(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	for _, lang := range Languages() {
+		files, err := filepath.Glob(filepath.Join(dir, lang, "*.tmpl"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			key := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+			if _, ok := catalogs[lang][key]; !ok {
+				return fmt.Errorf("%s: неизвестный текст %s", file, key)
+			}
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			parsed, err := parse(key, strings.TrimSuffix(string(content), "\n"))
+			if err != nil {
+				return err
+			}
+			templates[lang][key] = parsed
+		}
+	}
+	return nil
+}
+
+// Languages возвращает коды поддерживаемых языков
+func Languages() []string {
+	return []string{"ru", "en"}
+}
+
+// Language приводит код языка из Telegram (например, "en-US") к поддерживаемому
+func Language(code string) string {
+	code = strings.ToLower(strings.Split(strings.Replace(code, "_", "-", 1), "-")[0])
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	return Default
+}
+
+// T возвращает текст key на языке lang, подставляя параметры вместо {0}, {1} и так далее
+func T(lang, key string, params ...interface{}) string {
+	return render(lang, key, arguments{params: params})
+}
+
+// Markdown работает как T, но экранирует параметры для сообщений с разметкой Markdown:
+// логин с подчёркиванием не сломает форматирование. Параметры типа Raw подставляются как есть
+func Markdown(lang, key string, params ...interface{}) string {
+	return render(lang, key, arguments{params: params, markdown: true})
+}
+
+func render(lang, key string, args arguments) string {
+	lang = Language(lang)
+	parsed, ok := templates[lang][key]
+	if !ok {
+		parsed, ok = templates[Default][key]
+		if !ok {
+			log.Printf("нет перевода для %s", key)
+			return key
+		}
+	}
+	var buffer bytes.Buffer
+	err := parsed.Execute(&buffer, args)
+	if err != nil {
+		log.Println(err.Error())
+		return key
+	}
+	return buffer.String()
+}
+
+// IsButton сообщает, что text — надпись кнопки key на любом из языков
+func IsButton(text, key string) bool {
+	for _, lang := range Languages() {
+		if _, ok := templates[lang][key]; ok && T(lang, key) == text {
+			return true
+		}
+	}
+	return false
+}
+
+var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+
+// EscapeMarkdown экранирует символы разметки Markdown
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// EscapeHTML экранирует символы разметки HTML для сообщений с ParseMode HTML
+func EscapeHTML(text string) string {
+	return html.EscapeString(text)
+}
+
+// arguments — параметры текста. Шаблон получает их через .Arg
+type arguments struct {
+	params   []interface{}
+	markdown bool
+}
+
+// Arg возвращает параметр index, подготовленный для места в разметке, где он стоит.
+// Внутри *жирного*, _курсива_ и `кода` Markdown не позволяет экранировать символы,
+// поэтому из параметра убирается закрывающий символ
+func (args arguments) Arg(index int, context string) string {
+	if index >= len(args.params) {
+		return ""
+	}
+	if raw, ok := args.params[index].(Raw); ok {
+		return string(raw)
+	}
+	text := fmt.Sprint(args.params[index])
+	if !args.markdown {
+		return text
+	}
+	switch context {
+	case "text":
+		return EscapeMarkdown(text)
+	case "link":
+		return strings.Replace(text, "]", "", -1)
+	case "url":
+		return strings.Replace(text, ")", "%29", -1)
+	}
+	return strings.Replace(text, context, "", -1)
+}
+
+var placeholderRegexp = regexp.MustCompile(`^\{(\d+)\}`)
+
+// parse превращает подстановки {0}, {1} в вызовы .Arg, запоминая, где в разметке они стоят
+func parse(key, text string) (*template.Template, error) {
+	var source bytes.Buffer
+	var entity byte
+	inLink, inURL := false, false
+	for i := 0; i < len(text); i++ {
+		if strings.HasPrefix(text[i:], "{{") {
+			end := strings.Index(text[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("%s: незакрытое действие шаблона", key)
+			}
+			source.WriteString(text[i : i+end+2])
+			i += end + 1
+			continue
+		}
+		if matched := placeholderRegexp.FindStringSubmatch(text[i:]); matched != nil {
+			context := "text"
+			switch {
+			case inURL:
+				context = "url"
+			case inLink:
+				context = "link"
+			case entity != 0:
+				context = string(entity)
+			}
+			index, _ := strconv.Atoi(matched[1])
+			fmt.Fprintf(&source, "{{.Arg %d %q}}", index, context)
+			i += len(matched[0]) - 1
+			continue
+		}
+		c := text[i]
+		source.WriteByte(c)
+		switch {
+		case c == '\\' && i+1 < len(text):
+			i++
+			source.WriteByte(text[i])
+		case inURL:
+			inURL = c != ')'
+		case entity != 0:
+			if c == entity {
+				entity = 0
+			}
+		case inLink:
+			inLink = c != ']'
+			inURL = c == ']' && i+1 < len(text) && text[i+1] == '('
+		case c == '*' || c == '_' || c == '`':
+			entity = c
+		case c == '[':
+			inLink = true
+		}
+	}
+	return template.New(key).Funcs(functions).Parse(source.String())
+}
@@ -0,0 +1,147 @@
+package i18n
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCatalogs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, text := range russian {
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: нет перевода для %s", lang, key)
+				continue
+			}
+			if placeholders(translated) != placeholders(text) {
+				t.Errorf("%s: разные подстановки в %s", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := russian[key]; !ok {
+				t.Errorf("%s: лишний ключ %s", lang, key)
+			}
+		}
+	}
+}
+
+// placeholders возвращает номер последней подстановки в тексте
+func placeholders(text string) int {
+	last := -1
+	for i := 0; strings.Contains(text, "{"+strconv.Itoa(i)+"}"); i++ {
+		last = i
+	}
+	return last
+}
+
+func TestT(t *testing.T) {
+	if text := T("en", "stats.line", "chiliec", 2, 150, "0.001"); text !=
+		"*chiliec*: votes — 2, total weight — 150%, curation rewards — 0.001 GOLOS\n" {
+		t.Errorf("неожиданный текст %s", text)
+	}
+	if text := T("de", "vote.accepted"); text != "Голос принят" {
+		t.Errorf("для неизвестного языка ожидали русский, получили %s", text)
+	}
+	if text := T("ru", "post.banned_tag"); text != "Нельзя предлагать посты с тегом " {
+		t.Errorf("недостающие параметры должны заменяться пустыми строками: %s", text)
+	}
+	if text := T("ru", "unknown.key"); text != "unknown.key" {
+		t.Errorf("неизвестный ключ должен возвращаться как есть: %s", text)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	if text := Markdown("en", "invite.error", "bad_name*"); text != "Could not create the link: bad\\_name\\*" {
+		t.Errorf("параметр вне разметки нужно экранировать: %s", text)
+	}
+	if text := Markdown("en", "stats.line", "chili*ec", 2, 150, "0.001"); !strings.HasPrefix(text, "*chiliec*:") {
+		t.Errorf("внутри жирного текста звёздочку нужно убрать: %s", text)
+	}
+	if text := Markdown("en", "referral.paid", "a_b", "c", "5.000"); !strings.HasPrefix(text,
+		"The referrer [@a_b](https://golos.io/@a_b/transfers) and") {
+		t.Errorf("внутри ссылки экранировать нельзя: %s", text)
+	}
+	if text := Markdown("en", "invite", "link", "url", Raw("*raw*")); !strings.HasSuffix(text, "\n\n*raw*") {
+		t.Errorf("Raw подставляется как есть: %s", text)
+	}
+	if text := T("en", "invite.error", "bad_name"); text != "Could not create the link: bad_name" {
+		t.Errorf("без разметки экранировать не нужно: %s", text)
+	}
+}
+
+func TestEscapeHTML(t *testing.T) {
+	if text := EscapeHTML(`<b>"a" & 'b'</b>`); text != "&lt;b&gt;&#34;a&#34; &amp; &#39;b&#39;&lt;/b&gt;" {
+		t.Errorf("неверно экранировано: %s", text)
+	}
+	parsed, err := parse("test", `<i>{{html "a<b"}}</i>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err = parsed.Execute(&buffer, arguments{}); err != nil || buffer.String() != "<i>a&lt;b</i>" {
+		t.Errorf("функция html в шаблоне: %s %v", buffer.String(), err)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		templates["en"]["post.accepted"], _ = parse("post.accepted", english["post.accepted"])
+	}()
+	err = os.Mkdir(filepath.Join(dir, "en"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "en", "post.accepted.tmpl")
+	err = ioutil.WriteFile(file, []byte("{{if true}}Thanks!{{end}}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := T("en", "post.accepted"); text != "Thanks!" {
+		t.Errorf("шаблон из каталога не подхватился: %q", text)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "en", "unknown.tmpl"), []byte("text"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if LoadTemplates(dir) == nil {
+		t.Error("шаблон для неизвестного текста — вероятно, опечатка в имени файла")
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	cases := map[string]string{
+		"en-US": "en",
+		"EN":    "en",
+		"ru":    "ru",
+		"uk":    Default,
+		"":      Default,
+	}
+	for code, expected := range cases {
+		if language := Language(code); language != expected {
+			t.Errorf("язык %s определён как %s вместо %s", code, language, expected)
+		}
+	}
+}
+
+func TestIsButton(t *testing.T) {
+	if !IsButton("🐬Delegate", "button.add_key") || !IsButton("🐬Делегировать", "button.add_key") {
+		t.Error("кнопка должна узнаваться на любом языке")
+	}
+	if IsButton("🐬Delegate", "button.remove_key") {
+		t.Error("чужая кнопка не должна узнаваться")
+	}
+}
@@ -0,0 +1,234 @@
+package i18n
+
+var russian = map[string]string{
+	"button.add_key":          "🐬Делегировать",
+	"button.remove_key":       "🦀Остановить",
+	"button.power":            "💪Настройка",
+	"button.information":      "⚓️Информация",
+	"button.all_accounts":     "Все аккаунты",
+	"button.start_curating":   "Стать куратором",
+	"button.stop_curating":    "Прекратить кураторство",
+	"button.curating_approve": "🐬‍️Я справлюсь",
+	"button.curating_decline": "🐡‍Слишком сложно",
+	"button.claim_check":      "✅Проверить",
+	"button.restore_access":   "🐬Вернуть доступ",
+	"button.like":             "👍Лайк",
+	"button.dislike":          "👎Дизлайк",
+	"button.week":             "Неделя",
+	"button.month":            "Месяц",
+	"button.all_time":         "Всё время",
+	"button.language":         "Русский",
+	"button.previous":         "⬅️Назад",
+	"button.next":             "Дальше➡️",
+
+	"start": "Привет, {0}! \n\n" +
+		"Я — бот для коллективного кураторства в [социальной блокчейн-сети \"Голос\"](https://golos.io).\n\n" +
+		"Мой код полностью открыт и находится здесь: {1}\n\n" +
+		"Предлагаю начать с нажатия кнопки \"{2}\", " +
+		"после чего я дам ссылку на группу для предложения постов.\n\n" +
+		"По любым вопросам пиши моему хозяину — {3}",
+	"start.username":  "%username%",
+	"not_understood":  "Не понимаю",
+	"admins_only":     "Команда доступна только администраторам",
+	"rate_limited":    "Слишком много запросов, подожди минуту",
+	"delegate_first":  "Сначала делегируй мне права кнопкой {0}",
+	"error.developer": "Произошла ошибка, свяжись с разработчиком - {0}",
+	"error.try_later": "Что-то пошло не так. Попробуй повторить позже " +
+		"или свяжись с разработчиком: {0}",
+
+	"cancel.done":    "Хорошо, забудем об этом",
+	"cancel.nothing": "Отменять нечего",
+
+	"language.choose": "Выбери язык",
+	"language.set":    "Теперь я говорю по-русски",
+
+	"add_key.instructions": "Добавь доверенный аккаунт *{0}* в " +
+		"[{1}]({2}) " +
+		"(или через [форму от vik'a](https://golos.cf/multi/)), " +
+		"а затем скажи мне свой логин на Голосе",
+	"add_key.no_access": "Доступ у этого аккаунта для меня отсутствует. " +
+		"Добавить его можно в https://golos.cf/multi/ для аккаунта *{0}*",
+	"add_key.success": "Поздравляю, теперь ты почти полноправный участник! " +
+		"Чтобы вообще все плюшки заиметь, можешь стать еще и куратором. " +
+		"Присоединяйся к нашей группе, там бывает весело: {0}",
+	"claim.instructions": "Осталось подтвердить, что аккаунт *{0}* принадлежит тебе. " +
+		"Переведи 0.001 GOLOS на аккаунт *{1}* с заметкой `{2}` " +
+		"или отправь custom\\_json с этим кодом, подписанный активным ключом. " +
+		"После этого нажми кнопку ниже. Код действует сутки",
+	"claim.not_found": "Заявка не найдена, добавь аккаунт заново кнопкой {0}",
+	"claim.expired":   "Код подтверждения устарел, добавь аккаунт заново кнопкой {0}",
+	"claim.not_yet":   "Пока не вижу подтверждения, попробуй через минуту",
+	"claim.lost": "Владелец аккаунта {0} подтвердил его " +
+		"из другого профиля Telegram, поэтому я отвязала его от тебя",
+
+	"account.not_active": "Аккаунт не активирован",
+	"remove_key.choose":  "Какой аккаунт мне больше не использовать?",
+	"remove_key.done": "Отлично, я больше не буду использовать этот аккаунт при курировании постов. " +
+		"Дополнительно можешь удалить все сторонние ключи из своего аккаунта здесь: " +
+		"https://golos.cf/multi/off.html",
+	"revoke": "Аккаунт *{0}* больше не доверяет мне свою Силу Голоса, " +
+		"поэтому я перестала голосовать с него.\n\n" +
+		"Если это случайность — снова добавь доверенный аккаунт *{1}* и скажи мне свой логин " +
+		"после нажатия кнопки \"{2}\".",
+	"revoke.curator": " Кураторство пока приостановлено и вернётся вместе с доступом.",
+
+	"power.prompt": "Введи значение делегируемой силы Голоса от 1 до 100%",
+	"power.prompt_account": "Введи значение делегируемой силы Голоса от 1 до 100% " +
+		"для аккаунта *{0}*",
+	"power.prompt_choose": "Введи значение делегируемой силы Голоса от 1 до 100% " +
+		"для аккаунта *{0}* или выбери другой аккаунт",
+	"power.not_understood": "Не поняла. Введи значение делегируемой силы Голоса от 1 до 100%",
+	"power.updated": "Предоставленная мне в распоряжение сила Голоса " +
+		"для аккаунта *{0}* теперь равна *{1}%*\n" +
+		"Сила Голоса аккаунта с учётом делегирования: *{2} GOLOS*, " +
+		"при текущем заряде голос стоит около *{3} GBG*",
+	"power.too_small": "У тебя пока слишком маленькая Сила Голоса для этого: " +
+		"полный голос стоит около {0} GBG, а нужно хотя бы {1} GBG",
+
+	"info.no_info":  "У меня пока нет информации для тебя",
+	"info.account":  "Аккаунт: *{0}*, делегированная сила: *{1}%*{2}\n",
+	"info.disabled": " (отключён)",
+	"info": "{0}" +
+		"Доверие: *{1}* (одобрено кураторами: {2}%, протухло: {3}%, " +
+		"плагиат: {4}, возраст аккаунта: {5} дн.)",
+	"info.referral": "\nРеферальная ссылка: [{0}]({1})\n" +
+		"(дает обоим по {2} Силы Голоса, " +
+		"у приглашаемого должно быть как минимум {3} постов " +
+		"и он не должен взаимодействовать с Голосовалочкой до приглашения)",
+
+	"post.too_early":    "Прошло слишком мало времени после твоего последнего поста. Наберись терпения!",
+	"post.banned_tag":   "Нельзя предлагать посты с тегом {0}",
+	"post.allowed_tags": "Я принимаю только посты с тегами: {0}",
+	"post.tag_quota": "Слишком много уже открытых голосований с тегом {0}. " +
+		"Подожди, пока они завершатся, или предложи пост на другую тему.",
+	"post.not_delegator":   "Предлагать посты для голосования могут только голосующие пользователи. Жулик не воруй!",
+	"post.paid_out":        "Выплата за пост уже была произведена! Есть что-нибудь посвежее?",
+	"post.payout_declined": "Мне не интересно голосовать за пост с отключенными выплатами",
+	"post.too_many_votes": "Слишком много уже открытых голосований. " +
+		"Подожди, пока другой голос получит голоса или полиция свежести избавится от протухших постов.",
+	"post.too_many_user_votes": "У тебя уже достаточно постов на голосовании. Дождись, пока кураторы их оценят!",
+	"post.banned_user":         "Тебе запрещено предлагать посты",
+	"post.banned_author":       "Посты этого автора не принимаются",
+	"post.vox_populi":          "Сообщества vox-populi могут сами себя поддержать",
+	"post.too_short":           "Слишком мало текста, не скупись на буквы!",
+	"post.duplicate":           "Уже голосовала за этот пост!",
+	"post.accepted":            "Пост выставлен на голосование.",
+	"post.new": "Новый пост - новая оценка. Курируй, куратор\n\n" +
+		"*{0}*\n" +
+		"Автор: [@{1}](https://golos.io/@{1}), репутация {2}\n" +
+		"Теги: {3}\n" +
+		"Длина: {4} симв., картинок: {5}\n" +
+		"Выплата: {6}, голосов: {7}\n" +
+		"{8}{9}\n\n" +
+		"[Читать]({10})",
+	"post.card.uniqueness": "Уникальность: {0}%\n",
+	"post.card.submitter":  "Предложил: [{0}](https://golos.io/@{0})",
+	"post.card.anonymous":  "Предложил: аноним",
+
+	"group.welcome": "{0}, добро пожаловать! В этой группе остаются только те, кто делегировал мне Силу Голоса. " +
+		"Напиши мне в личные сообщения @{1} и нажми «Делегировать», иначе через {2} мин. мне придётся тебя удалить",
+	"group.verify": "{0}, в этой группе остаются только те, кто делегировал мне Силу Голоса, а у тебя активного аккаунта нет. " +
+		"Напиши мне в личные сообщения @{1} и нажми «Делегировать», иначе через {2} мин. мне придётся тебя удалить",
+
+	"curating.already": "Ты уже являешься куратором",
+	"curating.approved": "Отлично, теперь ты будешь участвовать в курировании постов. " +
+		"Скоро я начну присылать тебе ссылки, подожди немного",
+	"curating.declined": "Хороший выбор. Курирование чужих постов — сложный и неблагодарный процесс. " +
+		"Лучше пиши свои посты и скидывай мне ссылки на них, а кураторы пусть делают свою работу!",
+	"curating.stopped":     "Бремя кураторства покинуло тебя. Когда вдоволь насладишься свободой — возвращайся!",
+	"curating.not_curator": "То, что мертво — умереть не может. Так и ты — нельзя отказаться от курирования, не будучи куратором",
+
+	"vote.not_curator": "Чекни свои привилегии. Ты не куратор!",
+	"vote.repeated":    "И да настигнет Админская кара всех тех, кто пытается злоупотреблять своей властью и голосовать несколько раз! Админь",
+	"vote.accepted":    "Голос принят",
+	"vote.success":     "Успешно проголосовала c {0} аккаунтов за пост\n{1}",
+	"vote.error":       "В процессе голосования произошла ошибка, свяжитесь с разработчиком - {0}\n{1}",
+	"vote.addled": "Прости, {0}, твой пост ({1}/{2}) так и не дождался своих голосов. В следующий раз напиши что-нибудь " +
+		"получше и кураторы обязательно это оценят",
+	"vote.rejected": "Пост {0}/{1} был отклонен кураторами",
+
+	"my.empty":             "Ты пока не предлагал постов",
+	"my.title":             "Твои посты {0}–{1} из {2}:\n\n",
+	"my.line":              "{0}. [{1}/{2}](https://golos.io/@{1}/{2}) — {3}, 👍 {4} 👎 {5}, проголосовало аккаунтов: {6}\n",
+	"my.status.open":       "голосование идёт",
+	"my.status.supported":  "поддержан",
+	"my.status.rejected":   "отклонён",
+	"my.status.addled":     "протух",
+	"my.status.plagiarism": "плагиат",
+	"my.status.closed":     "закрыт администратором",
+	"my.next_now":          "\nСледующий пост можно предложить прямо сейчас",
+	"my.next_at":           "\nСледующий пост можно предложить после {0}",
+
+	"stats.week":  "за неделю",
+	"stats.month": "за месяц",
+	"stats.all":   "за всё время",
+	"stats.title": "Статистика {0}:\n",
+	"stats.line":  "*{0}*: голосов — {1}, суммарный вес — {2}%, кураторские — {3} GOLOS\n",
+
+	"invite": "Реферальная ссылка: [{0}]({1})\n" +
+		"Название кампании можно указать после команды: /invite summer\n\n{2}",
+	"invite.error":              "Не получилось создать ссылку: {0}",
+	"invite.disabled":           "Реферальная программа сейчас не работает",
+	"referral.stats_empty":      "По ссылкам пока никто не приходил",
+	"referral.stats_title":      "Приглашено / зарегистрировалось / получили награду:\n",
+	"referral.stats_line":       "{0}, {1}: {2} / {3} / {4}\n",
+	"referral.no_campaign":      "без кампании",
+	"referral.already_reviewed": "Награду за {0} уже рассмотрели",
+	"referral.approved":         "Награда за {0} одобрена и будет выплачена",
+	"referral.rejected":         "Награда за {0} отклонена",
+	"referral.paid": "Пригласивший [@{0}](https://golos.io/@{0}/transfers) " +
+		"и приглашённый [@{1}](https://golos.io/@{1}/transfers) получают по {2} Силы Голоса в рамках партнёрской программы",
+
+	"button.referral_approve":       "Выплатить",
+	"button.referral_reject":        "Отклонить",
+	"admin.referral_review":         "Реферальная награда за {0} (пригласил {1}) требует проверки:\n- {2}",
+	"suspicion.young":               "аккаунт создан {0}",
+	"suspicion.created_by_referrer": "аккаунт создан пригласившим",
+	"suspicion.recovery":            "общий аккаунт восстановления {0}",
+	"suspicion.transfers":           "переводы между пригласившим и приглашённым",
+	"suspicion.monthly_cap":         "пригласивший уже получил {0} наград за месяц",
+
+	"admin.claim_moved":    "Аккаунт {0} перешёл от пользователя {1} к пользователю {2} после подтверждения владения",
+	"admin.claim_rejected": "Заявка пользователя {0} на аккаунт {1} отклонена: владение подтвердил пользователь {2}",
+	"admin.claim_taken":    "Пользователь {0} пытается привязать аккаунт {1}, уже привязанный к пользователю {2}",
+	"admin.claim_rivals":   "На аккаунт {0} претендуют пользователи {1} и {2}",
+
+	"admin.failed":          "Не получилось: {0}",
+	"admin.unknown_command": "неизвестная команда: {0}",
+	"admin.not_linked":      "аккаунт {0} не привязан",
+	"admin.usage.ban":       "формат команды: /{0} user|author <логин или ID>",
+	"admin.usage.vote":      "укажи номер голосования",
+	"admin.usage.broadcast": "формат команды: /broadcast <текст>",
+	"admin.usage.user":      "формат команды: /user <логин или ID>",
+	"admin.usage.audit":     "формат команды: /audit [{0} [ключ]] или /audit user:<ID>|admin:<ID>|system:<задача>",
+	"admin.usage.export":    "формат команды: /export csv|jsonl [votes|responses|credentials|referrals|all] [с ГГГГ-ММ-ДД] [по ГГГГ-ММ-ДД]",
+	"admin.banned":          "{0} {1} забанен",
+	"admin.unbanned":        "Бан {0} {1} снят",
+	"admin.not_banned":      "{0} {1} не был забанен",
+	"admin.vote_not_found":  "голосование {0} не найдено",
+	"admin.closed":          "Голосование {0} за пост {1}/{2} закрыто",
+	"admin.reopened":        "Голосование {0} за пост {1}/{2} снова открыто",
+	"admin.forcevote":       "Голосую за пост {0}/{1}",
+	"admin.vote_completed":  "голосование {0} уже завершено",
+	"admin.vote_cast":       "за пост голосования {0} уже проголосовали аккаунты, открыть его снова нельзя",
+	"admin.author_banned":   "автор {0} забанен",
+	"admin.broadcast":       "Рассылка поставлена в очередь для {0} чатов, не получилось для {1}",
+	"admin.curators":        "Активных кураторов: {0} (оценок за месяц)\n",
+	"admin.curator":         "{0} ({1}): {2}\n",
+	"admin.queue_empty":     "Нет открытых голосований",
+	"admin.queue":           "Открытых голосований: {0}\n",
+	"admin.queue_line":      "{0}. {1}/{2}: +{3} −{4}, предложил {5} {6}\n",
+	"admin.user":            "Пользователь {0}, доверие {1}, открытых постов: {2}, забанен: {3}\n",
+	"admin.user_account":    "{0}: чат {1}, сила {2}%, активен: {3}, куратор: {4}\n",
+	"admin.removal_failed":  "Не получается удалить пользователя {0} из группы: {1}. Больше пытаться не буду, удалите его вручную",
+	"admin.removals_empty":  "Из группы пока никого не удаляли",
+	"admin.removals":        "Последние удаления из группы:\n",
+	"admin.removal":         "{0}: {1}, {2}\n",
+	"admin.audit_empty":     "В журнале изменений ничего не нашлось",
+	"admin.audit":           "Последние изменения:\n",
+	"admin.change_created":  "создано",
+	"admin.change_deleted":  "удалено",
+	"admin.exported":        "Выгружено строк: {0}",
+	"admin.import_hint":     "Пришлите файл выгрузки JSON Lines с подписью /import",
+	"admin.imported":        "Загружено аккаунтов: {0}, рефералов: {1}, голосований: {2}, ответов кураторов: {3}",
+}
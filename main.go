@@ -2,41 +2,49 @@ package main
 
 import (
 	"database/sql"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
-	"net/url"
+	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	golosClient "github.com/asuleymanov/golos-go/client"
+	"github.com/asuleymanov/golos-go/types"
 	"github.com/go-telegram-bot-api/telegram-bot-api"
-	"github.com/grokify/html-strip-tags-go"
 
+	"github.com/GolosTools/golos-vote-bot/blockchain"
 	configuration "github.com/GolosTools/golos-vote-bot/config"
+	"github.com/GolosTools/golos-vote-bot/conversation"
 	"github.com/GolosTools/golos-vote-bot/db"
+	"github.com/GolosTools/golos-vote-bot/dispatcher"
+	"github.com/GolosTools/golos-vote-bot/dump"
 	"github.com/GolosTools/golos-vote-bot/helpers"
+	"github.com/GolosTools/golos-vote-bot/i18n"
 	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/router"
+	"github.com/GolosTools/golos-vote-bot/storage"
 )
 
+// ключи надписей кнопок в каталоге i18n, они же действия в states
 const (
-	buttonAddKey        = "🐬Делегировать"
-	buttonRemoveKey     = "🦀Остановить"
-	buttonSetPowerLimit = "💪Настройка"
-	buttonInformation   = "⚓️Информация"
+	buttonAddKey        = "button.add_key"
+	buttonRemoveKey     = "button.remove_key"
+	buttonSetPowerLimit = "button.power"
+	buttonInformation   = "button.information"
 )
 
 var (
-	config   configuration.Config
-	database *sql.DB
-	bot      *tgbotapi.BotAPI
+	config      configuration.Config
+	store       storage.Storage
+	bot         *tgbotapi.BotAPI
+	outbox      *dispatcher.Dispatcher
+	routes      *router.Router
+	blockEvents *blockchain.Follower
 )
 
 func main() {
@@ -45,21 +53,41 @@ func main() {
 		log.Panic(err.Error())
 	}
 	config = configuration
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrate(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	err = helpers.CheckWebhookConfig(config)
+	if err != nil {
+		log.Panic(err)
+	}
+	if len(config.TemplatesDir) > 0 {
+		err = i18n.LoadTemplates(config.TemplatesDir)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+	if len(config.ReferralSecret) == 0 {
+		log.Println("Реферальные ссылки не создаются и не принимаются: не задан referral_secret")
+	}
 	golosClient.Key_List[config.Account] = golosClient.Keys{
 		PKey: config.PostingKey,
 		AKey: config.ActiveKey}
-	database, err = db.InitDB(config.DatabasePath)
+	store, err = openStorage()
 	if err != nil {
-		if err.Error() == "unable to open database file" {
-			path, err := filepath.Abs(config.DatabasePath)
-			if err != nil {
-				log.Panic(err)
-			}
-			log.Panic(fmt.Sprintf("unable to open database at path: %s", path))
-		}
 		log.Panic(err)
 	}
-	defer database.Close()
+	defer store.Close()
+	if len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "import") {
+		err = dumpCommand(os.Args[1], os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	bot, err = tgbotapi.NewBotAPI(config.TelegramToken)
 	if err != nil {
@@ -68,863 +96,496 @@ func main() {
 	bot.Debug = config.DebugMode
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
+	outbox = dispatcher.New(bot, dispatcher.NewDatabaseStore(store))
+	outbox.OnBlocked = onChatBlocked
+	err = outbox.Restore()
+	if err != nil {
+		log.Panic(err)
+	}
+	go outbox.Run()
+
+	blockEvents = blockchain.NewFollower(nil, blockchain.NewDatabaseCursor("events", store))
+	blockEvents.Subscribe(types.TypeAccountUpdate, onAccountUpdate)
+	go followBlocks()
 	go freshnessPolice()
 	go checkAuthority()
 	go queueProcessor()
+	go referralProcessor()
+	go claimCollector()
+	go membershipPolice()
 	//go supportedPostsReporter()
 	//go curationMotivator()
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates, err := bot.GetUpdatesChan(u)
+	routes, err = newRouter()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	updates, err := updatesChannel()
 	if err != nil {
 		log.Panic(err)
 	}
 	for update := range updates {
-		err := processMessage(update)
-		if err != nil {
-			log.Println(err)
-		}
+		processUpdate(update)
 	}
 }
 
-func processMessage(update tgbotapi.Update) error {
-	chatID, err := helpers.GetChatID(update)
-	if err != nil {
-		return err
+// updatesChannel получает обновления через вебхук, если задан webhook_url, и опросом в остальных случаях
+func updatesChannel() (tgbotapi.UpdatesChannel, error) {
+	if len(config.WebhookURL) == 0 {
+		// пока вебхук установлен, Telegram не отдаёт обновления опросом
+		_, err := bot.RemoveWebhook()
+		if err != nil {
+			log.Println("не удалили вебхук: " + err.Error())
+		}
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		return bot.GetUpdatesChan(u)
 	}
-	userID, err := helpers.GetUserID(update)
-	if err != nil {
-		return err
+	link := strings.TrimRight(config.WebhookURL, "/") + helpers.WebhookPath(config.WebhookSecret)
+	webhook := tgbotapi.NewWebhook(link)
+	if len(config.WebhookCert) > 0 {
+		// самоподписанный сертификат нужно показать Telegram
+		webhook = tgbotapi.NewWebhookWithCert(link, config.WebhookCert)
 	}
-	msg := tgbotapi.NewMessage(chatID, "")
-
-	state, err := models.GetStateByUserID(userID, database)
+	_, err := bot.SetWebhook(webhook)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	if update.Message != nil {
-		domainRegexp, err := helpers.GetDomainRegexp(config.Domains)
-		if err != nil {
-			return err
-		}
-		if update.Message.Chat.Type != "private" {
-			return nil
+	updates := make(chan tgbotapi.Update, bot.Buffer)
+	server := &http.Server{
+		Addr:    config.WebhookListen,
+		Handler: helpers.WebhookHandler(config.WebhookSecret, updates),
+	}
+	go func() {
+		var err error
+		if len(config.WebhookCert) > 0 {
+			err = server.ListenAndServeTLS(config.WebhookCert, config.WebhookKey)
+		} else {
+			err = server.ListenAndServe()
 		}
-		switch {
-		case update.Message.IsCommand():
-			switch update.Message.Command() {
-			case "start":
-				username := "%username%"
-				if len(update.Message.From.FirstName) > 0 {
-					username = update.Message.From.FirstName
-				}
-				msg.Text = fmt.Sprintf("Привет, %s! \n\n"+
-					"Я — бот для коллективного кураторства в [социальной блокчейн-сети \"Голос\"](https://golos.io).\n\n"+
-					"Мой код полностью открыт и находится здесь: %s\n\n"+
-					"Предлагаю начать с нажатия кнопки \""+buttonAddKey+"\", "+
-					"после чего я дам ссылку на группу для предложения постов.\n\n"+
-					"По любым вопросам пиши моему хозяину — %s",
-					username, config.Repository, config.Developer)
-				// save referral if exists
-				if len(update.Message.CommandArguments()) > 0 {
-					_, err := models.GetCredentialByUserID(userID, database)
-					if err == sql.ErrNoRows {
-						decodedString, err := base64.URLEncoding.DecodeString(update.Message.CommandArguments())
-						if err == nil {
-							referrer, err := models.GetCredentialByUserName(string(decodedString), database)
-							if err == nil && referrer.Active == true {
-								referral := models.Referral{UserID: userID,
-									Referrer:  string(decodedString),
-									UserName:  "",
-									Completed: false}
-								_, err = referral.Save(database)
-								if err != nil {
-									log.Println("не сохранили реферала: " + err.Error())
-								}
-							}
-						} else {
-							log.Printf("не смогли раскодировать строку %s", update.Message.CommandArguments())
-						}
-					}
-				}
-			}
-			state.Action = update.Message.Command()
-		case update.Message.Text == buttonAddKey:
-			msg.Text = fmt.Sprintf("Добавь доверенный аккаунт *%s* в "+
-				"[https://golostools.github.io/golos-vote-bot/](https://golostools.github.io/golos-vote-bot/) "+
-				"(или через [форму от vik'a](https://golos.cf/multi/)), "+
-				"а затем скажи мне свой логин на Голосе", config.Account)
-			state.Action = buttonAddKey
-		case update.Message.Text == buttonRemoveKey:
-			msg.Text = fmt.Sprintf("Произошла ошибка, свяжись с разработчиком - %s", config.Developer)
-			isActive := models.IsActiveCredential(userID, database)
-			if isActive {
-				credential, err := models.GetCredentialByUserID(userID, database)
-				credential.Active = false
-				credential.Curates = false
-				result, err := credential.Save(database)
-				if err != nil {
-					log.Println(err.Error())
-				}
-				if true == result && err == nil {
-					msg.Text = "Отлично, я больше не буду использовать твой аккаунт при курировании постов. " +
-						"Дополнительно можешь удалить все сторонние ключи из своего аккаунта здесь: " +
-						"https://golos.cf/multi/off.html"
-				}
-			} else {
-				msg.Text = "Аккаунт не активирован"
-			}
-			state.Action = buttonRemoveKey
-		case update.Message.Text == buttonSetPowerLimit:
-			if false == models.IsActiveCredential(userID, database) {
-				msg.Text = "Сначала делегируй мне права кнопкой " + buttonAddKey
-				break
-			}
-			msg.Text = "Введи значение делегируемой силы Голоса от 1 до 100%"
-			state.Action = buttonSetPowerLimit
-		case update.Message.Text == buttonInformation:
-			if false == models.IsActiveCredential(userID, database) {
-				msg.Text = "У меня пока нет информации для тебя"
-				break
-			}
-			credential, err := models.GetCredentialByUserID(userID, database)
-			if err != nil {
-				return err
-			}
-			encodedUserName := base64.URLEncoding.EncodeToString([]byte(credential.UserName))
-			referralLink := "https://t.me/" + config.TelegramBotName + "?start=" + encodedUserName
-			msg.Text = fmt.Sprintf("Аккаунт: *%s*, делегированная сила: *%d%%*\n"+
-				"Реферальная ссылка: [%s](%s)\n"+
-				"(дает обоим по %.3f Силы Голоса, "+
-				"у приглашаемого должно быть как минимум %d постов "+
-				"и он не должен взаимодействовать с Голосовалочкой до приглашения)",
-				credential.UserName, credential.Power, referralLink, referralLink, config.ReferralFee, config.ReferralMinimumPostCount)
-			var button tgbotapi.InlineKeyboardButton
-			if models.IsActiveCurator(userID, database) {
-				button = tgbotapi.NewInlineKeyboardButtonData("Прекратить кураторство", "curating_stop")
-			} else {
-				button = tgbotapi.NewInlineKeyboardButtonData("Стать куратором", "curating_start")
-			}
-			keyboard := []tgbotapi.InlineKeyboardButton{button}
-			markup := tgbotapi.NewInlineKeyboardMarkup(keyboard)
-			msg.ReplyMarkup = markup
-			state.Action = buttonInformation
-		case domainRegexp.MatchString(update.Message.Text):
-			msg.ReplyToMessageID = update.Message.MessageID
-
-			matched := domainRegexp.FindStringSubmatch(update.Message.Text)
-			author, permalink := matched[1], matched[2]
-
-			golos := golosClient.NewApi(config.Rpc, config.Chain)
-			defer golos.Rpc.Close()
-			post, err := golos.Rpc.Database.GetContent(author, permalink)
-			if err != nil {
-				return err
-			}
-			// check post exists in blockchain
-			if post.Author != author || post.Permlink != permalink {
-				return nil
-			}
-
-			lastVote := models.GetLastVoteForUserID(userID, database)
-			userInterval, _ := models.ComputeIntervalForUser(userID, 10, config.PostingInterval, database)
-			if time.Since(lastVote.Date) < userInterval && !config.DebugMode {
-				msg.Text = "Прошло слишком мало времени после твоего последнего поста. Наберись терпения!"
-				break
-			}
-
-			if config.Censorship {
-				tags := post.JsonMetadata.Tags
-				includesBannedTag := false
-				for _, bannedTag := range config.BannedTags {
-					for _, postTag := range tags {
-						if postTag == bannedTag {
-							includesBannedTag = true
-							msg.Text = "Нельзя предлагать посты с тегом " + postTag
-						}
-					}
-
-				}
-				if includesBannedTag {
-					break
-				}
-			}
-
-			isActive := models.IsActiveCredential(userID, database)
-			if !isActive {
-				msg.Text = "Предлагать посты для голосования могут только голосующие пользователи. Жулик не воруй!"
-				break
-			}
-
-			if post.Mode != "first_payout" {
-				msg.Text = "Выплата за пост уже была произведена! Есть что-нибудь посвежее?"
-				break
-			}
-
-			if post.MaxAcceptedPayout == "0.000 GBG" {
-				msg.Text = "Мне не интересно голосовать за пост с отключенными выплатами"
-				break
-			}
-
-			if models.GetOpenedVotesCount(database) >= config.MaximumOpenedVotes {
-				msg.Text = "Слишком много уже открытых голосований. " +
-					"Подожди, пока другой голос получит голоса или полиция свежести избавится от протухших постов."
-				break
-			}
+		log.Panic(err)
+	}()
+	log.Printf("Жду обновления на %s", config.WebhookListen)
+	return updates, nil
+}
 
-			if helpers.IsVoxPopuli(author) && config.IgnoreVP {
-				msg.Text = "Сообщества vox-populi могут сами себя поддержать"
-				break
-			}
+// mainKeyboard — постоянная клавиатура с основными действиями на языке пользователя
+func mainKeyboard(lang string) tgbotapi.ReplyKeyboardMarkup {
+	firstButton := tgbotapi.NewKeyboardButton(i18n.T(lang, buttonAddKey))
+	secondButton := tgbotapi.NewKeyboardButton(i18n.T(lang, buttonRemoveKey))
+	firstButtonRow := []tgbotapi.KeyboardButton{firstButton, secondButton}
 
-			if len(post.Body) < config.MinimumPostLength {
-				msg.Text = "Слишком мало текста, не скупись на буквы!"
-				break
-			}
+	thirdButton := tgbotapi.NewKeyboardButton(i18n.T(lang, buttonSetPowerLimit))
+	fourthButton := tgbotapi.NewKeyboardButton(i18n.T(lang, buttonInformation))
+	secondButtonRow := []tgbotapi.KeyboardButton{thirdButton, fourthButton}
 
-			percent := 100
-
-			voteModel := models.Vote{
-				UserID:    userID,
-				Author:    author,
-				Permalink: permalink,
-				Percent:   percent,
-				Completed: false,
-				Rejected:  false,
-				Addled:    false,
-				Date:      time.Now(),
-			}
-
-			if voteModel.Exists(database) {
-				msg.Text = "Уже голосовала за этот пост!"
-				break
-			}
+	return tgbotapi.NewReplyKeyboard(firstButtonRow, secondButtonRow)
+}
 
-			voteID, err := voteModel.Save(database)
+// userLanguage возвращает язык пользователя. Пока пользователь не выбрал язык сам,
+// он следует за настройками Telegram из from
+func userLanguage(userID int, from *tgbotapi.User) string {
+	language, err := store.GetLanguageByUserID(userID, i18n.Default)
+	if err != nil {
+		log.Println("не получили язык пользователя: " + err.Error())
+		return i18n.Default
+	}
+	if from != nil && !language.Manual && len(from.LanguageCode) > 0 {
+		detected := i18n.Language(from.LanguageCode)
+		if detected != language.Code {
+			language.Code = detected
+			err = store.SaveLanguage(language, models.UserActor(userID))
 			if err != nil {
-				return err
+				log.Println("не сохранили язык пользователя: " + err.Error())
 			}
+		}
+	}
+	return language.Code
+}
 
-			log.Printf("Вкинули статью \"%s\" автора \"%s\" в чате %d", permalink, author, chatID)
-
-			msg.Text = "Пост выставлен на голосование."
-
-			if checkUniqueness(post.Body, voteModel) {
-				go newPost(voteID, author, permalink, chatID)
-			}
-
-			return nil
-		case state.Action == buttonAddKey:
-			login := strings.ToLower(update.Message.Text)
-			login = strings.Trim(login, "@")
-			credential := models.Credential{
-				UserID:   userID,
-				ChatID:   chatID,
-				UserName: login,
-				Power:    100,
-				Active:   true,
-				Curates:  false,
-			}
-
-			golos := golosClient.NewApi(config.Rpc, config.Chain)
-			defer golos.Rpc.Close()
-			accounts, err := golos.Rpc.Database.GetAccounts([]string{login})
-			if err != nil {
-				return err
-			} else if len(accounts) == 1 {
-				hasPostingAuth := false
-				for _, auth := range accounts[0].Posting.AccountAuths {
-					if auth.([]interface{})[0] == config.Account {
-						hasPostingAuth = true
-						break
-					}
-				}
-				if hasPostingAuth {
-					// send referral fee
-					referral, err := models.GetReferralByUserID(userID, database)
-					if err == nil && !referral.Completed {
-						if err = referral.SetCompleted(database); err == nil {
-							referral.UserName = credential.UserName
-							referral.Save(database)
-							_, err = models.GetCredentialByUserName(credential.UserName, database)
-							if err == sql.ErrNoRows {
-								go sendReferralFee(referral.Referrer, credential.UserName)
-							}
-						}
-					}
-
-					_, err = credential.Save(database)
-					if err != nil {
-						return err
-					}
-					msg.Text = "Поздравляю, теперь ты почти полноправный участник! " +
-						"Чтобы вообще все плюшки заиметь, можешь стать еще и куратором. " +
-						"Присоединяйся к нашей группе, там бывает весело: " + config.GroupLink
-					state.Action = "successAuth"
-				} else {
-					msg.Text = fmt.Sprintf("Доступ у этого аккаунта для меня отсутствует. "+
-						"Добавить его можно в https://golos.cf/multi/ для аккаунта *%s*", config.Account)
-				}
-			} else {
-				msg.Text = fmt.Sprintf("Что-то пошло не так. Попробуй повторить позже "+
-					"или свяжись с разработчиком: %s", config.Developer)
-				log.Printf("Введён некорректный логин: %s", update.Message.Text)
-			}
-		case state.Action == buttonSetPowerLimit:
-			re := regexp.MustCompile("[0-9]+")
-			valueString := re.FindString(update.Message.Text)
-			value, err := strconv.Atoi(valueString)
+// openStorage открывает хранилище из настроек. Базу SQLite создаёт и мигрирует пакет db,
+// базу PostgreSQL — само хранилище
+func openStorage() (storage.Storage, error) {
+	if config.DatabaseDriver == storage.DriverPostgres {
+		return storage.Open(config.DatabaseDriver, config.DatabaseURL, nil)
+	}
+	database, err := db.InitDB(config.DatabasePath)
+	if err != nil {
+		if err.Error() == "unable to open database file" {
+			path, err := filepath.Abs(config.DatabasePath)
 			if err != nil {
-				msg.Text = "Не поняла. Введи значение делегируемой силы Голоса от 1 до 100%"
-				break
-			}
-			if value >= 1 && value <= 100 {
-				if false == models.IsActiveCredential(userID, database) {
-					msg.Text = "Сначала делегируй мне права кнопкой " + buttonAddKey
-					break
-				}
-
-				credential, err := models.GetCredentialByUserID(userID, database)
-				if err != nil {
-					return err
-				}
-
-				golos := golosClient.NewApi(config.Rpc, config.Chain)
-				defer golos.Rpc.Close()
-
-				accounts, err := golos.Rpc.Database.GetAccounts([]string{credential.UserName})
-				if err != nil {
-					return err
-				}
-
-				voteWeightThreshold := 1.0 * 1000.0 * 1000.0
-				vestingSharesPreparedString := strings.Split(accounts[0].VestingShares, " ")[0]
-				vestingShares, err := strconv.ParseFloat(vestingSharesPreparedString, 64)
-				if err != nil {
-					return err
-				}
-				if vestingShares > voteWeightThreshold {
-					err = credential.UpdatePower(value, database)
-					if err != nil {
-						return err
-					}
-					msg.Text = fmt.Sprintf("Предоставленная мне в распоряжение сила Голоса "+
-						"для аккаунта *%s* теперь равна *%d%%*", credential.UserName, value)
-				} else {
-					msg.Text = "У тебя пока слишком маленькая Сила Голоса для этого"
-				}
-				state.Action = "updatedPower"
-			}
-		default:
-			if update.Message.Chat.Type != "private" {
-				return nil
+				return nil, err
 			}
-			msg.ReplyToMessageID = update.Message.MessageID
-			msg.Text = "Не понимаю"
+			return nil, fmt.Errorf("unable to open database at path: %s", path)
 		}
-		if msg.ReplyMarkup == nil && update.Message.Chat.Type == "private" {
-			firstButton := tgbotapi.NewKeyboardButton(buttonAddKey)
-			secondButton := tgbotapi.NewKeyboardButton(buttonRemoveKey)
-			firstButtonRow := []tgbotapi.KeyboardButton{firstButton, secondButton}
-
-			thirdButton := tgbotapi.NewKeyboardButton(buttonSetPowerLimit)
-			fourthButton := tgbotapi.NewKeyboardButton(buttonInformation)
-			secondButtonRow := []tgbotapi.KeyboardButton{thirdButton, fourthButton}
+		return nil, err
+	}
+	return storage.Open(config.DatabaseDriver, config.DatabaseURL, database)
+}
 
-			keyboard := tgbotapi.NewReplyKeyboard(firstButtonRow, secondButtonRow)
-			msg.ReplyMarkup = keyboard
+// migrate выполняет команды migrate status, migrate up и migrate down
+func migrate(args []string) error {
+	if config.DatabaseDriver == storage.DriverPostgres {
+		return errors.New("migrate работает только с SQLite: миграции PostgreSQL применяются при запуске бота")
+	}
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	if command == "status" {
+		// статус только смотрит на базу и не должен её создавать или менять
+		database, err := db.OpenReadOnly(config.DatabasePath)
+		if err != nil {
+			return err
 		}
-	} else if update.CallbackQuery != nil {
-		arr := strings.Split(update.CallbackQuery.Data, "_")
-		voteStringID, action := arr[0], arr[1]
-		if voteStringID == "curating" {
-			switch action {
-			case "start":
-				if models.IsActiveCurator(userID, database) {
-					msg.Text = "Ты уже являешься куратором"
-					bot.Send(msg)
-					return nil
-				}
-				credential, err := models.GetCredentialByUserID(userID, database)
-				if err == nil && credential.ChatID == 0 {
-					credential.ChatID = chatID
-					credential.Save(database)
-				}
-				msg := tgbotapi.NewEditMessageText(chatID, update.CallbackQuery.Message.MessageID, "")
-				msg.Text = config.CurationRules
-				approveButton := tgbotapi.NewInlineKeyboardButtonData("🐬‍️Я справлюсь", "curating_approve")
-				declineButton := tgbotapi.NewInlineKeyboardButtonData("🐡‍Слишком сложно", "curating_decline")
-				keyboard := []tgbotapi.InlineKeyboardButton{approveButton, declineButton}
-				markup := tgbotapi.NewInlineKeyboardMarkup(keyboard)
-				msg.ReplyMarkup = &markup
-				bot.Send(msg)
-			case "approve":
-				err = models.ActivateCurator(userID, database)
-				if err != nil {
-					return err
-				}
-				msg := tgbotapi.NewEditMessageText(chatID, update.CallbackQuery.Message.MessageID, "")
-				msg.Text = "Отлично, теперь ты будешь участвовать в курировании постов. " +
-					"Скоро я начну присылать тебе ссылки, подожди немного"
-				bot.Send(msg)
-			case "decline":
-				msg := tgbotapi.NewEditMessageText(chatID, update.CallbackQuery.Message.MessageID, "")
-				msg.Text = "Хороший выбор. Курирование чужих постов — сложный и неблагодарный процесс. " +
-					"Лучше пиши свои посты и скидывай мне ссылки на них, а кураторы пусть делают свою работу!"
-				bot.Send(msg)
-			case "stop":
-				msg := tgbotapi.NewEditMessageText(chatID, update.CallbackQuery.Message.MessageID, "")
-				if models.IsActiveCurator(userID, database) {
-					err = models.DeactivateCurator(userID, database)
-					if err != nil {
-						return nil
-					}
-					msg.Text = "Бремя кураторства покинуло тебя. Когда вдоволь насладишься свободой — возвращайся!"
-				} else {
-					msg.Text = "То, что мертво — умереть не может. Так и ты — нельзя отказаться от курирования, не будучи куратором"
-				}
-				bot.Send(msg)
-			default:
-				return errors.New("неподдерживаемое действие: " + action)
-			}
-		} else {
-			voteID, err := strconv.ParseInt(voteStringID, 10, 64)
-			if err != nil {
-				return err
-			}
-			if !models.IsActiveCurator(userID, database) {
-				config := tgbotapi.CallbackConfig{
-					CallbackQueryID: update.CallbackQuery.ID,
-					Text:            "Чекни свои привелегии. Ты не куратор!",
-				}
-				bot.AnswerCallbackQuery(config)
-				return nil
-			}
-
-			voteModel := models.GetVote(database, voteID)
-			if voteModel.Completed {
-				return nil
-			}
-
-			isGood := action == "good"
-			response := models.Response{
-				UserID: userID,
-				VoteID: voteID,
-				Result: isGood,
-				Date:   time.Now(),
-			}
-			text := "И да настигнет Админская кара всех тех, кто пытается злоупотреблять своей властью и голосовать несколько раз! Админь"
-			responseExists := response.Exists(database)
-			if !responseExists {
-				text = "Голос принят"
-				messageID, err := helpers.GetMessageID(update)
-				if err != nil {
-					return err
-				}
-				msg := tgbotapi.NewEditMessageText(chatID, messageID, "")
-				msg.Text = text
-				_, err = bot.Send(msg)
-				if err != nil {
-					log.Println(err.Error())
+		defer database.Close()
+		statuses, err := db.Status(database)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "не применена"
+			if status.Applied {
+				state = "применена"
+				if !status.AppliedAt.IsZero() {
+					state += " " + status.AppliedAt.Format("02.01.2006 15:04")
 				}
 			}
-
-			callbackConfig := tgbotapi.CallbackConfig{
-				CallbackQueryID: update.CallbackQuery.ID,
-				Text:            text,
-			}
-			bot.AnswerCallbackQuery(callbackConfig)
-
-			if !responseExists {
-				_, err := response.Save(database)
-				if err != nil {
-					return err
-				}
+			if status.Modified {
+				state += ", файл изменён после применения"
 			}
+			fmt.Printf("%04d_%s: %s\n", status.Version, status.Name, state)
 		}
 		return nil
 	}
-
-	_, err = state.Save(database)
+	if command != "up" && command != "down" {
+		return errors.New("использование: migrate status|up|down")
+	}
+	backup, err := db.Backup(config.DatabasePath)
 	if err != nil {
 		return err
 	}
-
-	if msg.Text == "" {
-		return errors.New("отсутствует текст сообщения")
+	if len(backup) > 0 {
+		fmt.Printf("Копия базы: %s\n", backup)
 	}
-
-	msg.ParseMode = "Markdown"
-	msg.DisableWebPagePreview = true
-	_, err = bot.Send(msg)
+	database, err := db.Open(config.DatabasePath)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func removeUser(bot *tgbotapi.BotAPI, chatID int64, userID int) error {
-	memberConfig := tgbotapi.KickChatMemberConfig{
-		ChatMemberConfig: tgbotapi.ChatMemberConfig{
-			ChatID: chatID,
-			UserID: userID,
-		},
-		UntilDate: 0,
+	defer database.Close()
+	switch command {
+	case "up":
+		applied, err := db.Up(database)
+		for _, migration := range applied {
+			fmt.Printf("Применена %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Все миграции уже применены")
+		}
+	case "down":
+		migration, err := db.Down(database)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Откачена %04d_%s\n", migration.Version, migration.Name)
 	}
-	_, err := bot.KickChatMember(memberConfig)
-	return err
+	return nil
 }
 
-// https://text.ru/api-check/manual
-func checkUniqueness(text string, voteModel models.Vote) bool {
-	token := config.TextRuToken
-	if len(config.TextRuToken) == 0 {
-		return false
-	}
-
-	text = strip.StripTags(text)
-
-	if len(text) < config.MinimumPostLength {
-		return false
-	}
-
-	cut := func(text string, to int) string {
-		runes := []rune(text)
-		if len(runes) > to {
-			return string(runes[:to])
+// dumpCommand выполняет команды export и import: выгрузку истории кураторства
+// в CSV или JSON Lines и загрузку выгрузки JSON Lines в пустую базу
+func dumpCommand(command string, args []string) error {
+	switch command {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ContinueOnError)
+		format := flags.String("format", dump.FormatJSONL, "формат: csv или jsonl")
+		entity := flags.String("entity", "all", "votes, responses, credentials, referrals или all")
+		since := flags.String("since", "", "начало периода, ГГГГ-ММ-ДД")
+		until := flags.String("until", "", "конец периода, не включая его, ГГГГ-ММ-ДД")
+		output := flags.String("o", "", "файл выгрузки, по умолчанию стандартный вывод")
+		err := flags.Parse(args)
+		if err != nil {
+			return err
 		}
-		return text
-	}
-	maxSymbolCount := 2000
-	text = cut(text, maxSymbolCount)
-
-	httpClient := http.Client{}
-	form := url.Values{}
-	form.Add("text", text)
-	form.Add("userkey", token)
-	domainList := strings.Join(config.Domains, ",")
-	form.Add("exceptdomain", domainList)
-	form.Add("visible", "vis_on")
-	req, err := http.NewRequest("POST", "http://api.text.ru/post", strings.NewReader(form.Encode()))
-	if err != nil {
-		log.Println(err.Error())
-		return false
-	}
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Println(err.Error())
-		return false
-	}
-	if resp.StatusCode != 200 {
-		log.Println("статус не 200")
-		return false
-	}
-	type Uid struct {
-		TextUid string `json:"text_uid"`
-	}
-	var uid Uid
-	jsonParser := json.NewDecoder(resp.Body)
-	jsonParser.Decode(&uid)
-	if len(uid.TextUid) == 0 {
-		log.Println("Не распарсили text_uid")
-		return false
-	}
-	step := 0
-	for step < 50 {
-		step += 1
-		time.Sleep(time.Second * 15)
-		log.Printf("step %d", step)
-		client := http.Client{}
-		form := url.Values{}
-		form.Add("uid", uid.TextUid)
-		form.Add("userkey", token)
-		//form.Add("jsonvisible", "detail")
-		req, err := http.NewRequest("POST", "http://api.text.ru/post", strings.NewReader(form.Encode()))
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		resp, err := client.Do(req)
+		options, err := exportOptions(*format, *entity, *since, *until)
 		if err != nil {
-			log.Println(err.Error())
-			return false
+			return err
 		}
-		type Result struct {
-			TextUnique string `json:"text_unique"`
-			ResultJson string `json:"result_json"`
+		out := os.Stdout
+		if len(*output) > 0 {
+			out, err = os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
 		}
-		var result Result
-		jsonParser := json.NewDecoder(resp.Body)
-		jsonParser.Decode(&result)
-		if len(result.TextUnique) == 0 {
-			continue
+		count, err := dump.Export(out, store, options)
+		if err != nil {
+			return err
 		}
-		textUnique, err := strconv.ParseFloat(result.TextUnique, 32)
+		fmt.Fprintf(os.Stderr, "Выгружено строк: %d\n", count)
+	case "import":
+		if len(args) != 1 {
+			return errors.New("использование: import <файл.jsonl>")
+		}
+		file, err := os.Open(args[0])
 		if err != nil {
-			log.Println(err.Error())
-			return false
+			return err
 		}
-		log.Println(textUnique)
-		if textUnique < 20 {
-			voteModel.Completed = true
-			_, err := voteModel.Save(database)
-			if err != nil {
-				log.Println(err.Error())
-				return false
-			}
-			return false
-		} else {
-			random := func(min, max int) int {
-				rand.Seed(time.Now().Unix())
-				return rand.Intn(max-min) + min
-			}
-			imageNumber := random(1, 18)
-			report := fmt.Sprintf("[![Уникальность проверена через TEXT.RU](https://text.ru/image/get/%s/%d)](https://text.ru/antiplagiat/%s)",
-				uid.TextUid, imageNumber, uid.TextUid)
-			err = helpers.SendComment(voteModel.Author, voteModel.Permalink, report, config)
-			if err != nil {
-				log.Println(err.Error())
-			}
-			return true
+		defer file.Close()
+		counts, err := dump.Import(file, store, models.SystemActor("import"))
+		if err != nil {
+			return err
 		}
-		// если дошли сюда, то выходим из цикла
-		break
+		fmt.Println(importSummary(counts, i18n.Default))
 	}
-	return false
+	return nil
 }
 
-func sendReferralFee(referrer string, referral string) {
-	if referrer == referral {
-		log.Printf("Пригласивший и приглашенный %s совпадают", referral)
+// newRouter регистрирует обработчики команд, кнопок и сообщений
+func newRouter() (*router.Router, error) {
+	domainRegexp, err := helpers.GetDomainRegexp(config.Domains)
+	if err != nil {
+		return nil, err
+	}
+	routes := router.New()
+	// сообщения группы и голоса кураторов не ограничиваем: их поток задают не пользователи бота
+	routes.Use(router.Logging(), router.Recover(), router.When(func(ctx *router.Context) bool {
+		return !isGroupMessage(ctx) && !isVoteCallback(ctx)
+	}, router.RateLimit(30, time.Minute, rateLimited)))
+
+	adminOnly := router.Allow(isAdminContext, func(ctx *router.Context) error {
+		return reply(ctx, tgbotapi.NewMessage(ctx.ChatID, i18n.T(ctx.Lang, "admins_only")))
+	})
+
+	routes.Match(isGroupMessage, func(ctx *router.Context) error {
+		return processGroupMessage(ctx.Message())
+	})
+	routes.Match(func(ctx *router.Context) bool {
+		return ctx.Message() != nil && !ctx.Message().Chat.IsPrivate()
+	}, ignoreUpdate)
+
+	routes.Command("start", handleStart, interruptDialog)
+	routes.Command("invite", handleInvite, interruptDialog)
+	routes.Command("referrals", handleReferrals, interruptDialog, adminOnly)
+	routes.Command("stats", handleStats, interruptDialog)
+	routes.Command("language", handleLanguage, interruptDialog)
+	routes.Command("my", handleMy, interruptDialog)
+	routes.Command("cancel", handleCancel)
+	for _, command := range []string{"ban", "unban", "close", "forcevote", "reopen", "broadcast", "curators", "queue", "user", "removals", "audit"} {
+		routes.Command(command, handleAdminCommand, interruptDialog, adminOnly)
+	}
+	routes.Command("export", handleExport, interruptDialog, adminOnly)
+	routes.Command("import", handleImport, interruptDialog, adminOnly)
+	// выгрузку для загрузки присылают файлом с подписью /import
+	routes.Match(func(ctx *router.Context) bool {
+		message := ctx.Message()
+		return message != nil && message.Document != nil && strings.HasPrefix(message.Caption, "/import")
+	}, handleImport, interruptDialog, adminOnly)
+
+	routes.Button(buttonAddKey, handleAddKey)
+	routes.Button(buttonRemoveKey, handleRemoveKey)
+	routes.Button(buttonSetPowerLimit, handleSetPower)
+	routes.Button(buttonInformation, handleInformation)
+	routes.Match(func(ctx *router.Context) bool {
+		return ctx.Message() != nil && domainRegexp.MatchString(ctx.Message().Text)
+	}, func(ctx *router.Context) error {
+		return handlePostLink(ctx, domainRegexp)
+	})
+	routes.Match(awaiting(conversation.AwaitingLogin), handleLogin)
+	routes.Match(awaiting(conversation.AwaitingPower), handlePower)
+
+	routes.Callback("curating", handleCurating)
+	routes.Callback("claim", handleClaimCheck)
+	routes.Callback("referral", handleReferralDecision, router.Allow(isAdminContext, func(ctx *router.Context) error {
+		return errors.New("недопустимое решение по рефералу: " + ctx.Callback().Data)
+	}))
+	routes.Callback("stats", handleStatsCallback)
+	routes.Callback("my", handleMyCallback)
+	routes.Callback("power", handlePowerCallback)
+	routes.Callback("stop", handleStopCallback)
+	routes.Callback("lang", handleLanguageCallback)
+	routes.Match(isVoteCallback, handleVoteCallback)
+
+	routes.Fallback(handleUnknown)
+	return routes, nil
+}
+
+// processUpdate собирает контекст обновления и передаёт его обработчикам
+func processUpdate(update tgbotapi.Update) {
+	ctx, err := newContext(update)
+	if err != nil {
+		log.Println(err)
 		return
 	}
-	golos := golosClient.NewApi(config.Rpc, config.Chain)
-	defer golos.Rpc.Close()
-	accounts, err := golos.Rpc.Database.GetAccounts([]string{referral})
+	// ошибки обработчиков пишет в лог router.Logging
+	routes.Handle(ctx)
+}
+
+func newContext(update tgbotapi.Update) (*router.Context, error) {
+	ctx := &router.Context{Update: update, Now: time.Now()}
+	var err error
+	ctx.ChatID, err = helpers.GetChatID(update)
 	if err != nil {
-		log.Println("Не получили аккаунт " + referral)
-		return
+		return nil, err
 	}
-	if accounts[0].PostCount.Int64() < int64(config.ReferralMinimumPostCount) {
-		log.Printf("За новичка %s награды не будет, слишком мало постов", referral)
-		return
+	ctx.UserID, err = helpers.GetUserID(update)
+	if err != nil {
+		return nil, err
 	}
-	amount := fmt.Sprintf("%.3f GOLOS", config.ReferralFee)
-	err = golos.TransferToVesting(config.Account, referrer, amount)
-	err2 := golos.TransferToVesting(config.Account, referral, amount)
+	state, err := store.GetStateByUserID(ctx.UserID)
 	if err != nil {
-		log.Println(fmt.Sprintf("Не отправили силу голоса %s \nаккаунту %s", err.Error(), referrer))
+		return nil, err
 	}
-	if err2 != nil {
-		log.Println(fmt.Sprintf("Не отправили силу голоса %s \nаккаунту %s", err.Error(), referral))
+	ctx.Dialog = conversation.Conversation{
+		State:   conversation.State(state.Action),
+		Payload: state.Payload,
+		Expires: state.Expires,
 	}
-	if err != nil || err2 != nil {
-		return
+	ctx.Lang = userLanguage(ctx.UserID, ctx.From())
+	return ctx, nil
+}
+
+// reply отвечает в личный чат, сохранив диалог. Без другой клавиатуры показывается основная
+func reply(ctx *router.Context, msg tgbotapi.MessageConfig) error {
+	err := saveConversation(ctx.UserID, ctx.Dialog)
+	if err != nil {
+		return err
 	}
-	markdownLink := func(account string) string {
-		return fmt.Sprintf("[@%s](https://golos.io/@%s/transfers)", account, account)
+	if msg.Text == "" {
+		return errors.New("отсутствует текст сообщения")
+	}
+	if msg.ReplyMarkup == nil {
+		msg.ReplyMarkup = mainKeyboard(ctx.Lang)
 	}
-	referrerLink := markdownLink(referrer)
-	referralLink := markdownLink(referral)
-	text := fmt.Sprintf("Пригласивший %s и приглашённый %s получают по %.3f Силы Голоса в рамках партнёрской программы",
-		referrerLink, referralLink, config.ReferralFee)
-	msg := tgbotapi.NewMessage(config.GroupID, text)
 	msg.ParseMode = "Markdown"
+	msg.DisableWebPagePreview = true
 	_, err = bot.Send(msg)
+	return err
+}
+
+// fire переводит диалог пользователя в следующее состояние
+func fire(ctx *router.Context, event conversation.Event, payload string) error {
+	dialog, err := ctx.Dialog.Fire(event, payload, ctx.Now)
 	if err != nil {
-		log.Println("Не отправили сообщение: " + err.Error())
+		return err
 	}
+	ctx.Dialog = dialog
+	return nil
 }
 
-func checkAuthority() {
-	for {
-		credentials, err := models.GetAllActiveCredentials(database)
-		log.Printf("Загружено %d аккаунтов для проверки", len(credentials))
+// interruptDialog завершает начатый диалог: любая команда его прерывает
+func interruptDialog(next router.Handler) router.Handler {
+	return func(ctx *router.Context) error {
+		err := fire(ctx, conversation.Cancel, "")
 		if err != nil {
-			log.Println(err.Error())
-		}
-		golos := golosClient.NewApi(config.Rpc, config.Chain)
-		for _, credential := range credentials {
-			if !golos.Verify_Delegate_Posting_Key_Sign(credential.UserName, config.Account) {
-				log.Printf("Пользователь %s отключён", credential.UserName)
-				credential.Active = false
-				_, err = credential.Save(database)
-				if err != nil {
-					log.Println(err.Error())
-				}
-			}
+			return err
 		}
-		golos.Rpc.Close()
-		time.Sleep(1 * time.Hour)
+		return next(ctx)
 	}
 }
 
-func newPost(voteID int64, author string, permalink string, chatID int64) {
-	curatorChatIDs, err := models.GetAllActiveCurstorsChatID(database)
-	if err != nil {
-		log.Println(err.Error())
-		return
+func awaiting(state conversation.State) router.Matcher {
+	return func(ctx *router.Context) bool {
+		return ctx.Message() != nil && ctx.Dialog.Current(ctx.Now) == state
 	}
-	curateText := "Новый пост - новая оценка. Курируй, куратор\n" + helpers.GetInstantViewLink(author, permalink)
-	for _, curatorChatID := range curatorChatIDs {
-		if curatorChatID == chatID {
-			continue
-		}
-		msg := tgbotapi.NewMessage(curatorChatID, curateText)
-		markup := helpers.GetVoteMarkup(voteID)
-		msg.ReplyMarkup = markup
-		msg.DisableWebPagePreview = false
+}
 
-		_, err := bot.Send(msg)
-		if err != nil {
-			log.Println(fmt.Sprintf("Не смогли отправить сообщение куратору %n", curatorChatID))
-		}
-	}
+func ignoreUpdate(ctx *router.Context) error {
+	return nil
 }
 
-func queueProcessor() {
-	for {
-		// TODO: вынести минуты в настройки
-		time.Sleep(36 * time.Minute)
-		log.Println("Начинаю голосование за лучший пост")
-		votes, err := models.GetAllOpenedVotes(database)
-		if err != nil {
-			log.Println(err.Error())
-			continue
-		}
-		if len(votes) == 0 {
-			log.Println("Нет открытых голосований")
-			continue
-		}
-		maxDiff := 0
-		mostLikedPost := votes[0]
-		for _, vote := range votes {
-			var positives, negatives int
-			positives, negatives = models.GetNumResponsesVoteID(vote.VoteID, database)
-			diff := positives - negatives
-			if diff > maxDiff {
-				maxDiff = diff
-				mostLikedPost = vote
-			}
-		}
-		log.Printf("Лучший пост определен: %s/%s", mostLikedPost.Author, mostLikedPost.Permalink)
-		successVotesCount, err := helpers.Vote(mostLikedPost, database, config)
-		text := fmt.Sprintf("Успешно проголосовала c %d аккаунтов за пост\n%s",
-			successVotesCount,
-			helpers.GetInstantViewLink(mostLikedPost.Author, mostLikedPost.Permalink))
-		if err != nil {
-			log.Println(err.Error())
-			text = fmt.Sprintf("В процессе голосования произошла ошибка, свяжитесь с разработчиком - %s\n%s",
-				config.Developer,
-				helpers.GetInstantViewLink(mostLikedPost.Author, mostLikedPost.Permalink))
-		}
-		msg := tgbotapi.NewMessage(config.GroupID, text)
-		_, err = bot.Send(msg)
-		if err != nil {
-			log.Println(err.Error())
-		}
-	}
+func isGroupMessage(ctx *router.Context) bool {
+	return ctx.Message() != nil && ctx.ChatID == config.GroupID
 }
 
-func freshnessPolice() {
-	golos := golosClient.NewApi(config.Rpc, config.Chain)
-	votes, err := models.GetAllOpenedVotes(database)
-	log.Printf("Загружено %d постов для проверки", len(votes))
-	if err != nil {
-		log.Panic(err.Error())
-	}
-	for _, vote := range votes {
-		post, err := golos.Rpc.Database.GetContent(vote.Author, vote.Permalink)
-		if err != nil {
-			log.Println(err.Error())
-			continue
-		}
-		if post.Mode != "first_payout" {
-			vote.Completed = true
-			vote.Addled = true
-			vote.Save(database)
-			go excuseUs(vote)
-		}
+// isVoteCallback узнаёт кнопки оценки поста: они начинаются с номера голосования
+func isVoteCallback(ctx *router.Context) bool {
+	if ctx.Callback() == nil {
+		return false
 	}
-	golos.Rpc.Close()
-	time.Sleep(1 * time.Hour)
-	freshnessPolice()
+	_, err := strconv.ParseInt(router.CallbackPrefix(ctx.Callback().Data), 10, 64)
+	return err == nil
 }
 
-func excuseUs(vote models.Vote) {
-	positives, negatives := models.GetNumResponsesVoteID(vote.VoteID, database)
-	var msg tgbotapi.MessageConfig
-	if positives >= negatives {
-		text := fmt.Sprintf("Прости, %s, твой пост (%s/%s) так и не дождался своих голосов. В следующий раз напиши что-нибудь "+
-			"получше и кураторы обязательно это оценят", vote.Author, vote.Author, vote.Permalink)
-		msg = tgbotapi.NewMessage(config.GroupID, text)
-	} else {
-		vote.Rejected = true
-		vote.Save(database)
-		text := fmt.Sprintf("Пoст %d/%d был отклонен кураторами", vote.Author, vote.Permalink)
-		msg = tgbotapi.NewMessage(config.GroupID, text)
-	}
-	_, err := bot.Send(msg)
-	if err != nil {
-		log.Println(err)
+func rateLimited(ctx *router.Context) error {
+	log.Printf("Пользователь %d присылает слишком много запросов, пропускаем", ctx.UserID)
+	// без ответа Telegram до таймаута крутит на кнопке индикатор загрузки
+	if ctx.Callback() != nil {
+		bot.AnswerCallbackQuery(tgbotapi.CallbackConfig{
+			CallbackQueryID: ctx.Callback().ID,
+			Text:            i18n.T(ctx.Lang, "rate_limited"),
+		})
 	}
+	return nil
 }
 
-func supportedPostsReporter() {
-	time.Sleep(models.WannaSleepOneDay(12, 0)) // Спать до 12:00 следующего дня
-	for {
-		//supportedPosts, err:= models.GetTrulyCompletedVotesSince(models.GetLastReportDate(database), database)
-		//if err != nil {
-		//	log.Println(err)
-		//} else {
-		//Я понятия не имею, как постить пост
-		//err := golos.Post(config.Account, title, body, permlink, "", post_image string, config.ReportTags, v *PC_Vote, o *PC_Options)
-		//if err != nil {
-		//	log.Println(err)
-		//} else {
-		//	models.NewReportPosted(database)
-		//}
-
-		//}
-		time.Sleep(24 * time.Hour)
+func handleUnknown(ctx *router.Context) error {
+	if ctx.Message() == nil {
+		return errors.New("неподдерживаемое обновление")
 	}
+	msg := tgbotapi.NewMessage(ctx.ChatID, i18n.T(ctx.Lang, "not_understood"))
+	msg.ReplyToMessageID = ctx.Message().MessageID
+	return reply(ctx, msg)
 }
 
-func curationMotivator() {
-	time.Sleep(models.WannaSleepTill(0, 20, 0)) // Спать до 20:00 ближайшего воскресенья
-	for {
-		lastRewardDate := models.GetLastRewardDate(database)
-		allResponses := models.GetNumResponsesForMotivation(lastRewardDate, database)
-		var needResponsesToBeRewarded int
-
-		golos := golosClient.NewApi(config.Rpc, config.Chain)
-		defer golos.Rpc.Close()
-		accounts, err := golos.Rpc.Database.GetAccounts([]string{config.Account})
-		if err != nil {
-			log.Println(err)
-		} else {
-			gold, _ := strconv.Atoi(strings.Replace(strings.Replace(accounts[0].SbdBalance, ".", "", 1), " GBG", "", 1))
-			if gold < allResponses {
-				needResponsesToBeRewarded = allResponses / gold
-			} else {
-				needResponsesToBeRewarded = 1
-			}
-			curatorIDs, err := models.GetUserIDsForMotivation(lastRewardDate, database)
-			if err != nil {
-				log.Println(err)
-			} else {
-				for _, userID := range curatorIDs {
-					credential, err := models.GetCredentialByUserID(userID, database)
-					if !credential.Active || err != nil {
-						continue
+func handleStart(ctx *router.Context) error {
+	message := ctx.Message()
+	username := i18n.T(ctx.Lang, "start.username")
+	if len(message.From.FirstName) > 0 {
+		username = message.From.FirstName
+	}
+	msg := tgbotapi.NewMessage(ctx.ChatID,
+		i18n.Markdown(ctx.Lang, "start", username, config.Repository, i18n.T(ctx.Lang, buttonAddKey), config.Developer))
+	// save referral if exists
+	if len(message.CommandArguments()) > 0 {
+		_, err := store.GetCredentialByUserID(ctx.UserID)
+		if err == sql.ErrNoRows {
+			token, err := helpers.ParseReferralToken(message.CommandArguments(), config.ReferralSecret, time.Now())
+			if err == nil {
+				referrer, err := store.GetCredentialByUserName(token.Referrer)
+				if err == nil && referrer.Active == true {
+					referral := models.Referral{UserID: ctx.UserID,
+						Referrer:  token.Referrer,
+						UserName:  "",
+						Campaign:  token.Campaign,
+						Completed: false}
+					err = store.SaveReferral(referral, models.UserActor(ctx.UserID))
+					if err != nil {
+						log.Println("не сохранили реферала: " + err.Error())
 					}
-					curatorResponses := models.GetNumResponsesForMotivationForUserID(userID, lastRewardDate, database)
-					goldForCurator := curatorResponses / needResponsesToBeRewarded
-					amount := fmt.Sprintf("%d.%.3d GBG", goldForCurator/1000, goldForCurator%1000)
-					err = golos.Transfer(config.Account, credential.UserName, "Вознаграждение для кураторов", amount)
 				}
+			} else {
+				log.Printf("не приняли реферальную ссылку %s: %s", message.CommandArguments(), err.Error())
 			}
 		}
-		time.Sleep(7 * 24 * time.Hour)
 	}
+	return reply(ctx, msg)
+}
+
+func handleCancel(ctx *router.Context) error {
+	msg := tgbotapi.NewMessage(ctx.ChatID, i18n.T(ctx.Lang, "cancel.done"))
+	if ctx.Dialog.Current(ctx.Now) == conversation.Idle {
+		msg.Text = i18n.T(ctx.Lang, "cancel.nothing")
+	}
+	err := fire(ctx, conversation.Cancel, "")
+	if err != nil {
+		return err
+	}
+	return reply(ctx, msg)
+}
+
+func saveConversation(userID int, dialog conversation.Conversation) error {
+	state := models.State{
+		UserID:  userID,
+		Action:  string(dialog.State),
+		Payload: dialog.Payload,
+		Expires: dialog.Expires,
+	}
+	err := store.SaveState(state)
+	return err
 }
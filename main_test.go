@@ -1 +1,46 @@
 package main
+
+import (
+	"testing"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	configuration "github.com/GolosTools/golos-vote-bot/config"
+	"github.com/GolosTools/golos-vote-bot/db"
+	"github.com/GolosTools/golos-vote-bot/router"
+	"github.com/GolosTools/golos-vote-bot/storage"
+)
+
+// setUp подменяет глобальные хранилище и настройки бота чистыми тестовыми
+func setUp(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store = storage.NewSQLite(database)
+	t.Cleanup(func() { store.Close() })
+	config = configuration.Config{GroupID: -100, Admins: []int{1}}
+}
+
+func TestIsVoteCallback(t *testing.T) {
+	vote := &router.Context{Update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{Data: "42_good"}}}
+	if !isVoteCallback(vote) {
+		t.Error("кнопка оценки поста начинается с номера голосования")
+	}
+	stats := &router.Context{Update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{Data: "stats_week"}}}
+	if isVoteCallback(stats) || isVoteCallback(&router.Context{}) {
+		t.Error("остальные обновления не голоса кураторов")
+	}
+}
+
+func TestIsGroupMessage(t *testing.T) {
+	setUp(t)
+	group := &router.Context{ChatID: config.GroupID, Update: tgbotapi.Update{Message: &tgbotapi.Message{}}}
+	if !isGroupMessage(group) {
+		t.Error("сообщение из группы кураторов")
+	}
+	private := &router.Context{ChatID: 5, Update: tgbotapi.Update{Message: &tgbotapi.Message{}}}
+	if isGroupMessage(private) {
+		t.Error("личное сообщение не из группы")
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminAction — запись журнала команд администраторов
+type AdminAction struct {
+	ID        int64
+	AdminID   int
+	Command   string
+	Arguments string
+	Result    string
+	Date      time.Time
+}
+
+func (action AdminAction) Save(db Executor) (int64, error) {
+	result, err := db.Exec("INSERT INTO admin_actions(admin_id, command, arguments, result, date) "+
+		"values(?, ?, ?, ?, ?)",
+		action.AdminID, action.Command, action.Arguments, action.Result, action.Date)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetLastAdminActions возвращает последние команды администраторов, новые первыми
+func GetLastAdminActions(limit int, db Executor) (actions []AdminAction, err error) {
+	rows, err := db.Query("SELECT id, admin_id, command, arguments, result, date FROM admin_actions "+
+		"ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return actions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var action AdminAction
+		err = rows.Scan(&action.ID, &action.AdminID, &action.Command, &action.Arguments, &action.Result, &action.Date)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
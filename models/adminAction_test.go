@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestGetLastAdminActions(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{"ban", "close", "reopen"} {
+		_, err = AdminAction{AdminID: 1, Command: command, Arguments: "42", Date: time.Now()}.Save(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	actions, err := GetLastAdminActions(2, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Command != "reopen" || actions[1].Command != "close" {
+		t.Errorf("неожиданный журнал %#v", actions)
+	}
+}
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Виды участников, меняющих данные
+const (
+	ActorUser   = "user"
+	ActorAdmin  = "admin"
+	ActorSystem = "system"
+)
+
+// Actor — кто меняет данные: пользователь или администратор, заданный номером в Telegram,
+// либо фоновая задача бота, заданная названием
+type Actor struct {
+	Kind string
+	ID   int
+	Job  string
+}
+
+func UserActor(userID int) Actor {
+	return Actor{Kind: ActorUser, ID: userID}
+}
+
+func AdminActor(adminID int) Actor {
+	return Actor{Kind: ActorAdmin, ID: adminID}
+}
+
+func SystemActor(job string) Actor {
+	return Actor{Kind: ActorSystem, Job: job}
+}
+
+// String — запись участника в журнале: user:123, admin:5, system:rewards
+func (actor Actor) String() string {
+	if actor.Kind == ActorSystem {
+		return actor.Kind + ":" + actor.Job
+	}
+	return actor.Kind + ":" + strconv.Itoa(actor.ID)
+}
+
+// ParseActor разбирает запись участника, сделанную String
+func ParseActor(value string) (Actor, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return Actor{}, errors.New("участник записывается как user:<ID>, admin:<ID> или system:<задача>")
+	}
+	switch parts[0] {
+	case ActorUser, ActorAdmin:
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return Actor{}, errors.New("неверный ID участника: " + parts[1])
+		}
+		return Actor{Kind: parts[0], ID: id}, nil
+	case ActorSystem:
+		return SystemActor(parts[1]), nil
+	}
+	return Actor{}, errors.New("неизвестный вид участника: " + parts[0])
+}
+
+// Сущности, изменения которых попадают в журнал. Служебные таблицы (диалоги, очередь
+// сообщений, курсоры) в журнал не пишутся, а голоса аккаунтов, удаления из группы, теги голосований,
+// команды администраторов и события и так только дописываются
+const (
+	EntityCredential = "credential"
+	EntityVote       = "vote"
+	EntityResponse   = "response"
+	EntityReferral   = "referral"
+	EntityTrust      = "trust"
+	EntityBan        = "ban"
+	EntityClaim      = "claim"
+	EntityLanguage   = "language"
+	EntityMember     = "member"
+)
+
+// AuditEntities — все сущности журнала изменений
+var AuditEntities = []string{EntityCredential, EntityVote, EntityResponse, EntityReferral,
+	EntityTrust, EntityBan, EntityClaim, EntityLanguage, EntityMember}
+
+// Change — запись журнала изменений. Before и After — значения сущности в JSON:
+// у созданной сущности пуст Before, у удалённой — After
+type Change struct {
+	ID       int64
+	Actor    string
+	Entity   string
+	EntityID string
+	Before   string
+	After    string
+	Date     time.Time
+}
+
+func (change Change) Save(db Executor) (int64, error) {
+	result, err := db.Exec("INSERT INTO audit(actor, entity, entity_id, old_value, new_value, date) "+
+		"values(?, ?, ?, ?, ?, ?)",
+		change.Actor, change.Entity, change.EntityID, change.Before, change.After, change.Date)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// NewChange готовит запись журнала об изменении сущности. nil вместо before или after означает,
+// что сущности до или после изменения нет. Если ничего не изменилось, Before и After равны
+func NewChange(actor Actor, entity string, entityID string, before interface{}, after interface{}) (change Change, err error) {
+	change = Change{
+		Actor:    actor.String(),
+		Entity:   entity,
+		EntityID: entityID,
+		Date:     time.Now(),
+	}
+	change.Before, err = encodeValue(before)
+	if err != nil {
+		return change, err
+	}
+	change.After, err = encodeValue(after)
+	return change, err
+}
+
+// RecordChange записывает в журнал изменение сущности. Если ничего не изменилось, запись не делается.
+// Чтобы изменение не осталось без записи, вызывать его нужно в той же транзакции, что и само изменение
+func RecordChange(actor Actor, entity string, entityID string, before interface{}, after interface{}, db Executor) error {
+	change, err := NewChange(actor, entity, entityID, before, after)
+	if err != nil || change.Before == change.After {
+		return err
+	}
+	_, err = change.Save(db)
+	return err
+}
+
+func encodeValue(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// FieldChange — поле сущности, изменившееся в записи журнала
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Diff возвращает изменившиеся поля по алфавиту. У созданной или удалённой
+// сущности перечисляются все поля
+func (change Change) Diff() ([]FieldChange, error) {
+	before, err := decodeValue(change.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeValue(change.After)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	var diff []FieldChange
+	for field := range fields {
+		previous, current := string(before[field]), string(after[field])
+		if previous != current {
+			diff = append(diff, FieldChange{Field: field, Before: previous, After: current})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Field < diff[j].Field
+	})
+	return diff, nil
+}
+
+func decodeValue(value string) (fields map[string]json.RawMessage, err error) {
+	if len(value) == 0 {
+		return fields, nil
+	}
+	err = json.Unmarshal([]byte(value), &fields)
+	return fields, err
+}
+
+// ChangeFilter — условия выборки из журнала. Пустые поля ничего не ограничивают
+type ChangeFilter struct {
+	Actor    string
+	Entity   string
+	EntityID string
+	Since    time.Time
+}
+
+// GetChanges возвращает последние изменения, подходящие под фильтр, новые первыми
+func GetChanges(filter ChangeFilter, limit int, db Executor) (changes []Change, err error) {
+	query := "SELECT id, actor, entity, entity_id, old_value, new_value, date FROM audit WHERE date >= ?"
+	args := []interface{}{filter.Since}
+	if len(filter.Actor) > 0 {
+		query += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if len(filter.Entity) > 0 {
+		query += " AND entity = ?"
+		args = append(args, filter.Entity)
+	}
+	if len(filter.EntityID) > 0 {
+		query += " AND entity_id = ?"
+		args = append(args, filter.EntityID)
+	}
+	rows, err := db.Query(query+" ORDER BY id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		return changes, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change Change
+		err = rows.Scan(&change.ID,
+			&change.Actor,
+			&change.Entity,
+			&change.EntityID,
+			&change.Before,
+			&change.After,
+			&change.Date)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
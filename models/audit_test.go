@@ -0,0 +1,151 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+var testActor = SystemActor("test")
+
+func TestActor(t *testing.T) {
+	for _, actor := range []Actor{UserActor(42), AdminActor(7), SystemActor("rewards")} {
+		parsed, err := ParseActor(actor.String())
+		if err != nil || parsed != actor {
+			t.Errorf("%s разобран как %#v, ошибка %v", actor, parsed, err)
+		}
+	}
+	for _, value := range []string{"", "user", "user:abc", "robot:1", "system:"} {
+		if _, err := ParseActor(value); err == nil {
+			t.Errorf("%q разобран без ошибки", value)
+		}
+	}
+}
+
+func TestRecordChange(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := Credential{UserID: 1, ChatID: 1, UserName: "chiliec", Power: 100, Active: true}
+	_, err = credential.Save(UserActor(1), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// повторное сохранение без изменений в журнал не попадает
+	_, err = credential.Save(UserActor(1), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DeactivateCurator(1, AdminActor(2), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ActivateCurator(1, AdminActor(2), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = credential.UpdatePower(50, SystemActor("power"), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := GetChanges(ChangeFilter{Entity: EntityCredential, EntityID: "chiliec"}, 10, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("%d изменений вместо 3: %#v", len(changes), changes)
+	}
+	if changes[2].Before != "" || !strings.Contains(changes[2].After, `"Power":100`) {
+		t.Errorf("неожиданное создание аккаунта %#v", changes[2])
+	}
+	if changes[1].Actor != "admin:2" || !strings.Contains(changes[1].After, `"Curates":true`) {
+		t.Errorf("неожиданное назначение куратором %#v", changes[1])
+	}
+	if changes[0].Actor != "system:power" || !strings.Contains(changes[0].Before, `"Power":100`) ||
+		!strings.Contains(changes[0].After, `"Power":50`) {
+		t.Errorf("неожиданное изменение силы %#v", changes[0])
+	}
+
+	_, err = Ban{Kind: BanAuthor, Target: "spammer", AdminID: 2, Date: time.Now()}.Save(AdminActor(2), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DeleteBan(BanAuthor, "spammer", AdminActor(2), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err = GetChanges(ChangeFilter{Actor: "admin:2", Entity: EntityBan}, 10, database)
+	if err != nil || len(changes) != 2 || changes[0].After != "" || changes[0].EntityID != "author:spammer" {
+		t.Errorf("неожиданный журнал банов %#v, ошибка %v", changes, err)
+	}
+	changes, err = GetChanges(ChangeFilter{Since: time.Now().Add(time.Hour)}, 10, database)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("изменения из будущего %#v, ошибка %v", changes, err)
+	}
+}
+
+func TestChangeInTransaction(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// журнал отказывается принимать записи: изменение без записи о нём не должно сохраниться
+	_, err = database.Exec("CREATE TEMP TRIGGER audit_broken BEFORE INSERT ON audit " +
+		"BEGIN SELECT RAISE(ABORT, 'журнал недоступен'); END")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Credential{UserID: 1, UserName: "chiliec", Active: true}.Save(testActor, database)
+	if err == nil || saved {
+		t.Fatal("сохранение должно сорваться вместе с записью в журнал")
+	}
+	if _, err = GetCredentialByUserName("chiliec", database); err == nil {
+		t.Error("аккаунт сохранён без записи в журнале")
+	}
+	if _, err = (Vote{UserID: 1, Author: "chiliec", Permalink: "post"}).Save(testActor, database); err == nil {
+		t.Error("голосование сохранено без записи в журнале")
+	}
+	if votes, _ := GetAllOpenedVotes(database); len(votes) != 0 {
+		t.Errorf("голосования сохранены без записи в журнале: %v", votes)
+	}
+}
+
+func TestAuditAppendOnly(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RecordChange(testActor, EntityMember, "1", nil, Member{UserID: 1}, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = database.Exec("UPDATE audit SET actor = 'user:1'"); err == nil {
+		t.Error("запись журнала изменили")
+	}
+	if _, err = database.Exec("DELETE FROM audit"); err == nil {
+		t.Error("запись журнала удалили")
+	}
+}
+
+func TestChangeDiff(t *testing.T) {
+	change := Change{
+		Before: `{"UserID":1,"Power":100,"Curates":false}`,
+		After:  `{"UserID":1,"Power":50,"Curates":true}`,
+	}
+	diff, err := change.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FieldChange{{Field: "Curates", Before: "false", After: "true"}, {Field: "Power", Before: "100", After: "50"}}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("\n%#v\n%#v\nНе равны!", diff, expected)
+	}
+	diff, err = Change{Before: `{"Kind":"author"}`}.Diff()
+	if err != nil || len(diff) != 1 || diff[0].After != "" {
+		t.Errorf("неожиданные поля удалённой сущности %#v, ошибка %v", diff, err)
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	// BanUser запрещает пользователю Telegram предлагать посты и курировать, Target — его ID
+	BanUser = "user"
+	// BanAuthor запрещает выставлять на голосование посты автора, Target — его логин
+	BanAuthor = "author"
+)
+
+type Ban struct {
+	Kind    string
+	Target  string
+	AdminID int
+	Date    time.Time
+}
+
+func (ban Ban) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := banValue(ban.Kind, ban.Target, tx)
+		_, err := tx.Exec("INSERT OR REPLACE INTO bans(kind, target, admin_id, date) values(?, ?, ?, ?)",
+			ban.Kind, ban.Target, ban.AdminID, ban.Date)
+		if err != nil {
+			return err
+		}
+		after := banValue(ban.Kind, ban.Target, tx)
+		return RecordChange(actor, EntityBan, ban.Kind+":"+ban.Target, before, after, tx)
+	})
+	return err == nil, err
+}
+
+// DeleteBan снимает бан и сообщает, был ли он
+func DeleteBan(kind string, target string, actor Actor, db Executor) (deleted bool, err error) {
+	err = Transaction(db, func(tx Executor) error {
+		before := banValue(kind, target, tx)
+		result, err := tx.Exec("DELETE FROM bans WHERE kind = ? AND target = ?", kind, target)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil || count == 0 {
+			return err
+		}
+		deleted = true
+		return RecordChange(actor, EntityBan, kind+":"+target, before, nil, tx)
+	})
+	return deleted && err == nil, err
+}
+
+// banValue возвращает бан для журнала изменений или nil, если его нет
+func banValue(kind string, target string, db Executor) interface{} {
+	row := db.QueryRow("SELECT kind, target, admin_id, date FROM bans WHERE kind = ? AND target = ?", kind, target)
+	var ban Ban
+	err := row.Scan(&ban.Kind, &ban.Target, &ban.AdminID, &ban.Date)
+	if err != nil {
+		return nil
+	}
+	return ban
+}
+
+func IsBanned(kind string, target string, db Executor) bool {
+	row := db.QueryRow("SELECT COUNT(*) FROM bans WHERE kind = ? AND target = ?", kind, target)
+	var count int
+	row.Scan(&count)
+	return count > 0
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestBans(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ban := Ban{Kind: BanAuthor, Target: "spammer", AdminID: 1, Date: time.Now()}
+	_, err = ban.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsBanned(BanAuthor, "spammer", database) {
+		t.Error("автор должен быть забанен")
+	}
+	if IsBanned(BanUser, "spammer", database) {
+		t.Error("бан автора не должен распространяться на пользователя")
+	}
+	deleted, err := DeleteBan(BanAuthor, "spammer", testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted || IsBanned(BanAuthor, "spammer", database) {
+		t.Error("бан должен сняться")
+	}
+	deleted, err = DeleteBan(BanAuthor, "spammer", testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted {
+		t.Error("повторно снимать нечего")
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Cast — голос, отданный ботом от имени одного из аккаунтов
+type Cast struct {
+	VoteID    int64
+	UserName  string
+	Author    string
+	Permalink string
+	Weight    int
+	Date      time.Time
+}
+
+func (cast Cast) Save(db Executor) (bool, error) {
+	_, err := db.Exec("INSERT INTO casts(vote_id, user_name, author, permalink, weight, date) "+
+		"values(?, ?, ?, ?, ?, ?)",
+		cast.VoteID, cast.UserName, cast.Author, cast.Permalink, cast.Weight, cast.Date)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetCastsCountForVoteID возвращает, сколько аккаунтов проголосовало за пост
+func GetCastsCountForVoteID(voteID int64, db Executor) (count int) {
+	row := db.QueryRow("SELECT COUNT(DISTINCT user_name) FROM casts WHERE vote_id = ?", voteID)
+	row.Scan(&count)
+	return count
+}
+
+func GetCastsByUserNameSince(userName string, date time.Time, db Executor) (casts []Cast, err error) {
+	rows, err := db.Query("SELECT vote_id, user_name, author, permalink, weight, date FROM casts "+
+		"WHERE user_name = ? AND date > ? ORDER BY id", userName, date)
+	if err != nil {
+		return casts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cast Cast
+		err = rows.Scan(&cast.VoteID, &cast.UserName, &cast.Author, &cast.Permalink, &cast.Weight, &cast.Date)
+		if err != nil {
+			return casts, err
+		}
+		casts = append(casts, cast)
+	}
+	return casts, nil
+}
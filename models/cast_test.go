@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestGetCastsByUserNameSince(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	old := Cast{VoteID: 1, UserName: "chiliec", Author: "babin", Permalink: "old", Weight: 10000, Date: now.Add(-40 * 24 * time.Hour)}
+	fresh := Cast{VoteID: 2, UserName: "chiliec", Author: "babin", Permalink: "fresh", Weight: 5000, Date: now}
+	other := Cast{VoteID: 2, UserName: "babin", Author: "babin", Permalink: "fresh", Weight: 5000, Date: now}
+	for _, cast := range []Cast{old, fresh, other} {
+		_, err = cast.Save(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	casts, err := GetCastsByUserNameSince("chiliec", now.Add(-7*24*time.Hour), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(casts) != 1 || casts[0].Permalink != fresh.Permalink || !casts[0].Date.Equal(fresh.Date) {
+		t.Errorf("неожиданные голоса %#v", casts)
+	}
+	casts, err = GetCastsByUserNameSince("chiliec", time.Time{}, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(casts) != 2 {
+		t.Errorf("%d голосов вместо 2", len(casts))
+	}
+}
+
+func TestGetCastsCountForVoteID(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userName := range []string{"chiliec", "babin", "chiliec"} {
+		cast := Cast{VoteID: 1, UserName: userName, Author: "babin", Permalink: "post", Weight: 10000, Date: time.Now()}
+		_, err = cast.Save(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := GetCastsCountForVoteID(1, database); count != 2 {
+		t.Errorf("%d аккаунтов вместо 2", count)
+	}
+	if count := GetCastsCountForVoteID(2, database); count != 0 {
+		t.Errorf("%d аккаунтов вместо 0", count)
+	}
+}
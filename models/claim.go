@@ -0,0 +1,127 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// ClaimTTL — сколько действует код подтверждения. Просроченные заявки удаляются
+const ClaimTTL = 24 * time.Hour
+
+// Claim — заявка пользователя на аккаунт Голоса, ожидающая подтверждения владения
+type Claim struct {
+	UserID   int
+	ChatID   int64
+	UserName string
+	Code     string
+	Date     time.Time
+}
+
+// NewClaim создаёт заявку с одноразовым кодом подтверждения
+func NewClaim(userID int, chatID int64, userName string) (Claim, error) {
+	bytes := make([]byte, 8)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return Claim{}, err
+	}
+	return Claim{
+		UserID:   userID,
+		ChatID:   chatID,
+		UserName: userName,
+		Code:     "gvb-" + hex.EncodeToString(bytes),
+		Date:     time.Now(),
+	}, nil
+}
+
+// Expired сообщает, что код подтверждения заявки больше не действует
+func (claim Claim) Expired(now time.Time) bool {
+	return now.Sub(claim.Date) >= ClaimTTL
+}
+
+func (claim Claim) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := claimValue(claim.UserID, tx)
+		_, err := tx.Exec("INSERT OR REPLACE INTO claims(user_id, chat_id, user_name, code, date) "+
+			"values(?, ?, ?, ?, ?)",
+			claim.UserID, claim.ChatID, claim.UserName, claim.Code, claim.Date)
+		if err != nil {
+			return err
+		}
+		after := claimValue(claim.UserID, tx)
+		return RecordChange(actor, EntityClaim, strconv.Itoa(claim.UserID), before, after, tx)
+	})
+	return err == nil, err
+}
+
+func (claim Claim) Delete(actor Actor, db Executor) error {
+	return Transaction(db, func(tx Executor) error {
+		before := claimValue(claim.UserID, tx)
+		_, err := tx.Exec("DELETE FROM claims WHERE user_id = ?", claim.UserID)
+		if err != nil {
+			return err
+		}
+		return RecordChange(actor, EntityClaim, strconv.Itoa(claim.UserID), before, nil, tx)
+	})
+}
+
+// claimValue возвращает заявку для журнала изменений или nil, если её нет
+func claimValue(userID int, db Executor) interface{} {
+	claim, err := GetClaimByUserID(userID, db)
+	if err != nil {
+		return nil
+	}
+	return claim
+}
+
+func GetClaimByUserID(userID int, db Executor) (claim Claim, err error) {
+	row := db.QueryRow("SELECT user_id, chat_id, user_name, code, date FROM claims WHERE user_id = ?", userID)
+	err = row.Scan(&claim.UserID, &claim.ChatID, &claim.UserName, &claim.Code, &claim.Date)
+	return claim, err
+}
+
+// GetRivalClaims возвращает действующие заявки других пользователей на тот же аккаунт
+func GetRivalClaims(claim Claim, db Executor) (claims []Claim, err error) {
+	rows, err := db.Query("SELECT user_id, chat_id, user_name, code, date FROM claims "+
+		"WHERE user_name = ? AND user_id != ? AND date > ?", claim.UserName, claim.UserID, time.Now().Add(-ClaimTTL))
+	if err != nil {
+		return claims, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rival Claim
+		err = rows.Scan(&rival.UserID, &rival.ChatID, &rival.UserName, &rival.Code, &rival.Date)
+		if err != nil {
+			return claims, err
+		}
+		claims = append(claims, rival)
+	}
+	return claims, nil
+}
+
+// PurgeExpiredClaims удаляет заявки с устаревшими кодами и возвращает их количество
+func PurgeExpiredClaims(now time.Time, actor Actor, db Executor) (int, error) {
+	rows, err := db.Query("SELECT user_id FROM claims WHERE date <= ?", now.Add(-ClaimTTL))
+	if err != nil {
+		return 0, err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		err = rows.Scan(&userID)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	for i, userID := range userIDs {
+		err = Claim{UserID: userID}.Delete(actor, db)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(userIDs), nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestGetRivalClaims(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim, err := NewClaim(1, 2, "chiliec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = claim.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimFromDb, err := GetClaimByUserID(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimFromDb.Code != claim.Code || claimFromDb.UserName != claim.UserName {
+		t.Errorf("\n%#v\n%#v\nНе равны!", claim, claimFromDb)
+	}
+	rival, err := NewClaim(3, 4, "chiliec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rival.Code == claim.Code {
+		t.Error("коды подтверждения должны быть разными")
+	}
+	_, err = rival.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rivals, err := GetRivalClaims(claim, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rivals) != 1 || rivals[0].UserID != 3 {
+		t.Errorf("неожиданные конкурирующие заявки %#v", rivals)
+	}
+	err = rival.Delete(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rivals, _ = GetRivalClaims(claim, database)
+	if len(rivals) != 0 {
+		t.Error("заявка не удалена")
+	}
+}
+
+func TestPurgeExpiredClaims(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	stale := Claim{UserID: 1, ChatID: 1, UserName: "chiliec", Code: "gvb-1", Date: now.Add(-ClaimTTL - time.Minute)}
+	fresh := Claim{UserID: 2, ChatID: 2, UserName: "chiliec", Code: "gvb-2", Date: now}
+	for _, claim := range []Claim{stale, fresh} {
+		_, err = claim.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !stale.Expired(now) || fresh.Expired(now) {
+		t.Error("неправильный срок действия заявок")
+	}
+	rivals, err := GetRivalClaims(fresh, database)
+	if err != nil || len(rivals) != 0 {
+		t.Errorf("просроченная заявка считается конкурирующей: %#v, ошибка %v", rivals, err)
+	}
+	count, err := PurgeExpiredClaims(now, testActor, database)
+	if err != nil || count != 1 {
+		t.Errorf("удалено %d заявок вместо 1, ошибка %v", count, err)
+	}
+	if _, err = GetClaimByUserID(1, database); err == nil {
+		t.Error("просроченная заявка не удалена")
+	}
+	if _, err = GetClaimByUserID(2, database); err != nil {
+		t.Error("действующая заявка удалена")
+	}
+}
@@ -1,10 +1,11 @@
 package models
 
 import (
-	"database/sql"
 	"log"
 )
 
+// Credential — аккаунт Голоса, доверивший боту свою Силу Голоса.
+// У одного пользователя Telegram может быть несколько таких аккаунтов
 type Credential struct {
 	UserID   int
 	ChatID   int64
@@ -14,53 +15,124 @@ type Credential struct {
 	Curates  bool
 }
 
-func (credential Credential) Save(db *sql.DB) (bool, error) {
-	prepare, err := db.Prepare("INSERT OR REPLACE INTO credentials(" +
-		"user_id," +
-		"chat_id," +
-		"user_name," +
-		"power," +
-		"active," +
-		"curates) " +
-		"values(?, ?, ?, ?, ?, ?)")
-	defer prepare.Close()
+const credentialColumns = "user_id, chat_id, user_name, power, active, curates"
+
+func scanCredential(row scanner) (credential Credential, err error) {
+	err = row.Scan(&credential.UserID,
+		&credential.ChatID,
+		&credential.UserName,
+		&credential.Power,
+		&credential.Active,
+		&credential.Curates)
+	return credential, err
+}
+
+func (credential Credential) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := credentialValue(credential.UserName, tx)
+		prepare, err := tx.Prepare("INSERT OR REPLACE INTO credentials(" +
+			"user_id," +
+			"chat_id," +
+			"user_name," +
+			"power," +
+			"active," +
+			"curates) " +
+			"values(?, ?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer prepare.Close()
+		_, err = prepare.Exec(
+			credential.UserID,
+			credential.ChatID,
+			credential.UserName,
+			credential.Power,
+			credential.Active,
+			credential.Curates)
+		if err != nil {
+			return err
+		}
+		after := credentialValue(credential.UserName, tx)
+		return RecordChange(actor, EntityCredential, credential.UserName, before, after, tx)
+	})
+	return err == nil, err
+}
+
+// credentialValue возвращает аккаунт для журнала изменений или nil, если его нет
+func credentialValue(userName string, db Executor) interface{} {
+	credential, err := GetCredentialByUserName(userName, db)
 	if err != nil {
-		return false, err
+		return nil
 	}
-	_, err = prepare.Exec(
-		credential.UserID,
-		credential.ChatID,
-		credential.UserName,
-		credential.Power,
-		credential.Active,
-		credential.Curates)
+	return credential
+}
+
+// GetCredentialByUserID возвращает основной аккаунт пользователя: первый из активных
+func GetCredentialByUserID(userID int, db Executor) (credential Credential, err error) {
+	row := db.QueryRow("SELECT "+credentialColumns+" FROM credentials "+
+		"WHERE user_id = ? ORDER BY active DESC, rowid LIMIT 1", userID)
+	return scanCredential(row)
+}
+
+// GetCredentialsByUserID возвращает все аккаунты пользователя
+func GetCredentialsByUserID(userID int, db Executor) (credentials []Credential, err error) {
+	rows, err := db.Query("SELECT "+credentialColumns+" FROM credentials "+
+		"WHERE user_id = ? ORDER BY rowid", userID)
 	if err != nil {
-		return false, err
+		return credentials, err
 	}
-	return true, nil
+	defer rows.Close()
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			return credentials, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
 }
 
-func GetCredentialByUserID(userID int, db *sql.DB) (credential Credential, err error) {
-	row := db.QueryRow("SELECT user_id, chat_id, user_name, power, active, curates FROM credentials WHERE user_id = ?", userID)
-	err = row.Scan(&credential.UserID, &credential.ChatID, &credential.UserName, &credential.Power, &credential.Active, &credential.Curates)
-	return credential, err
+// GetActiveCredentialsByUserID возвращает аккаунты пользователя, с которых бот может голосовать
+func GetActiveCredentialsByUserID(userID int, db Executor) (active []Credential, err error) {
+	credentials, err := GetCredentialsByUserID(userID, db)
+	for _, credential := range credentials {
+		if credential.Active {
+			active = append(active, credential)
+		}
+	}
+	return active, err
 }
 
-func GetCredentialByUserName(userName string, db *sql.DB) (credential Credential, err error) {
-	row := db.QueryRow("SELECT user_id, chat_id, user_name, power, active, curates FROM credentials WHERE user_name = ?", userName)
-	err = row.Scan(&credential.UserID, &credential.ChatID, &credential.UserName, &credential.Power, &credential.Active, &credential.Curates)
-	return credential, err
+func GetCredentialByUserName(userName string, db Executor) (credential Credential, err error) {
+	row := db.QueryRow("SELECT "+credentialColumns+" FROM credentials WHERE user_name = ?", userName)
+	return scanCredential(row)
+}
+
+// GetAllCredentials возвращает все аккаунты, включая отключённые
+func GetAllCredentials(db Executor) (credentials []Credential, err error) {
+	rows, err := db.Query("SELECT " + credentialColumns + " FROM credentials ORDER BY rowid")
+	if err != nil {
+		return credentials, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			return credentials, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
 }
 
-func GetAllActiveCredentials(db *sql.DB) (credentials []Credential, err error) {
-	rows, err := db.Query("SELECT user_id, chat_id, user_name, power, active, curates FROM credentials")
+func GetAllActiveCredentials(db Executor) (credentials []Credential, err error) {
+	rows, err := db.Query("SELECT " + credentialColumns + " FROM credentials")
 	if err != nil {
 		return credentials, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var credential Credential
-		err := rows.Scan(&credential.UserID, &credential.ChatID, &credential.UserName, &credential.Power, &credential.Active, &credential.Curates)
+		credential, err := scanCredential(rows)
 		if err == nil && credential.Active {
 			credentials = append(credentials, credential)
 		}
@@ -68,40 +140,70 @@ func GetAllActiveCredentials(db *sql.DB) (credentials []Credential, err error) {
 	return credentials, err
 }
 
-func (credential Credential) UpdatePower(power int, db *sql.DB) error {
-	_, err := db.Exec("UPDATE credentials SET power = ? WHERE user_id = ?",
-		power, credential.UserID)
-	return err
+func (credential Credential) UpdatePower(power int, actor Actor, db Executor) error {
+	return Transaction(db, func(tx Executor) error {
+		before := credentialValue(credential.UserName, tx)
+		_, err := tx.Exec("UPDATE credentials SET power = ? WHERE user_name = ?",
+			power, credential.UserName)
+		if err != nil || before == nil {
+			return err
+		}
+		return RecordChange(actor, EntityCredential, credential.UserName, before, credentialValue(credential.UserName, tx), tx)
+	})
 }
 
-func IsActiveCredential(userID int, db *sql.DB) bool {
-	credential, err := GetCredentialByUserID(userID, db)
-	if err != nil {
-		return false
-	}
-	return credential.Active && len(credential.UserName) > 0
+// IsActiveCredential сообщает, есть ли у пользователя хотя бы один активный аккаунт
+func IsActiveCredential(userID int, db Executor) bool {
+	row := db.QueryRow("SELECT COUNT(*) FROM credentials "+
+		"WHERE user_id = ? AND active = 1 AND user_name != ''", userID)
+	var count int
+	row.Scan(&count)
+	return count > 0
 }
 
-func DeactivateCurator(userID int, db *sql.DB) error {
-	_, err := db.Exec("UPDATE credentials SET curates = 0 WHERE user_id = ?", userID)
-	return err
+// DeactivateCurator снимает кураторство с пользователя сразу для всех его аккаунтов
+func DeactivateCurator(userID int, actor Actor, db Executor) error {
+	return setCurates(userID, false, actor, db)
 }
 
-func ActivateCurator(userID int, db *sql.DB) error {
-	_, err := db.Exec("UPDATE credentials SET curates = 1 WHERE user_id = ?", userID)
-	return err
+// ActivateCurator делает пользователя куратором сразу для всех его аккаунтов
+func ActivateCurator(userID int, actor Actor, db Executor) error {
+	return setCurates(userID, true, actor, db)
+}
+
+func setCurates(userID int, curates bool, actor Actor, db Executor) error {
+	return Transaction(db, func(tx Executor) error {
+		credentials, err := GetCredentialsByUserID(userID, tx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE credentials SET curates = ? WHERE user_id = ?", curates, userID)
+		if err != nil {
+			return err
+		}
+		for _, credential := range credentials {
+			err = RecordChange(actor, EntityCredential, credential.UserName,
+				credential, credentialValue(credential.UserName, tx), tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func IsActiveCurator(userID int, db *sql.DB) bool {
-	row := db.QueryRow("SELECT curates FROM credentials WHERE user_id = ?", userID)
-	var result bool
-	row.Scan(&result)
-	return result
+// IsActiveCurator сообщает, курирует ли пользователь сейчас.
+// Кураторство отключённого аккаунта приостанавливается до возвращения доступа
+func IsActiveCurator(userID int, db Executor) bool {
+	row := db.QueryRow("SELECT COUNT(*) FROM credentials WHERE user_id = ? AND curates = 1 AND active = 1", userID)
+	var count int
+	row.Scan(&count)
+	return count > 0
 }
 
-func GetAllActiveCurstorsChatID(db *sql.DB) ([]int64, error) {
+func GetAllActiveCurstorsChatID(db Executor) ([]int64, error) {
 	var chatIDs []int64
-	rows, err := db.Query("SELECT chat_id FROM credentials WHERE curates = 1")
+	rows, err := db.Query("SELECT DISTINCT chat_id FROM credentials WHERE curates = 1 AND active = 1")
 	if err != nil {
 		return chatIDs, err
 	}
@@ -118,9 +220,9 @@ func GetAllActiveCurstorsChatID(db *sql.DB) ([]int64, error) {
 	return chatIDs, err
 }
 
-func GetAllActiveCurstorsID(db *sql.DB) ([]int, error) {
+func GetAllActiveCurstorsID(db Executor) ([]int, error) {
 	var IDs []int
-	rows, err := db.Query("SELECT user_id FROM credentials WHERE curates = 1")
+	rows, err := db.Query("SELECT DISTINCT user_id FROM credentials WHERE curates = 1 AND active = 1")
 	if err != nil {
 		return IDs, err
 	}
@@ -136,3 +238,22 @@ func GetAllActiveCurstorsID(db *sql.DB) ([]int, error) {
 	}
 	return IDs, err
 }
+
+// GetAllChatIDs возвращает чаты всех пользователей, которые когда-либо привязывали аккаунт
+func GetAllChatIDs(db Executor) ([]int64, error) {
+	var chatIDs []int64
+	rows, err := db.Query("SELECT DISTINCT chat_id FROM credentials WHERE chat_id != 0")
+	if err != nil {
+		return chatIDs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var result int64
+		err = rows.Scan(&result)
+		if err != nil {
+			return chatIDs, err
+		}
+		chatIDs = append(chatIDs, result)
+	}
+	return chatIDs, nil
+}
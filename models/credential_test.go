@@ -19,7 +19,7 @@ func TestDbCredentials(t *testing.T) {
 		Active:   true,
 		Curates:  true,
 	}
-	_, err = credential.Save(database)
+	_, err = credential.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -45,7 +45,7 @@ func TestCredential_UpdatePower(t *testing.T) {
 		Active:   true,
 		Curates:  true,
 	}
-	_, err = credential.Save(database)
+	_, err = credential.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -57,7 +57,7 @@ func TestCredential_UpdatePower(t *testing.T) {
 		t.Error("Начальная сила неправильная")
 	}
 
-	err = credential.UpdatePower(42, database)
+	err = credential.UpdatePower(42, testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -87,7 +87,7 @@ func TestIsActiveCredential(t *testing.T) {
 	if exists {
 		t.Error("Не должен быть активным")
 	}
-	_, err = credential.Save(database)
+	_, err = credential.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -96,3 +96,124 @@ func TestIsActiveCredential(t *testing.T) {
 		t.Error("Должен существовать")
 	}
 }
+
+func TestIsActiveCurator(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := Credential{
+		UserID:   1,
+		ChatID:   1,
+		UserName: "chiliec",
+		Power:    100,
+		Active:   true,
+		Curates:  true,
+	}
+	_, err = credential.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsActiveCurator(credential.UserID, database) {
+		t.Error("должен быть куратором")
+	}
+	credential.Active = false
+	_, err = credential.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsActiveCurator(credential.UserID, database) {
+		t.Error("кураторство отключённого аккаунта должно быть приостановлено")
+	}
+	chatIDs, err := GetAllActiveCurstorsChatID(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chatIDs) != 0 {
+		t.Error("приостановленный куратор не должен получать посты")
+	}
+	credentialFromDb, err := GetCredentialByUserID(credential.UserID, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !credentialFromDb.Curates {
+		t.Error("кураторство не должно теряться при отключении")
+	}
+}
+
+func TestGetCredentialsByUserID(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	personal := Credential{UserID: 1, ChatID: 1, UserName: "chiliec", Power: 100, Active: true}
+	project := Credential{UserID: 1, ChatID: 1, UserName: "golostools", Power: 100, Active: true}
+	for _, credential := range []Credential{personal, project} {
+		_, err = credential.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	credentials, err := GetCredentialsByUserID(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 2 || credentials[0] != personal || credentials[1] != project {
+		t.Errorf("неожиданные аккаунты %#v", credentials)
+	}
+
+	err = project.UpdatePower(42, testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	personalFromDb, _ := GetCredentialByUserName(personal.UserName, database)
+	projectFromDb, _ := GetCredentialByUserName(project.UserName, database)
+	if personalFromDb.Power != 100 || projectFromDb.Power != 42 {
+		t.Error("сила должна меняться только у одного аккаунта")
+	}
+
+	err = ActivateCurator(1, testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chatIDs, err := GetAllActiveCurstorsChatID(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chatIDs) != 1 {
+		t.Errorf("куратор с двумя аккаунтами должен получать посты один раз: %v", chatIDs)
+	}
+
+	personal.Active = false
+	personal.Curates = true
+	_, err = personal.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsActiveCredential(1, database) || !IsActiveCurator(1, database) {
+		t.Error("пользователь с активным вторым аккаунтом остаётся активным")
+	}
+	primary, err := GetCredentialByUserID(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.UserName != project.UserName {
+		t.Error("основным должен быть активный аккаунт")
+	}
+}
+
+func TestGetAllCredentials(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Credential{UserID: 1, UserName: "chiliec", Active: true}.Save(testActor, database)
+	Credential{UserID: 2, UserName: "babin", Active: false}.Save(testActor, database)
+	credentials, err := GetAllCredentials(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 2 || credentials[1].UserName != "babin" {
+		t.Errorf("неожиданные аккаунты %#v", credentials)
+	}
+}
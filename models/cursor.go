@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Cursor хранит номер последнего обработанного блока
+type Cursor struct {
+	Name     string
+	BlockNum uint32
+}
+
+func (cursor Cursor) Save(db Executor) (bool, error) {
+	_, err := db.Exec("INSERT OR REPLACE INTO cursors(name, block_num, date) values(?, ?, ?)",
+		cursor.Name, cursor.BlockNum, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func GetCursor(name string, db Executor) (cursor Cursor, err error) {
+	row := db.QueryRow("SELECT name, block_num FROM cursors WHERE name = ?", name)
+	err = row.Scan(&cursor.Name, &cursor.BlockNum)
+	if err == sql.ErrNoRows {
+		return Cursor{Name: name}, nil
+	}
+	return cursor, err
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestGetCursor(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := GetCursor("events", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.BlockNum != 0 {
+		t.Error("новый курсор должен начинаться с нуля")
+	}
+	cursor.BlockNum = 42
+	_, err = cursor.Save(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursorFromDb, err := GetCursor("events", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != cursorFromDb {
+		t.Errorf("\n%#v\n%#v\nНе равны!", cursor, cursorFromDb)
+	}
+}
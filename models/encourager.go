@@ -1,17 +1,16 @@
 package models
 
 import (
-	"database/sql"
 	"time"
 )
 
-func GetLastRewardDate(db *sql.DB) (lastReportDate time.Time) {
+func GetLastRewardDate(db Executor) (lastReportDate time.Time) {
 	row := db.QueryRow("SELECT date FROM events WHERE type = 'REWARD' ORDER BY date DESC LIMIT 1")
 	row.Scan(&lastReportDate)
 	return lastReportDate
 }
 
-func NewRewardDistributed(db *sql.DB) (int64, error) {
+func NewRewardDistributed(db Executor) (int64, error) {
 	result, _ := db.Exec("INSERT INTO events (type) VALUES ('REWARD')")
 	return result.LastInsertId()
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// Executor — база *sql.DB или открытая на ней транзакция *sql.Tx.
+// Функции моделей принимают его, чтобы несколько изменений можно было записать одной транзакцией
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// Transaction выполняет do в транзакции: при ошибке изменения откатываются.
+// Если db — уже транзакция, do выполняется в ней, а фиксирует её тот, кто её открыл
+func Transaction(db Executor, do func(tx Executor) error) error {
+	database, ok := db.(*sql.DB)
+	if !ok {
+		return do(db)
+	}
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	err = do(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// Language — язык, на котором бот общается с пользователем
+type Language struct {
+	UserID int
+	Code   string
+	// Manual — язык выбран командой /language и не меняется вслед за настройками Telegram
+	Manual bool
+}
+
+func (language Language) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := languageValue(language.UserID, tx)
+		prepare, err := tx.Prepare("INSERT OR REPLACE INTO languages(" +
+			"user_id," +
+			"code," +
+			"manual) " +
+			"values(?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer prepare.Close()
+		_, err = prepare.Exec(language.UserID, language.Code, language.Manual)
+		if err != nil {
+			return err
+		}
+		after := languageValue(language.UserID, tx)
+		return RecordChange(actor, EntityLanguage, strconv.Itoa(language.UserID), before, after, tx)
+	})
+	return err == nil, err
+}
+
+// languageValue возвращает сохранённый язык для журнала изменений или nil, если его нет
+func languageValue(userID int, db Executor) interface{} {
+	row := db.QueryRow("SELECT user_id, code, manual FROM languages WHERE user_id = ?", userID)
+	var language Language
+	err := row.Scan(&language.UserID, &language.Code, &language.Manual)
+	if err != nil {
+		return nil
+	}
+	return language
+}
+
+// GetLanguageByUserID возвращает сохранённый язык пользователя или defaultCode, если его ещё нет
+func GetLanguageByUserID(userID int, defaultCode string, db Executor) (language Language, err error) {
+	row := db.QueryRow("SELECT user_id, code, manual FROM languages WHERE user_id = ?", userID)
+	err = row.Scan(&language.UserID, &language.Code, &language.Manual)
+	if err == sql.ErrNoRows {
+		return Language{UserID: userID, Code: defaultCode}, nil
+	}
+	return language, err
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestGetLanguageByUserID(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	language, err := GetLanguageByUserID(123, "ru", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if language != (Language{UserID: 123, Code: "ru"}) {
+		t.Fatal("Для нового пользователя ожидали язык по умолчанию")
+	}
+	language = Language{UserID: 123, Code: "en", Manual: true}
+	_, err = language.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	languageFromDatabase, err := GetLanguageByUserID(123, "ru", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if language != languageFromDatabase {
+		t.Fatal("Язык не совпадает")
+	}
+}
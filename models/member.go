@@ -0,0 +1,111 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Member — участник группы кураторов. Verified означает, что у него есть активный аккаунт
+// или он в списке исключений, Since — когда это последний раз менялось.
+// Attempts — сколько раз не получилось удалить его из группы, RetryAt — когда пробовать снова
+type Member struct {
+	UserID   int
+	Verified bool
+	Since    time.Time
+	Attempts int
+	RetryAt  time.Time
+}
+
+const memberColumns = "user_id, verified, since, attempts, retry_at"
+
+func scanMember(row scanner) (member Member, err error) {
+	err = row.Scan(&member.UserID, &member.Verified, &member.Since, &member.Attempts, &member.RetryAt)
+	return member, err
+}
+
+func (member Member) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := memberValue(member.UserID, tx)
+		_, err := tx.Exec("INSERT OR REPLACE INTO members("+memberColumns+") values(?, ?, ?, ?, ?)",
+			member.UserID, member.Verified, member.Since, member.Attempts, member.RetryAt)
+		if err != nil {
+			return err
+		}
+		after := memberValue(member.UserID, tx)
+		return RecordChange(actor, EntityMember, strconv.Itoa(member.UserID), before, after, tx)
+	})
+	return err == nil, err
+}
+
+// memberValue возвращает участника для журнала изменений или nil, если его нет
+func memberValue(userID int, db Executor) interface{} {
+	member, err := GetMemberByUserID(userID, db)
+	if err != nil {
+		return nil
+	}
+	return member
+}
+
+func GetMemberByUserID(userID int, db Executor) (member Member, err error) {
+	return scanMember(db.QueryRow("SELECT "+memberColumns+" FROM members WHERE user_id = ?", userID))
+}
+
+func GetAllMembers(db Executor) (members []Member, err error) {
+	rows, err := db.Query("SELECT " + memberColumns + " FROM members")
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return members, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func DeleteMember(userID int, actor Actor, db Executor) error {
+	return Transaction(db, func(tx Executor) error {
+		before := memberValue(userID, tx)
+		_, err := tx.Exec("DELETE FROM members WHERE user_id = ?", userID)
+		if err != nil {
+			return err
+		}
+		return RecordChange(actor, EntityMember, strconv.Itoa(userID), before, nil, tx)
+	})
+}
+
+// Removal — запись о том, что бот удалил участника из группы
+type Removal struct {
+	UserID int
+	Reason string
+	Date   time.Time
+}
+
+func (removal Removal) Save(db Executor) (bool, error) {
+	_, err := db.Exec("INSERT INTO removals(user_id, reason, date) values(?, ?, ?)",
+		removal.UserID, removal.Reason, removal.Date)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func GetLastRemovals(limit int, db Executor) (removals []Removal, err error) {
+	rows, err := db.Query("SELECT user_id, reason, date FROM removals ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return removals, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var removal Removal
+		err = rows.Scan(&removal.UserID, &removal.Reason, &removal.Date)
+		if err != nil {
+			return removals, err
+		}
+		removals = append(removals, removal)
+	}
+	return removals, nil
+}
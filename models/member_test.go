@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestMembers(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := Member{UserID: 1, Verified: false, Since: time.Now()}
+	_, err = member.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member.Verified = true
+	_, err = member.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := GetMemberByUserID(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Verified {
+		t.Error("участник должен быть подтверждён")
+	}
+	err = DeleteMember(1, testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := GetAllMembers(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 0 {
+		t.Errorf("ожидали пустой список участников, получили %d", len(members))
+	}
+}
+
+func TestRemovals(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reason := range []string{"первая", "вторая"} {
+		_, err = Removal{UserID: 1, Reason: reason, Date: time.Now()}.Save(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	removals, err := GetLastRemovals(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removals) != 1 || removals[0].Reason != "вторая" {
+		t.Errorf("ожидали последнее удаление, получили %v", removals)
+	}
+}
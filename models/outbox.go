@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxMessage — сообщение, которое ещё не удалось отправить в Telegram.
+// Config хранит tgbotapi.MessageConfig в JSON. Attempts — сколько раз Telegram его отклонил,
+// RetryAt — когда пробовать снова. Dead означает, что бот сдался, Error — последняя ошибка
+type OutboxMessage struct {
+	ID       int64
+	ChatID   int64
+	Config   string
+	Date     time.Time
+	Attempts int
+	RetryAt  time.Time
+	Dead     bool
+	Error    string
+}
+
+func (message OutboxMessage) Save(db Executor) (int64, error) {
+	result, err := db.Exec("INSERT INTO outbox(chat_id, config, date) values(?, ?, ?)",
+		message.ChatID, message.Config, message.Date)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func DeleteOutboxMessage(id int64, db Executor) error {
+	_, err := db.Exec("DELETE FROM outbox WHERE id = ?", id)
+	return err
+}
+
+// RetryOutboxMessage запоминает неудачную попытку отправки и время следующей
+func RetryOutboxMessage(id int64, attempts int, retryAt time.Time, lastError string, db Executor) error {
+	_, err := db.Exec("UPDATE outbox SET attempts = ?, retry_at = ?, error = ? WHERE id = ?",
+		attempts, retryAt, lastError, id)
+	return err
+}
+
+// BuryOutboxMessage оставляет сообщение, которое так и не удалось отправить, среди недоставленных:
+// из очереди оно уходит, но остаётся в таблице для разбора
+func BuryOutboxMessage(id int64, attempts int, lastError string, db Executor) error {
+	_, err := db.Exec("UPDATE outbox SET dead = 1, attempts = ?, error = ? WHERE id = ?", attempts, lastError, id)
+	return err
+}
+
+// GetOutboxMessages возвращает неотправленные сообщения в порядке постановки в очередь, кроме недоставленных
+func GetOutboxMessages(db Executor) (messages []OutboxMessage, err error) {
+	rows, err := db.Query("SELECT id, chat_id, config, date, attempts, retry_at, dead, error FROM outbox " +
+		"WHERE dead = 0 ORDER BY id")
+	if err != nil {
+		return messages, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var message OutboxMessage
+		err = rows.Scan(&message.ID, &message.ChatID, &message.Config, &message.Date,
+			&message.Attempts, &message.RetryAt, &message.Dead, &message.Error)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestGetOutboxMessages(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstID, err := OutboxMessage{ChatID: 1, Config: `{"text":"first"}`, Date: time.Now()}.Save(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OutboxMessage{ChatID: 2, Config: `{"text":"second"}`, Date: time.Now()}.Save(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DeleteOutboxMessage(firstID, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := GetOutboxMessages(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].ChatID != 2 || messages[0].Config != `{"text":"second"}` {
+		t.Errorf("неожиданная очередь %#v", messages)
+	}
+}
+
+func TestRetryAndBuryOutboxMessage(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := OutboxMessage{ChatID: 1, Config: `{"text":"first"}`, Date: time.Now()}.Save(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retryAt := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	err = RetryOutboxMessage(id, 2, retryAt, "Internal Server Error", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := GetOutboxMessages(database)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("в очереди %d сообщений вместо одного: %v", len(messages), err)
+	}
+	if messages[0].Attempts != 2 || !messages[0].RetryAt.Equal(retryAt) || messages[0].Error != "Internal Server Error" {
+		t.Errorf("попытки не сохранились: %#v", messages[0])
+	}
+	err = BuryOutboxMessage(id, 3, "Bad Request: message is too long", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err = GetOutboxMessages(database)
+	if err != nil || len(messages) != 0 {
+		t.Errorf("недоставленное сообщение не должно возвращаться в очередь: %#v, %v", messages, err)
+	}
+	var dead int
+	database.QueryRow("SELECT COUNT(*) FROM outbox WHERE dead = 1").Scan(&dead)
+	if dead != 1 {
+		t.Error("недоставленное сообщение должно остаться в таблице")
+	}
+}
@@ -1,48 +1,211 @@
 package models
 
-import "database/sql"
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// ReferralPending — приглашённый зарегистрировался, награда ещё не выплачена
+	ReferralPending = "pending"
+	ReferralPaid    = "paid"
+	// ReferralExpired — приглашённый не набрал постов до крайнего срока
+	ReferralExpired = "expired"
+	// ReferralReview — награда подозрительна и ждёт решения администратора
+	ReferralReview   = "review"
+	ReferralRejected = "rejected"
+)
 
 type Referral struct {
-	UserID    int
-	Referrer  string
-	UserName  string
-	Completed bool
-}
-
-func (referral Referral) Save(db *sql.DB) (bool, error) {
-	prepare, err := db.Prepare("INSERT INTO referrals(" +
-		"user_id," +
-		"referrer," +
-		"referral," +
-		"completed) " +
-		"values(?, ?, ?, ?)")
-	defer prepare.Close()
+	UserID       int
+	Referrer     string
+	UserName     string
+	Campaign     string
+	Completed    bool
+	Status       string
+	Deadline     time.Time
+	Attempts     int
+	ReferrerPaid bool
+	ReferralPaid bool
+	// Approved — администратор разрешил выплату несмотря на подозрения
+	Approved bool
+	PaidAt   time.Time
+}
+
+const referralColumns = "user_id, referrer, referral, campaign, completed, status, deadline, attempts, referrer_paid, referral_paid, approved, paid_at"
+
+func scanReferral(row scanner) (referral Referral, err error) {
+	err = row.Scan(&referral.UserID,
+		&referral.Referrer,
+		&referral.UserName,
+		&referral.Campaign,
+		&referral.Completed,
+		&referral.Status,
+		&referral.Deadline,
+		&referral.Attempts,
+		&referral.ReferrerPaid,
+		&referral.ReferralPaid,
+		&referral.Approved,
+		&referral.PaidAt)
+	return referral, err
+}
+
+func (referral Referral) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := referralValue(referral.UserID, tx)
+		prepare, err := tx.Prepare("INSERT OR REPLACE INTO referrals(" +
+			"user_id," +
+			"referrer," +
+			"referral," +
+			"campaign," +
+			"completed," +
+			"status," +
+			"deadline," +
+			"attempts," +
+			"referrer_paid," +
+			"referral_paid," +
+			"approved," +
+			"paid_at) " +
+			"values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer prepare.Close()
+		_, err = prepare.Exec(
+			referral.UserID,
+			referral.Referrer,
+			referral.UserName,
+			referral.Campaign,
+			referral.Completed,
+			referral.Status,
+			referral.Deadline,
+			referral.Attempts,
+			referral.ReferrerPaid,
+			referral.ReferralPaid,
+			referral.Approved,
+			referral.PaidAt)
+		if err != nil {
+			return err
+		}
+		after := referralValue(referral.UserID, tx)
+		return RecordChange(actor, EntityReferral, strconv.Itoa(referral.UserID), before, after, tx)
+	})
+	return err == nil, err
+}
+
+func (referral Referral) SetCompleted(actor Actor, db Executor) error {
+	return Transaction(db, func(tx Executor) error {
+		before := referralValue(referral.UserID, tx)
+		_, err := tx.Exec("UPDATE referrals SET completed = 1 WHERE user_id = ?", referral.UserID)
+		if err != nil || before == nil {
+			return err
+		}
+		after := referralValue(referral.UserID, tx)
+		return RecordChange(actor, EntityReferral, strconv.Itoa(referral.UserID), before, after, tx)
+	})
+}
+
+// referralValue возвращает реферала для журнала изменений или nil, если его нет
+func referralValue(userID int, db Executor) interface{} {
+	referral, err := GetReferralByUserID(userID, db)
+	if err != nil {
+		return nil
+	}
+	return referral
+}
+
+// IsExpired сообщает, что приглашённый не успел выполнить условия программы. Срок действует
+// только до первой выплаты: начатую или одобренную администратором выплату доводят до конца.
+// Нулевой срок означает, что его нет
+func (referral Referral) IsExpired(now time.Time) bool {
+	if referral.Approved || referral.ReferrerPaid || referral.ReferralPaid {
+		return false
+	}
+	return !referral.Deadline.IsZero() && now.After(referral.Deadline)
+}
+
+// IsPaid сообщает, что награду получили обе стороны
+func (referral Referral) IsPaid() bool {
+	return referral.ReferrerPaid && referral.ReferralPaid
+}
+
+func GetReferralByUserID(userID int, db Executor) (referral Referral, err error) {
+	row := db.QueryRow("SELECT "+referralColumns+" FROM referrals WHERE user_id = ?", userID)
+	return scanReferral(row)
+}
+
+func GetAllReferrals(db Executor) (referrals []Referral, err error) {
+	rows, err := db.Query("SELECT " + referralColumns + " FROM referrals ORDER BY id")
 	if err != nil {
-		return false, err
+		return referrals, err
 	}
-	_, err = prepare.Exec(
-		referral.UserID,
-		referral.Referrer,
-		referral.UserName,
-		referral.Completed)
+	defer rows.Close()
+	for rows.Next() {
+		referral, err := scanReferral(rows)
+		if err != nil {
+			return referrals, err
+		}
+		referrals = append(referrals, referral)
+	}
+	return referrals, nil
+}
+
+// GetPendingReferrals возвращает рефералов, ожидающих выплаты
+func GetPendingReferrals(db Executor) (referrals []Referral, err error) {
+	rows, err := db.Query("SELECT "+referralColumns+" FROM referrals WHERE status = ?", ReferralPending)
 	if err != nil {
-		return false, err
+		return referrals, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		referral, err := scanReferral(rows)
+		if err != nil {
+			return referrals, err
+		}
+		referrals = append(referrals, referral)
 	}
-	return true, nil
+	return referrals, nil
 }
 
-func (referral Referral) SetCompleted(db *sql.DB) error {
-	_, err := db.Exec("UPDATE referrals SET completed = 1 WHERE user_id = ?", referral.UserID)
-	return err
+// GetPaidReferralsCountSince считает выплаченные пригласившему награды
+func GetPaidReferralsCountSince(referrer string, date time.Time, db Executor) (count int) {
+	row := db.QueryRow("SELECT COUNT(*) FROM referrals WHERE referrer = ? AND status = ? AND paid_at > ?",
+		referrer, ReferralPaid, date)
+	row.Scan(&count)
+	return count
 }
 
-func GetReferralByUserID(userID int, db *sql.DB) (referral Referral, err error) {
-	row := db.QueryRow("SELECT user_id, referrer, referral, completed FROM referrals WHERE user_id = ?", userID)
-	err = row.Scan(&referral.UserID, &referral.Referrer, &referral.UserName, &referral.Completed)
-	return referral, err
+// ReferralStats — итоги приглашений одного пригласившего в одной кампании
+type ReferralStats struct {
+	Referrer   string
+	Campaign   string
+	Invited    int
+	Registered int
+	Paid       int
+}
+
+// GetReferralStats возвращает итоги по кампаниям пригласившего, а для пустого referrer — по всем
+func GetReferralStats(referrer string, db Executor) (stats []ReferralStats, err error) {
+	rows, err := db.Query("SELECT referrer, campaign, COUNT(*), SUM(completed), "+
+		"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) FROM referrals "+
+		"WHERE ? = '' OR referrer = ? GROUP BY referrer, campaign ORDER BY referrer, campaign",
+		ReferralPaid, referrer, referrer)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row ReferralStats
+		err = rows.Scan(&row.Referrer, &row.Campaign, &row.Invited, &row.Registered, &row.Paid)
+		if err != nil {
+			return stats, err
+		}
+		stats = append(stats, row)
+	}
+	return stats, nil
 }
 
-func IsReferralExists(referral string, db *sql.DB) bool {
+func IsReferralExists(referral string, db Executor) bool {
 	row := db.QueryRow("SELECT user_id FROM referrals "+
 		"WHERE referral = ?", referral)
 	var userID *int
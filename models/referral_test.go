@@ -2,7 +2,9 @@ package models
 
 import (
 	"github.com/GolosTools/golos-vote-bot/db"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestReferral_Save(t *testing.T) {
@@ -16,7 +18,7 @@ func TestReferral_Save(t *testing.T) {
 		UserName:  "chiliec",
 		Completed: false,
 	}
-	_, err = referral.Save(database)
+	_, err = referral.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -40,7 +42,7 @@ func TestReferral_SetCompleted(t *testing.T) {
 		UserName:  "chiliec",
 		Completed: false,
 	}
-	_, err = referral.Save(database)
+	_, err = referral.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -53,7 +55,7 @@ func TestReferral_SetCompleted(t *testing.T) {
 		t.Error("реферал не должен быть completed")
 	}
 
-	referral.SetCompleted(database)
+	referral.SetCompleted(testActor, database)
 	referralFromDb2, err := GetReferralByUserID(referral.UserID, database)
 	if err != nil {
 		t.Error(err)
@@ -78,7 +80,7 @@ func TestIsReferralExists(t *testing.T) {
 		UserName:  user,
 		Completed: false,
 	}
-	_, err = referral.Save(database)
+	_, err = referral.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -86,3 +88,133 @@ func TestIsReferralExists(t *testing.T) {
 		t.Error("реферал должен существовать")
 	}
 }
+
+func TestGetPendingReferrals(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	referral := Referral{
+		UserID:   1,
+		Referrer: "worthless",
+	}
+	_, err = referral.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = referral.SetCompleted(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	referral.UserName = "chiliec"
+	referral.Completed = true
+	referral.Status = ReferralPending
+	referral.Deadline = time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	referral.ReferrerPaid = true
+	// повторное сохранение обновляет реферала, а не падает на уникальном user_id
+	_, err = referral.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	referrals, err := GetPendingReferrals(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(referrals) != 1 || referrals[0] != referral {
+		t.Errorf("неожиданные рефералы %#v", referrals)
+	}
+	if referral.IsPaid() || referral.IsExpired(time.Now()) {
+		t.Error("реферал ещё ждёт выплаты")
+	}
+	if referral.IsExpired(time.Now().Add(48 * time.Hour)) {
+		t.Error("начатая выплата не должна истекать")
+	}
+	referral.ReferrerPaid = false
+	if !referral.IsExpired(time.Now().Add(48 * time.Hour)) {
+		t.Error("срок реферала должен истечь")
+	}
+	referral.Approved = true
+	if referral.IsExpired(time.Now().Add(48 * time.Hour)) {
+		t.Error("одобренная администратором выплата не должна истекать")
+	}
+	if (Referral{}).IsExpired(time.Now()) {
+		t.Error("реферал без срока не должен истекать")
+	}
+}
+
+func TestGetPaidReferralsCountSince(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	referrals := []Referral{
+		{UserID: 1, Referrer: "worthless", Status: ReferralPaid, PaidAt: now},
+		{UserID: 2, Referrer: "worthless", Status: ReferralPaid, PaidAt: now.AddDate(0, -2, 0)},
+		{UserID: 3, Referrer: "worthless", Status: ReferralPending},
+		{UserID: 4, Referrer: "chiliec", Status: ReferralPaid, PaidAt: now},
+	}
+	for _, referral := range referrals {
+		_, err = referral.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := GetPaidReferralsCountSince("worthless", now.AddDate(0, -1, 0), database); count != 1 {
+		t.Errorf("%d выплат вместо 1", count)
+	}
+}
+
+func TestGetReferralStats(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	referrals := []Referral{
+		{UserID: 1, Referrer: "worthless", Campaign: "summer", Completed: true, Status: ReferralPaid},
+		{UserID: 2, Referrer: "worthless", Campaign: "summer", Completed: true, Status: ReferralPending},
+		{UserID: 3, Referrer: "worthless", Campaign: "summer"},
+		{UserID: 4, Referrer: "worthless"},
+		{UserID: 5, Referrer: "chiliec", Campaign: "summer"},
+	}
+	for _, referral := range referrals {
+		_, err = referral.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := GetReferralStats("worthless", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ReferralStats{
+		{Referrer: "worthless", Campaign: "", Invited: 1},
+		{Referrer: "worthless", Campaign: "summer", Invited: 3, Registered: 2, Paid: 1},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("\n%#v\n%#v\nНе равны!", expected, stats)
+	}
+	all, err := GetReferralStats("", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("%d строк статистики вместо 3", len(all))
+	}
+}
+
+func TestGetAllReferrals(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Referral{UserID: 1, Referrer: "chiliec", UserName: "newbie", Status: ReferralPending}.Save(testActor, database)
+	Referral{UserID: 2, Referrer: "chiliec", UserName: "oldbie", Status: ReferralPaid}.Save(testActor, database)
+	referrals, err := GetAllReferrals(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(referrals) != 2 || referrals[1].UserName != "oldbie" {
+		t.Errorf("неожиданные рефералы %#v", referrals)
+	}
+}
@@ -1,7 +1,7 @@
 package models
 
 import (
-	"database/sql"
+	"strconv"
 	"time"
 )
 
@@ -12,21 +12,42 @@ type Response struct {
 	Date   time.Time
 }
 
-func (response Response) Save(db *sql.DB) (bool, error) {
-	prepare, err := db.Prepare("INSERT OR REPLACE INTO responses(" +
-		"user_id," +
-		"vote_id," +
-		"result," +
-		"date) " +
-		"values(?, ?, ?, ?)")
+func (response Response) Save(actor Actor, db Executor) (bool, error) {
+	err := Transaction(db, func(tx Executor) error {
+		before := response.value(tx)
+		prepare, err := tx.Prepare("INSERT OR REPLACE INTO responses(" +
+			"user_id," +
+			"vote_id," +
+			"result," +
+			"date) " +
+			"values(?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer prepare.Close()
+		_, err = prepare.Exec(response.UserID, response.VoteID, response.Result, response.Date)
+		if err != nil {
+			return err
+		}
+		entityID := strconv.Itoa(response.UserID) + ":" + strconv.FormatInt(response.VoteID, 10)
+		return RecordChange(actor, EntityResponse, entityID, before, response.value(tx), tx)
+	})
+	return err == nil, err
+}
+
+// value возвращает сохранённый ответ куратора для журнала изменений или nil, если его нет
+func (response Response) value(db Executor) interface{} {
+	row := db.QueryRow("SELECT user_id, vote_id, result, date FROM responses "+
+		"WHERE user_id = ? AND vote_id = ?", response.UserID, response.VoteID)
+	var stored Response
+	err := row.Scan(&stored.UserID, &stored.VoteID, &stored.Result, &stored.Date)
 	if err != nil {
-		return false, err
+		return nil
 	}
-	_, err = prepare.Exec(response.UserID, response.VoteID, response.Result, response.Date)
-	return err != nil, err
+	return stored
 }
 
-func (response Response) Exists(db *sql.DB) bool {
+func (response Response) Exists(db Executor) bool {
 	row := db.QueryRow("SELECT id FROM responses "+
 		"WHERE user_id = ? AND vote_id = ?", response.UserID, response.VoteID)
 	var id *int
@@ -34,7 +55,7 @@ func (response Response) Exists(db *sql.DB) bool {
 	return id != nil
 }
 
-func GetAllResponsesForVoteID(voteID int64, db *sql.DB) (responses []Response, err error) {
+func GetAllResponsesForVoteID(voteID int64, db Executor) (responses []Response, err error) {
 	rows, err := db.Query("SELECT user_id, vote_id, result, date FROM responses WHERE vote_id = ?", voteID)
 	if err != nil {
 		return responses, err
@@ -48,7 +69,26 @@ func GetAllResponsesForVoteID(voteID int64, db *sql.DB) (responses []Response, e
 	return responses, nil
 }
 
-func GetNumResponsesVoteID(voteID int64, db *sql.DB) (int, int) {
+// GetResponsesBetween возвращает оценки кураторов, данные начиная с since и до until
+func GetResponsesBetween(since time.Time, until time.Time, db Executor) (responses []Response, err error) {
+	rows, err := db.Query("SELECT user_id, vote_id, result, date FROM responses "+
+		"WHERE date >= ? AND date < ? ORDER BY id", since, until)
+	if err != nil {
+		return responses, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var response Response
+		err = rows.Scan(&response.UserID, &response.VoteID, &response.Result, &response.Date)
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func GetNumResponsesVoteID(voteID int64, db Executor) (int, int) {
 	var pos int
 	var neg int
 	row := db.QueryRow("SELECT COUNT(*) FROM responses WHERE vote_id = ? AND result = 1", voteID)
@@ -58,13 +98,13 @@ func GetNumResponsesVoteID(voteID int64, db *sql.DB) (int, int) {
 	return pos, neg
 }
 
-func GetNumResponsesForMotivation(date time.Time, db *sql.DB) (num int) {
+func GetNumResponsesForMotivation(date time.Time, db Executor) (num int) {
 	row := db.QueryRow("SELECT COUNT(*) FROM responses WHERE date > ?", date)
 	row.Scan(&num)
 	return num
 }
 
-func GetUserIDsForMotivation(date time.Time, db *sql.DB) (userIDs []int, err error) {
+func GetUserIDsForMotivation(date time.Time, db Executor) (userIDs []int, err error) {
 	rows, err := db.Query("SELECT distinct user_id FROM responses WHERE date > ?", date)
 	if err != nil {
 		return userIDs, err
@@ -78,7 +118,7 @@ func GetUserIDsForMotivation(date time.Time, db *sql.DB) (userIDs []int, err err
 	return userIDs, nil
 }
 
-func GetNumResponsesForMotivationForUserID(userID int, date time.Time, db *sql.DB) (num int) {
+func GetNumResponsesForMotivationForUserID(userID int, date time.Time, db Executor) (num int) {
 	row := db.QueryRow("SELECT COUNT(*) FROM responses WHERE date > ? AND user_id = ?", date, userID)
 	row.Scan(&num)
 	return num
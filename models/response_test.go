@@ -18,7 +18,7 @@ func TestDbResponses(t *testing.T) {
 		Result: true,
 		Date:   time.Now(),
 	}
-	response.Save(database)
+	response.Save(testActor, database)
 	responsesFromDB, err := GetAllResponsesForVoteID(response.VoteID, database)
 	if response.Date.Unix() != responsesFromDB[0].Date.Unix() {
 		t.Error("Даты не совпадают!")
@@ -43,14 +43,14 @@ func TestDbResponsesForMotivation(t *testing.T) {
 		Result: true,
 		Date:   time.Now(),
 	}
-	response.Save(database)
+	response.Save(testActor, database)
 	response = Response{
 		UserID: 1,
 		VoteID: 2,
 		Result: true,
 		Date:   time.Now(),
 	}
-	response.Save(database)
+	response.Save(testActor, database)
 	response = Response{
 		UserID: 2,
 		VoteID: 2,
@@ -66,3 +66,25 @@ func TestDbResponsesForMotivation(t *testing.T) {
 	}
 
 }
+
+func TestGetResponsesBetween(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for voteID, date := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now} {
+		response := Response{UserID: 1, VoteID: int64(voteID), Result: true, Date: date}
+		_, err = response.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	responses, err := GetResponsesBetween(now.Add(-24*time.Hour), now.Add(time.Minute), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || responses[0].VoteID != 1 {
+		t.Errorf("неожиданные ответы за период %#v", responses)
+	}
+}
@@ -1,27 +1,37 @@
 package models
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
+// State хранит диалог с пользователем: Action — состояние conversation.State,
+// Payload — его данные, Expires — когда бот перестанет ждать ответа
 type State struct {
-	UserID int
-	Action string
+	UserID  int
+	Action  string
+	Payload string
+	Expires time.Time
 }
 
-func (state State) Save(db *sql.DB) (bool, error) {
+func (state State) Save(db Executor) (bool, error) {
 	prepare, err := db.Prepare("INSERT OR REPLACE INTO states(" +
 		"user_id," +
-		"action) " +
-		"values(?, ?)")
+		"action," +
+		"payload," +
+		"expires) " +
+		"values(?, ?, ?, ?)")
 	if err != nil {
 		return false, err
 	}
-	_, err = prepare.Exec(state.UserID, state.Action)
-	return err != nil, err
+	defer prepare.Close()
+	_, err = prepare.Exec(state.UserID, state.Action, state.Payload, state.Expires)
+	return err == nil, err
 }
 
-func GetStateByUserID(userID int, db *sql.DB) (state State, err error) {
-	row := db.QueryRow("SELECT user_id, action FROM states WHERE user_id = ?", userID)
-	err = row.Scan(&state.UserID, &state.Action)
+func GetStateByUserID(userID int, db Executor) (state State, err error) {
+	row := db.QueryRow("SELECT user_id, action, payload, expires FROM states WHERE user_id = ?", userID)
+	err = row.Scan(&state.UserID, &state.Action, &state.Payload, &state.Expires)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return State{UserID: userID, Action: ""}, nil
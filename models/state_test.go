@@ -1,8 +1,10 @@
 package models
 
 import (
-	"github.com/GolosTools/golos-vote-bot/db"
 	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
 )
 
 func TestGetStateByUserID(t *testing.T) {
@@ -10,16 +12,20 @@ func TestGetStateByUserID(t *testing.T) {
 	if err != nil {
 		t.Failed()
 	}
-	state := State{UserID: 123, Action: "some_action"}
-	_, err = state.Save(database)
+	state := State{UserID: 123, Action: "some_action", Payload: "chiliec", Expires: time.Now().Add(time.Minute)}
+	saved, err := state.Save(database)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !saved {
+		t.Error("успешное сохранение должно возвращать true")
+	}
 	stateFromDatabase, err := GetStateByUserID(state.UserID, database)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if state != stateFromDatabase {
+	if state.UserID != stateFromDatabase.UserID || state.Action != stateFromDatabase.Action ||
+		state.Payload != stateFromDatabase.Payload || !state.Expires.Equal(stateFromDatabase.Expires) {
 		t.Fatal("Стейт не совпадает")
 	}
 }
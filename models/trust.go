@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Веса составляющих доверия
+const (
+	trustApprovalWeight   = 0.5
+	trustFreshnessWeight  = 0.2
+	trustAgeWeight        = 0.3
+	trustPlagiarismFine   = 0.25
+	trustMinimumScore     = 0.1
+	trustMatureAccountAge = 365 * 24 * time.Hour
+	// пока у пользователя мало закрытых голосований, доверие к нему не выше, чем к новичку
+	TrustMinimumClosedVotes = 5
+	trustNewcomerScore      = 0.5
+	// доверие показывается с двумя знаками, меньшее изменение не стоит записи в журнал
+	trustScorePrecision = 0.01
+)
+
+// Trust — доверие к предлагающему посты пользователю, от 0 до 1
+type Trust struct {
+	UserID          int
+	Score           float64
+	ApprovalRate    float64
+	AddledRate      float64
+	PlagiarismCount int
+	ClosedVotes     int
+	AccountCreated  time.Time
+	Date            time.Time
+}
+
+// ComputeTrust считает доверие по закрытым голосованиям пользователя и возрасту его аккаунта
+func ComputeTrust(userID int, votes []Vote, accountCreated time.Time, now time.Time) Trust {
+	trust := Trust{
+		UserID:         userID,
+		ApprovalRate:   1,
+		AccountCreated: accountCreated,
+		Date:           now,
+	}
+	var rejected, addled int
+	for _, vote := range votes {
+		if vote.Plagiarism {
+			trust.PlagiarismCount++
+		}
+		// закрытое администратором голосование ничего не говорит о посте
+		if !vote.Completed || vote.Closed {
+			continue
+		}
+		trust.ClosedVotes++
+		if vote.Rejected {
+			rejected++
+		}
+		if vote.Addled {
+			addled++
+		}
+	}
+	if trust.ClosedVotes > 0 {
+		trust.ApprovalRate = 1 - float64(rejected)/float64(trust.ClosedVotes)
+		trust.AddledRate = float64(addled) / float64(trust.ClosedVotes)
+	}
+	score := trustApprovalWeight*trust.ApprovalRate +
+		trustFreshnessWeight*(1-trust.AddledRate) +
+		trustAgeWeight*trust.AgeFactor(now) -
+		trustPlagiarismFine*float64(trust.PlagiarismCount)
+	if !trust.Established() {
+		score = math.Min(score, trustNewcomerScore)
+	}
+	trust.Score = math.Max(0, math.Min(1, score))
+	return trust
+}
+
+// AgeFactor — доля от возраста "зрелого" аккаунта, от 0 до 1
+func (trust Trust) AgeFactor(now time.Time) float64 {
+	if trust.AccountCreated.IsZero() {
+		return 0
+	}
+	age := now.Sub(trust.AccountCreated)
+	if age <= 0 {
+		return 0
+	}
+	return math.Min(1, float64(age)/float64(trustMatureAccountAge))
+}
+
+// AccountAgeDays возвращает возраст аккаунта в днях
+func (trust Trust) AccountAgeDays(now time.Time) int {
+	if trust.AccountCreated.IsZero() {
+		return 0
+	}
+	return int(now.Sub(trust.AccountCreated).Hours() / 24)
+}
+
+// Interval растягивает базовый интервал (в минутах) обратно пропорционально доверию
+func (trust Trust) Interval(baseInterval int) time.Duration {
+	score := math.Max(trust.Score, trustMinimumScore)
+	return time.Duration(float64(baseInterval)/score) * time.Minute
+}
+
+// MaximumOpenedVotes — сколько открытых голосований может одновременно держать пользователь
+func (trust Trust) MaximumOpenedVotes(maximum int) int {
+	allowed := int(math.Floor(trust.Score*float64(maximum) + 0.5))
+	if allowed < 1 {
+		return 1
+	}
+	return allowed
+}
+
+// Established сообщает, достаточно ли у пользователя закрытых голосований, чтобы доверять ему больше, чем новичку
+func (trust Trust) Established() bool {
+	return trust.ClosedVotes >= TrustMinimumClosedVotes
+}
+
+// SkipsUniqueness сообщает, можно ли не проверять посты пользователя на уникальность
+func (trust Trust) SkipsUniqueness(trustedScore float64) bool {
+	return trustedScore > 0 && trust.Established() && trust.Score >= trustedScore
+}
+
+// Differs сообщает, отличается ли доверие от сохранённого чем-то, кроме даты расчёта.
+// Возраст аккаунта сдвигает оценку каждую минуту, поэтому она сравнивается с точностью до сотой
+func (trust Trust) Differs(saved Trust) bool {
+	return math.Abs(trust.Score-saved.Score) >= trustScorePrecision ||
+		trust.ApprovalRate != saved.ApprovalRate ||
+		trust.AddledRate != saved.AddledRate ||
+		trust.PlagiarismCount != saved.PlagiarismCount ||
+		trust.ClosedVotes != saved.ClosedVotes ||
+		!trust.AccountCreated.Equal(saved.AccountCreated)
+}
+
+// Save сохраняет доверие и возвращает true, если оно отличалось от сохранённого.
+// Неизменившееся доверие не переписывается, чтобы не засорять журнал изменений
+func (trust Trust) Save(actor Actor, db Executor) (saved bool, err error) {
+	err = Transaction(db, func(tx Executor) error {
+		before := trustValue(trust.UserID, tx)
+		if stored, ok := before.(Trust); ok && !trust.Differs(stored) {
+			return nil
+		}
+		saved = true
+		prepare, err := tx.Prepare("INSERT OR REPLACE INTO trusts(" +
+			"user_id," +
+			"score," +
+			"approval_rate," +
+			"addled_rate," +
+			"plagiarism_count," +
+			"closed_votes," +
+			"account_created," +
+			"date) " +
+			"values(?, ?, ?, ?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer prepare.Close()
+		_, err = prepare.Exec(trust.UserID,
+			trust.Score,
+			trust.ApprovalRate,
+			trust.AddledRate,
+			trust.PlagiarismCount,
+			trust.ClosedVotes,
+			trust.AccountCreated,
+			trust.Date)
+		if err != nil {
+			return err
+		}
+		after := trustValue(trust.UserID, tx)
+		return RecordChange(actor, EntityTrust, strconv.Itoa(trust.UserID), before, after, tx)
+	})
+	return saved && err == nil, err
+}
+
+// trustValue возвращает сохранённое доверие для журнала изменений или nil, если его ещё не считали
+func trustValue(userID int, db Executor) interface{} {
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM trusts WHERE user_id = ?", userID).Scan(&count)
+	if count == 0 {
+		return nil
+	}
+	trust, err := GetTrustByUserID(userID, db)
+	if err != nil {
+		return nil
+	}
+	return trust
+}
+
+// GetTrustByUserID возвращает сохранённое доверие или начальное, если его ещё не считали
+func GetTrustByUserID(userID int, db Executor) (trust Trust, err error) {
+	row := db.QueryRow("SELECT user_id, score, approval_rate, addled_rate, plagiarism_count, closed_votes, account_created, date "+
+		"FROM trusts WHERE user_id = ?", userID)
+	err = row.Scan(&trust.UserID,
+		&trust.Score,
+		&trust.ApprovalRate,
+		&trust.AddledRate,
+		&trust.PlagiarismCount,
+		&trust.ClosedVotes,
+		&trust.AccountCreated,
+		&trust.Date)
+	if err == sql.ErrNoRows {
+		return ComputeTrust(userID, nil, time.Time{}, time.Now()), nil
+	}
+	return trust, err
+}
+
+// UpdateTrust пересчитывает доверие по последним n постам пользователя и сохраняет его
+func UpdateTrust(userID int, n int, accountCreated time.Time, actor Actor, db Executor) (Trust, error) {
+	votes, err := GetLastVotesForUserID(userID, n, db)
+	if err != nil {
+		return Trust{}, err
+	}
+	trust := ComputeTrust(userID, votes, accountCreated, time.Now())
+	_, err = trust.Save(actor, db)
+	return trust, err
+}
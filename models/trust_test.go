@@ -0,0 +1,155 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+func TestComputeTrust(t *testing.T) {
+	now := time.Now()
+	newbie := ComputeTrust(1, nil, time.Time{}, now)
+	if newbie.ApprovalRate != 1 || newbie.AddledRate != 0 {
+		t.Errorf("неожиданное начальное доверие %#v", newbie)
+	}
+	// старый аккаунт без закрытых голосований не должен сразу получать полное доверие
+	newcomer := ComputeTrust(1, nil, now.Add(-2*365*24*time.Hour), now)
+	if newcomer.Established() || newcomer.Score > trustNewcomerScore {
+		t.Errorf("неожиданное доверие к новичку %#v", newcomer)
+	}
+	if newcomer.SkipsUniqueness(0.5) || newcomer.MaximumOpenedVotes(4) != 2 || newcomer.Interval(60) != 2*time.Hour {
+		t.Error("новичок получил привилегии доверенного пользователя")
+	}
+
+	votes := []Vote{
+		{Completed: true},
+		{Completed: true, Rejected: true},
+		{Completed: true, Addled: true},
+		{Completed: true},
+		{Completed: true},
+		{Completed: false},
+	}
+	veteran := ComputeTrust(1, votes, now.Add(-2*365*24*time.Hour), now)
+	if veteran.ApprovalRate != 0.8 {
+		t.Errorf("доля одобренных %f вместо 0.8", veteran.ApprovalRate)
+	}
+	if veteran.AddledRate != 0.2 {
+		t.Errorf("доля протухших %f вместо 0.2", veteran.AddledRate)
+	}
+	if !veteran.Established() || veteran.Score <= trustNewcomerScore {
+		t.Errorf("доверие к опытному пользователю ограничено как к новичку: %#v", veteran)
+	}
+	if veteran.AgeFactor(now) != 1 {
+		t.Error("старый аккаунт должен получать полный вес возраста")
+	}
+
+	closed := ComputeTrust(1, append(votes, Vote{Completed: true, Closed: true}), now.Add(-2*365*24*time.Hour), now)
+	if closed.ClosedVotes != veteran.ClosedVotes || closed.Score != veteran.Score {
+		t.Error("закрытое администратором голосование не должно влиять на доверие")
+	}
+
+	votes = append(votes, Vote{Completed: true, Plagiarism: true})
+	plagiarist := ComputeTrust(1, votes, now.Add(-2*365*24*time.Hour), now)
+	if plagiarist.PlagiarismCount != 1 {
+		t.Errorf("плагиат не учтён: %d", plagiarist.PlagiarismCount)
+	}
+	if plagiarist.Score >= veteran.Score {
+		t.Error("плагиат должен снижать доверие")
+	}
+}
+
+func TestTrust_Interval(t *testing.T) {
+	full := Trust{Score: 1, ClosedVotes: TrustMinimumClosedVotes}
+	if full.Interval(60) != time.Hour {
+		t.Errorf("неожиданный интервал %s", full.Interval(60))
+	}
+	half := Trust{Score: 0.5}
+	if half.Interval(60) != 2*time.Hour {
+		t.Errorf("неожиданный интервал %s", half.Interval(60))
+	}
+	zero := Trust{Score: 0}
+	if zero.Interval(60) != 10*time.Hour {
+		t.Errorf("неожиданный интервал %s", zero.Interval(60))
+	}
+	if zero.MaximumOpenedVotes(3) != 1 || full.MaximumOpenedVotes(3) != 3 {
+		t.Error("неправильное количество открытых голосований")
+	}
+	if zero.SkipsUniqueness(0) || !full.SkipsUniqueness(0.9) || half.SkipsUniqueness(0.9) {
+		t.Error("неправильный пропуск проверки уникальности")
+	}
+}
+
+func TestUpdateTrust(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trust, err := GetTrustByUserID(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trust.Score == 0 {
+		t.Error("начальное доверие не должно быть нулевым")
+	}
+	vote := Vote{
+		UserID:    1,
+		Author:    "chiliec",
+		Permalink: "test",
+		Percent:   100,
+		Completed: true,
+		Rejected:  true,
+		Date:      time.Now(),
+	}
+	_, err = vote.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	updated, err := UpdateTrust(1, 10, created, testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ApprovalRate != 0 {
+		t.Errorf("доля одобренных %f вместо 0", updated.ApprovalRate)
+	}
+	trustFromDb, err := GetTrustByUserID(1, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trustFromDb.Score != updated.Score || !trustFromDb.AccountCreated.Equal(created) {
+		t.Errorf("\n%#v\n%#v\nНе равны!", updated, trustFromDb)
+	}
+	interval, err := ComputeIntervalForUser(1, 60, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != updated.Interval(60) {
+		t.Errorf("неожиданный интервал %s", interval)
+	}
+}
+
+func TestTrust_SaveOnlyChanges(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-24 * time.Hour)
+	trust := ComputeTrust(1, nil, created, time.Now())
+	if saved, err := trust.Save(testActor, database); err != nil || !saved {
+		t.Fatalf("первое доверие должно сохраниться: %v", err)
+	}
+	// минута спустя возраст аккаунта чуть сдвинул оценку, но по сути ничего не изменилось
+	later := ComputeTrust(1, nil, created, time.Now().Add(time.Minute))
+	if saved, err := later.Save(testActor, database); err != nil || saved {
+		t.Errorf("неизменившееся доверие не должно переписываться: %v", err)
+	}
+	changes, err := GetChanges(ChangeFilter{Entity: EntityTrust}, 10, database)
+	if err != nil || len(changes) != 1 {
+		t.Errorf("в журнале %d изменений доверия вместо одного: %v", len(changes), err)
+	}
+	rejected := ComputeTrust(1, []Vote{{Completed: true, Rejected: true}}, created, time.Now())
+	if saved, err := rejected.Save(testActor, database); err != nil || !saved {
+		t.Errorf("изменившееся доверие должно сохраниться: %v", err)
+	}
+}
@@ -2,25 +2,43 @@ package models
 
 import (
 	"database/sql"
+	"strconv"
 	"time"
 )
 
 type Vote struct {
-	VoteID    int64
-	UserID    int
-	Author    string
-	Permalink string
-	Percent   int
-	Completed bool
-	Rejected  bool
-	Addled    bool
-	Date      time.Time
-}
-
-func GetVote(db *sql.DB, voteID int64) (vote Vote) {
-	row := db.QueryRow("SELECT id, user_id, author, permalink, percent, completed, rejected, addled, date "+
-		"FROM votes WHERE id = ?", voteID)
-	row.Scan(&vote.VoteID,
+	VoteID     int64
+	UserID     int
+	Author     string
+	Permalink  string
+	Percent    int
+	Completed  bool
+	Rejected   bool
+	Addled     bool
+	Plagiarism bool
+	// Closed — голосование закрыл администратор, итога у него нет
+	Closed bool
+	Date   time.Time
+}
+
+// Итоги голосования, которые видит предложивший пост
+const (
+	VoteOpen       = "open"
+	VoteSupported  = "supported"
+	VoteRejected   = "rejected"
+	VoteAddled     = "addled"
+	VotePlagiarism = "plagiarism"
+	VoteClosed     = "closed"
+)
+
+const voteColumns = "id, user_id, author, permalink, percent, completed, rejected, addled, plagiarism, closed, date"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVote(row scanner) (vote Vote, err error) {
+	err = row.Scan(&vote.VoteID,
 		&vote.UserID,
 		&vote.Author,
 		&vote.Permalink,
@@ -28,39 +46,106 @@ func GetVote(db *sql.DB, voteID int64) (vote Vote) {
 		&vote.Completed,
 		&vote.Rejected,
 		&vote.Addled,
+		&vote.Plagiarism,
+		&vote.Closed,
 		&vote.Date)
+	return vote, err
+}
+
+func scanVotes(rows *sql.Rows) (votes []Vote) {
+	defer rows.Close()
+	for rows.Next() {
+		vote, _ := scanVote(rows)
+		votes = append(votes, vote)
+	}
+	return votes
+}
+
+func GetVote(db Executor, voteID int64) (vote Vote) {
+	row := db.QueryRow("SELECT "+voteColumns+" FROM votes WHERE id = ?", voteID)
+	vote, _ = scanVote(row)
 	return vote
 }
 
-func (vote Vote) Save(db *sql.DB) (int64, error) {
-	prepare, err := db.Prepare("INSERT OR REPLACE INTO votes(" +
-		"user_id," +
-		"author," +
-		"permalink," +
-		"percent," +
-		"completed," +
-		"rejected," +
-		"addled," +
-		"date) " +
-		"values(?, ?, ?, ?, ?, ?, ?, ?)")
+// Save сохраняет голосование. У уже сохранённого голосования id не меняется,
+// иначе REPLACE выдал бы ему новый и оторвал бы от него ответы кураторов
+func (vote Vote) Save(actor Actor, db Executor) (voteID int64, err error) {
+	err = Transaction(db, func(tx Executor) error {
+		var id, before interface{}
+		if vote.VoteID != 0 {
+			id = vote.VoteID
+			before = voteValue(vote.VoteID, tx)
+		}
+		prepare, err := tx.Prepare("INSERT OR REPLACE INTO votes(" +
+			"id," +
+			"user_id," +
+			"author," +
+			"permalink," +
+			"percent," +
+			"completed," +
+			"rejected," +
+			"addled," +
+			"plagiarism," +
+			"closed," +
+			"date) " +
+			"values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer prepare.Close()
+		result, err := prepare.Exec(id,
+			vote.UserID,
+			vote.Author,
+			vote.Permalink,
+			vote.Percent,
+			vote.Completed,
+			vote.Rejected,
+			vote.Addled,
+			vote.Plagiarism,
+			vote.Closed,
+			vote.Date)
+		if err != nil {
+			return err
+		}
+		voteID, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return RecordChange(actor, EntityVote, strconv.FormatInt(voteID, 10), before, voteValue(voteID, tx), tx)
+	})
 	if err != nil {
 		return 0, err
 	}
-	result, err := prepare.Exec(vote.UserID,
-		vote.Author,
-		vote.Permalink,
-		vote.Percent,
-		vote.Completed,
-		vote.Rejected,
-		vote.Addled,
-		vote.Date)
-	if err != nil {
-		return 0, err
+	return voteID, nil
+}
+
+// voteValue возвращает голосование для журнала изменений или nil, если его нет
+func voteValue(voteID int64, db Executor) interface{} {
+	vote := GetVote(db, voteID)
+	if vote.VoteID == 0 {
+		return nil
 	}
-	return result.LastInsertId()
+	return vote
 }
 
-func (vote Vote) Exists(db *sql.DB) bool {
+// Status возвращает итог голосования или VoteOpen, пока оно идёт
+func (vote Vote) Status() string {
+	switch {
+	case vote.Plagiarism:
+		return VotePlagiarism
+	case vote.Rejected:
+		return VoteRejected
+	case vote.Addled:
+		return VoteAddled
+	case vote.Closed:
+		return VoteClosed
+	case vote.Completed:
+		return VoteSupported
+	}
+	return VoteOpen
+}
+
+func (vote Vote) Exists(db Executor) bool {
 	row := db.QueryRow("SELECT user_id FROM votes WHERE author = ? AND permalink = ?", vote.Author, vote.Permalink)
 	var userID *int
 	row.Scan(&userID)
@@ -70,110 +155,120 @@ func (vote Vote) Exists(db *sql.DB) bool {
 	return false
 }
 
-func GetOpenedVotesCount(db *sql.DB) (count int) {
+func GetOpenedVotesCount(db Executor) (count int) {
 	row := db.QueryRow("SELECT COUNT(*) FROM votes WHERE completed = 0")
 	row.Scan(&count)
 	return count
 }
 
-func GetLastVotesForUserID(userID int, num int, db *sql.DB) (votes []Vote, err error) {
-	rows, err := db.Query("SELECT id, user_id, author, permalink, percent, completed, rejected, addled, date "+
+func GetOpenedVotesCountForUserID(userID int, db Executor) (count int) {
+	row := db.QueryRow("SELECT COUNT(*) FROM votes WHERE completed = 0 AND user_id = ?", userID)
+	row.Scan(&count)
+	return count
+}
+
+func GetLastVotesForUserID(userID int, num int, db Executor) (votes []Vote, err error) {
+	rows, err := db.Query("SELECT "+voteColumns+" "+
 		"FROM votes WHERE user_id = ? ORDER BY ID DESC LIMIT ?", userID, num)
 	if err != nil {
 		return votes, err
 	}
-	for rows.Next() {
-		var vote Vote
-		rows.Scan(&vote.VoteID,
-			&vote.UserID,
-			&vote.Author,
-			&vote.Permalink,
-			&vote.Percent,
-			&vote.Completed,
-			&vote.Rejected,
-			&vote.Addled,
-			&vote.Date)
-		votes = append(votes, vote)
+	return scanVotes(rows), nil
+}
+
+// GetVotesPageForUserID возвращает посты пользователя от новых к старым, пропуская первые offset
+func GetVotesPageForUserID(userID int, limit int, offset int, db Executor) (votes []Vote, err error) {
+	rows, err := db.Query("SELECT "+voteColumns+" "+
+		"FROM votes WHERE user_id = ? ORDER BY id DESC LIMIT ? OFFSET ?", userID, limit, offset)
+	if err != nil {
+		return votes, err
 	}
-	return votes, err
+	return scanVotes(rows), nil
 }
 
-func GetLastVoteForUserID(userID int, db *sql.DB) (vote Vote) {
-	row := db.QueryRow("SELECT id, user_id, author, permalink, percent, completed, rejected, addled, date FROM votes "+
+func GetVotesCountForUserID(userID int, db Executor) (count int) {
+	row := db.QueryRow("SELECT COUNT(*) FROM votes WHERE user_id = ?", userID)
+	row.Scan(&count)
+	return count
+}
+
+func GetLastVoteForUserID(userID int, db Executor) (vote Vote) {
+	row := db.QueryRow("SELECT "+voteColumns+" FROM votes "+
 		"WHERE user_id = ? ORDER BY ID DESC LIMIT 1", userID)
-	row.Scan(&vote.VoteID,
-		&vote.UserID,
-		&vote.Author,
-		&vote.Permalink,
-		&vote.Percent,
-		&vote.Completed,
-		&vote.Rejected,
-		&vote.Addled,
-		&vote.Date)
+	vote, _ = scanVote(row)
 	return vote
 }
 
-func GetAllOpenedVotes(db *sql.DB) (votes []Vote, err error) {
-	rows, err := db.Query("SELECT id, user_id, author, permalink, percent, completed, rejected, addled, date " +
+func GetAllOpenedVotes(db Executor) (votes []Vote, err error) {
+	rows, err := db.Query("SELECT " + voteColumns + " " +
 		"FROM votes WHERE completed = 0")
 	if err != nil {
 		return votes, err
 	}
-	for rows.Next() {
-		var vote Vote
-		rows.Scan(&vote.VoteID,
-			&vote.UserID,
-			&vote.Author,
-			&vote.Permalink,
-			&vote.Percent,
-			&vote.Completed,
-			&vote.Rejected,
-			&vote.Addled,
-			&vote.Date)
-		votes = append(votes, vote)
+	return scanVotes(rows), nil
+}
+
+// ComputeIntervalForUser вычисляет интервал между постами пользователя по его доверию
+func ComputeIntervalForUser(userID int, baseInterval int, db Executor) (time.Duration, error) {
+	trust, err := GetTrustByUserID(userID, db)
+	if err != nil {
+		return 0, err
 	}
+	return trust.Interval(baseInterval), nil
+}
 
-	return votes, err
+func GetTrulyCompletedVotesSince(date time.Time, db Executor) (votes []Vote, err error) {
+	rows, err := db.Query("SELECT "+voteColumns+" "+
+		"FROM votes WHERE date > ? AND completed = 1 AND rejected = 0 AND addled = 0 AND plagiarism = 0 AND closed = 0", date)
+	if err != nil {
+		return votes, err
+	}
+	return scanVotes(rows), nil
 }
 
-//computes interval based on last n posts
-func ComputeIntervalForUser(userID int, n int, baseInterval int, db *sql.DB) (time.Duration, error) {
-	var good, all int
-	userVotes, err := GetLastVotesForUserID(userID, n, db)
+// GetVotesBetween возвращает голосования, предложенные начиная с since и до until
+func GetVotesBetween(since time.Time, until time.Time, db Executor) (votes []Vote, err error) {
+	rows, err := db.Query("SELECT "+voteColumns+" "+
+		"FROM votes WHERE date >= ? AND date < ? ORDER BY id", since, until)
 	if err != nil {
-		return 0, err
+		return votes, err
 	}
-	for _, vote := range userVotes {
-		if !vote.Rejected {
-			good = good + 1
-		}
-		all = all + 1
+	return scanVotes(rows), nil
+}
+
+func SaveVoteTags(voteID int64, tags []string, db Executor) error {
+	prepare, err := db.Prepare("INSERT OR IGNORE INTO vote_tags(vote_id, tag) values(?, ?)")
+	if err != nil {
+		return err
 	}
-	if good == 0 {
-		good = 1
+	defer prepare.Close()
+	for _, tag := range tags {
+		_, err = prepare.Exec(voteID, tag)
+		if err != nil {
+			return err
+		}
 	}
-	computedInterval := time.Duration(baseInterval*all/good) * time.Minute
-	return computedInterval, err
+	return nil
 }
 
-func GetTrulyCompletedVotesSince(date time.Time, db *sql.DB) (votes []Vote, err error) {
-	rows, err := db.Query("SELECT id, user_id, author, permalink, percent, completed, rejected, addled, date "+
-		"FROM votes WHERE date > ? AND completed = 1 AND rejected = 0 AND addled = 0", date)
+func GetVoteTags(voteID int64, db Executor) (tags []string, err error) {
+	rows, err := db.Query("SELECT tag FROM vote_tags WHERE vote_id = ?", voteID)
 	if err != nil {
-		return votes, err
+		return tags, err
 	}
+	defer rows.Close()
 	for rows.Next() {
-		var vote Vote
-		rows.Scan(&vote.VoteID,
-			&vote.UserID,
-			&vote.Author,
-			&vote.Permalink,
-			&vote.Percent,
-			&vote.Completed,
-			&vote.Rejected,
-			&vote.Addled,
-			&vote.Date)
-		votes = append(votes, vote)
+		var tag string
+		rows.Scan(&tag)
+		tags = append(tags, tag)
 	}
-	return votes, err
+	return tags, nil
+}
+
+func GetOpenedVotesCountForTag(tag string, db Executor) (count int) {
+	row := db.QueryRow("SELECT COUNT(DISTINCT votes.id) FROM votes "+
+		"JOIN vote_tags ON vote_tags.vote_id = votes.id "+
+		"WHERE votes.completed = 0 AND vote_tags.tag = ?", tag)
+	row.Scan(&count)
+	return count
 }
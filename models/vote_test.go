@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -22,7 +23,7 @@ func TestDbVotes(t *testing.T) {
 		Rejected:  false,
 		Date:      time.Now(),
 	}
-	_, err = vote.Save(database)
+	_, err = vote.Save(testActor, database)
 	if err != nil {
 		t.Error(err)
 	}
@@ -52,7 +53,7 @@ func TestGetLastVotes(t *testing.T) {
 		Rejected:  false,
 		Date:      time.Now(),
 	}
-	firstVote.Save(database)
+	firstVote.Save(testActor, database)
 	secondVote := Vote{
 		VoteID:    2,
 		UserID:    1,
@@ -63,7 +64,7 @@ func TestGetLastVotes(t *testing.T) {
 		Rejected:  false,
 		Date:      time.Now(),
 	}
-	secondVote.Save(database)
+	secondVote.Save(testActor, database)
 
 	lastVote := GetLastVoteForUserID(1, database)
 
@@ -82,3 +83,132 @@ func TestGetLastVotes(t *testing.T) {
 		t.Errorf("\n%#v\n%#v\nНе равны!", secondVote, lastVote)
 	}
 }
+
+func TestGetOpenedVotesCountForTag(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote := Vote{
+		UserID:    1,
+		Author:    "ExampleAuthor",
+		Permalink: "/example/permalink",
+		Percent:   100,
+		Date:      time.Now(),
+	}
+	voteID, err := vote.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SaveVoteTags(voteID, []string{"golos", "ru--foto", "golos"}, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := GetVoteTags(voteID, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 {
+		t.Errorf("неожиданные теги %#v", tags)
+	}
+	if count := GetOpenedVotesCountForTag("ru--foto", database); count != 1 {
+		t.Errorf("открытых голосований с тегом %d вместо 1", count)
+	}
+	if count := GetOpenedVotesCountForTag("art", database); count != 0 {
+		t.Errorf("открытых голосований с тегом %d вместо 0", count)
+	}
+}
+
+func TestVoteSaveKeepsID(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote := Vote{UserID: 1, Author: "first", Permalink: "post", Date: time.Now()}
+	firstID, err := vote.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Vote{UserID: 1, Author: "second", Permalink: "post", Date: time.Now()}.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote.VoteID = firstID
+	vote.Completed = true
+	secondID, err := vote.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secondID != firstID {
+		t.Errorf("id голосования изменился с %d на %d", firstID, secondID)
+	}
+	if !GetVote(database, firstID).Completed {
+		t.Error("голосование не обновилось")
+	}
+}
+
+func TestVoteStatus(t *testing.T) {
+	cases := map[string]Vote{
+		VoteOpen:       {},
+		VoteSupported:  {Completed: true},
+		VoteRejected:   {Completed: true, Rejected: true},
+		VoteAddled:     {Completed: true, Addled: true},
+		VotePlagiarism: {Completed: true, Plagiarism: true},
+	}
+	for status, vote := range cases {
+		if vote.Status() != status {
+			t.Errorf("статус %s вместо %s", vote.Status(), status)
+		}
+	}
+}
+
+func TestGetVotesPageForUserID(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 7; i++ {
+		vote := Vote{UserID: 1, Author: "chiliec", Permalink: strconv.Itoa(i), Date: time.Now()}
+		_, err = vote.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := Vote{UserID: 2, Author: "babin", Permalink: "other", Date: time.Now()}
+	_, err = other.Save(testActor, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := GetVotesCountForUserID(1, database); count != 7 {
+		t.Errorf("%d постов вместо 7", count)
+	}
+	votes, err := GetVotesPageForUserID(1, 5, 5, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 2 || votes[0].Permalink != "2" || votes[1].Permalink != "1" {
+		t.Errorf("неожиданная вторая страница %#v", votes)
+	}
+}
+
+func TestGetVotesBetween(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, date := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now} {
+		vote := Vote{UserID: 1, Author: "chiliec", Permalink: strconv.Itoa(i), Date: date}
+		_, err = vote.Save(testActor, database)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	votes, err := GetVotesBetween(now.Add(-24*time.Hour), now, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || votes[0].Permalink != "1" {
+		t.Errorf("неожиданные голосования за период %#v", votes)
+	}
+}
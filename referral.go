@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	golosClient "github.com/asuleymanov/golos-go/client"
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/helpers"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+	"github.com/GolosTools/golos-vote-bot/router"
+)
+
+const referralCheckInterval = time.Hour
+
+// referralWakeup будит referralProcessor раньше срока, например после одобрения выплаты.
+// Выплаты делает только он, поэтому одна награда не уходит дважды
+var referralWakeup = make(chan struct{}, 1)
+
+func handleInvite(ctx *router.Context) error {
+	msg := tgbotapi.NewMessage(ctx.ChatID, "")
+	credential, err := store.GetCredentialByUserID(ctx.UserID)
+	if err != nil || !credential.Active {
+		msg.Text = i18n.Markdown(ctx.Lang, "delegate_first", i18n.T(ctx.Lang, buttonAddKey))
+		return reply(ctx, msg)
+	}
+	link, err := referralLink(credential.UserName, ctx.Message().CommandArguments())
+	if err == helpers.ErrNoReferralSecret {
+		msg.Text = i18n.T(ctx.Lang, "invite.disabled")
+		return reply(ctx, msg)
+	} else if err != nil {
+		msg.Text = i18n.Markdown(ctx.Lang, "invite.error", err.Error())
+		return reply(ctx, msg)
+	}
+	stats, err := store.GetReferralStats(credential.UserName)
+	if err != nil {
+		return err
+	}
+	msg.Text = i18n.Markdown(ctx.Lang, "invite", link, link, i18n.Raw(referralStatsText(stats, ctx.Lang)))
+	return reply(ctx, msg)
+}
+
+func handleReferrals(ctx *router.Context) error {
+	stats, err := store.GetReferralStats("")
+	if err != nil {
+		return err
+	}
+	return reply(ctx, tgbotapi.NewMessage(ctx.ChatID, referralStatsText(stats, ctx.Lang)))
+}
+
+func handleReferralDecision(ctx *router.Context) error {
+	userID, lang := ctx.UserID, ctx.Lang
+	action := ctx.Arg(0)
+	referralUserID, err := strconv.Atoi(ctx.Arg(1))
+	if err != nil {
+		return err
+	}
+	referral, err := store.GetReferralByUserID(referralUserID)
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewEditMessageText(ctx.ChatID, ctx.Callback().Message.MessageID, "")
+	if referral.Status != models.ReferralReview {
+		msg.Text = i18n.T(lang, "referral.already_reviewed", referral.UserName)
+		bot.Send(msg)
+		return nil
+	}
+	switch action {
+	case "approve":
+		referral.Approved = true
+		referral.Status = models.ReferralPending
+		msg.Text = i18n.T(lang, "referral.approved", referral.UserName)
+	case "reject":
+		referral.Status = models.ReferralRejected
+		msg.Text = i18n.T(lang, "referral.rejected", referral.UserName)
+	default:
+		return errors.New("неподдерживаемое действие: " + action)
+	}
+	err = store.SaveReferral(referral, models.AdminActor(userID))
+	if err != nil {
+		return err
+	}
+	log.Printf("Администратор %d: %s", userID, msg.Text)
+	saveAdminAction(userID, "referral", action+" "+ctx.Arg(1), msg.Text)
+	if referral.Approved {
+		select {
+		case referralWakeup <- struct{}{}:
+		default:
+		}
+	}
+	bot.Send(msg)
+	return nil
+}
+
+// referralProcessor периодически пытается выплатить отложенные реферальные награды
+func referralProcessor() {
+	for {
+		referrals, err := store.GetPendingReferrals()
+		if err != nil {
+			log.Println("не получили рефералов: " + err.Error())
+		}
+		for _, referral := range referrals {
+			// пока шли выплаты предыдущим, реферала могли отклонить или обновить
+			referral, err = store.GetReferralByUserID(referral.UserID)
+			if err != nil || referral.Status != models.ReferralPending {
+				continue
+			}
+			payReferral(referral)
+		}
+		select {
+		case <-referralWakeup:
+		case <-time.After(referralCheckInterval):
+		}
+	}
+}
+
+// referralSuspicions ищет признаки того, что приглашённый — виртуал пригласившего
+func referralSuspicions(golos *golosClient.Client, referral models.Referral) ([]helpers.Suspicion, error) {
+	accounts, err := golos.Rpc.Database.GetAccounts([]string{referral.Referrer, referral.UserName})
+	if err != nil {
+		return nil, err
+	}
+	check := helpers.ReferralCheck{
+		Referrer:      referral.Referrer,
+		Invitee:       referral.UserName,
+		PaidThisMonth: store.GetPaidReferralsCountSince(referral.Referrer, time.Now().AddDate(0, -1, 0)),
+		MonthlyCap:    config.ReferralMonthlyCap,
+		MinimumAge:    config.ReferralMinimumAgeDays,
+		Now:           time.Now(),
+	}
+	for _, account := range accounts {
+		switch account.Name {
+		case referral.Referrer:
+			check.ReferrerRecovery = account.RecoveryAccount
+		case referral.UserName:
+			check.InviteeRecovery = account.RecoveryAccount
+			if account.Created != nil && account.Created.Time != nil {
+				check.Created = *account.Created.Time
+			}
+		}
+	}
+	// первые операции аккаунта содержат его создание
+	first, err := golos.Rpc.Database.GetAccountHistory(referral.UserName, 10, 10)
+	if err != nil {
+		return nil, err
+	}
+	check.Creator = helpers.AccountCreator(first)
+	check.History, err = golos.Rpc.Database.GetAccountHistory(referral.UserName, math.MaxUint64, 1000)
+	if err != nil {
+		return nil, err
+	}
+	return check.Suspicions(), nil
+}
+
+// requestReferralReview отправляет подозрительную выплату администраторам на проверку
+func requestReferralReview(referral models.Referral, reasons []helpers.Suspicion) {
+	text := func(lang string) string {
+		var lines []string
+		for _, reason := range reasons {
+			lines = append(lines, reason.Text(lang))
+		}
+		return i18n.T(lang, "admin.referral_review", referral.UserName, referral.Referrer, strings.Join(lines, "\n- "))
+	}
+	log.Println(text(i18n.Default))
+	userID := strconv.Itoa(referral.UserID)
+	for _, admin := range config.Admins {
+		lang := userLanguage(admin, nil)
+		msg := tgbotapi.NewMessage(int64(admin), text(lang))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.referral_approve"), "referral_approve_"+userID),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.referral_reject"), "referral_reject_"+userID)))
+		err := outbox.Send(msg)
+		if err != nil {
+			log.Println("не уведомили администратора: " + err.Error())
+		}
+	}
+}
+
+// referralLink создаёт подписанную реферальную ссылку с ограниченным сроком действия
+func referralLink(referrer, campaign string) (string, error) {
+	token := helpers.ReferralToken{Referrer: referrer, Campaign: campaign}
+	if config.ReferralLinkDays > 0 {
+		token.Expires = time.Now().AddDate(0, 0, config.ReferralLinkDays)
+	}
+	encoded, err := helpers.SignReferralToken(token, config.ReferralSecret)
+	if err != nil {
+		return "", err
+	}
+	return "https://t.me/" + config.TelegramBotName + "?start=" + encoded, nil
+}
+
+func referralStatsText(stats []models.ReferralStats, lang string) string {
+	if len(stats) == 0 {
+		return i18n.T(lang, "referral.stats_empty")
+	}
+	text := i18n.T(lang, "referral.stats_title")
+	for _, row := range stats {
+		campaign := row.Campaign
+		if len(campaign) == 0 {
+			campaign = i18n.T(lang, "referral.no_campaign")
+		}
+		text += i18n.Markdown(lang, "referral.stats_line", row.Referrer, campaign, row.Invited, row.Registered, row.Paid)
+	}
+	return text
+}
+
+// payReferral выплачивает награду каждой из сторон не больше одного раза
+func payReferral(referral models.Referral) {
+	save := func() {
+		err := store.SaveReferral(referral, models.SystemActor("referrals"))
+		if err != nil {
+			log.Println("не сохранили реферала: " + err.Error())
+		}
+	}
+	if referral.Referrer == referral.UserName {
+		log.Printf("Пригласивший и приглашенный %s совпадают", referral.UserName)
+		referral.Status = models.ReferralExpired
+		save()
+		return
+	}
+	if referral.IsExpired(time.Now()) {
+		log.Printf("За новичка %s награды не будет, истёк срок", referral.UserName)
+		referral.Status = models.ReferralExpired
+		save()
+		return
+	}
+	golos := golosClient.NewApi(config.Rpc, config.Chain)
+	defer golos.Rpc.Close()
+	accounts, err := golos.Rpc.Database.GetAccounts([]string{referral.UserName})
+	if err != nil || len(accounts) != 1 {
+		log.Println("Не получили аккаунт " + referral.UserName)
+		return
+	}
+	if accounts[0].PostCount.Int64() < int64(config.ReferralMinimumPostCount) {
+		return
+	}
+	if !referral.Approved && !referral.ReferrerPaid && !referral.ReferralPaid {
+		reasons, err := referralSuspicions(golos, referral)
+		if err != nil {
+			log.Println("не проверили реферала " + referral.UserName + ": " + err.Error())
+			return
+		}
+		if len(reasons) > 0 {
+			referral.Status = models.ReferralReview
+			save()
+			requestReferralReview(referral, reasons)
+			return
+		}
+	}
+	amount := fmt.Sprintf("%.3f GOLOS", config.ReferralFee)
+	if !referral.ReferrerPaid {
+		err = golos.TransferToVesting(config.Account, referral.Referrer, amount)
+		if err != nil {
+			log.Println(fmt.Sprintf("Не отправили силу голоса %s \nаккаунту %s", err.Error(), referral.Referrer))
+		} else {
+			referral.ReferrerPaid = true
+		}
+	}
+	if !referral.ReferralPaid {
+		err = golos.TransferToVesting(config.Account, referral.UserName, amount)
+		if err != nil {
+			log.Println(fmt.Sprintf("Не отправили силу голоса %s \nаккаунту %s", err.Error(), referral.UserName))
+		} else {
+			referral.ReferralPaid = true
+		}
+	}
+	referral.Attempts++
+	if !referral.IsPaid() {
+		save()
+		return
+	}
+	referral.Status = models.ReferralPaid
+	referral.PaidAt = time.Now()
+	save()
+	text := i18n.Markdown(i18n.Default, "referral.paid", referral.Referrer, referral.UserName,
+		fmt.Sprintf("%.3f", config.ReferralFee))
+	msg := tgbotapi.NewMessage(config.GroupID, text)
+	msg.ParseMode = "Markdown"
+	err = outbox.Send(msg)
+	if err != nil {
+		log.Println("Не отправили сообщение: " + err.Error())
+	}
+}
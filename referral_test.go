@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GolosTools/golos-vote-bot/i18n"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestReferralStatsText(t *testing.T) {
+	if text := referralStatsText(nil, "ru"); text != i18n.T("ru", "referral.stats_empty") {
+		t.Errorf("неожиданный текст %q", text)
+	}
+	text := referralStatsText([]models.ReferralStats{{Referrer: "some_user", Invited: 3, Registered: 2, Paid: 1}}, "ru")
+	if !strings.Contains(text, "some\\_user") || !strings.Contains(text, i18n.T("ru", "referral.no_campaign")) {
+		t.Errorf("неожиданный текст %q", text)
+	}
+}
+
+func TestReferralLinkWithoutSecret(t *testing.T) {
+	setUp(t)
+	if _, err := referralLink("chiliec", ""); err == nil {
+		t.Error("без секрета ссылка не создаётся")
+	}
+}
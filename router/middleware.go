@@ -0,0 +1,122 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Recover превращает панику обработчика в ошибку, чтобы одно обновление не роняло бота
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) (err error) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					log.Printf("Паника при обработке обновления %d: %v\n%s", ctx.Update.UpdateID, recovered, debug.Stack())
+					err = fmt.Errorf("паника: %v", recovered)
+				}
+			}()
+			return next(ctx)
+		}
+	}
+}
+
+// Logging пишет в лог ошибки обработчиков вместе с пользователем и временем обработки
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			started := time.Now()
+			err := next(ctx)
+			if err != nil {
+				log.Printf("Пользователь %d, чат %d: %s (%s)", ctx.UserID, ctx.ChatID, err.Error(), time.Since(started))
+			}
+			return err
+		}
+	}
+}
+
+// Allow пропускает к обработчику только тех, для кого allowed возвращает true,
+// остальным отвечает deny
+func Allow(allowed func(ctx *Context) bool, deny Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if !allowed(ctx) {
+				return deny(ctx)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// When применяет middleware только к обновлениям, для которых match возвращает true
+func When(match Matcher, middleware Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := middleware(next)
+		return func(ctx *Context) error {
+			if match(ctx) {
+				return wrapped(ctx)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// RateLimit пропускает от одного пользователя не больше burst обновлений за interval,
+// лишние отдаёт deny
+func RateLimit(burst int, interval time.Duration, deny Handler) Middleware {
+	limiter := newRateLimiter(burst, interval)
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			if !limiter.allow(ctx.UserID, ctx.Now) {
+				return deny(ctx)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// rateLimiter помнит время недавних обновлений каждого пользователя
+type rateLimiter struct {
+	burst    int
+	interval time.Duration
+	mutex    sync.Mutex
+	recent   map[int][]time.Time
+	swept    time.Time
+}
+
+func newRateLimiter(burst int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{burst: burst, interval: interval, recent: make(map[int][]time.Time)}
+}
+
+func (limiter *rateLimiter) allow(userID int, now time.Time) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	since := now.Add(-limiter.interval)
+	times := limiter.recent[userID][:0]
+	for _, moment := range limiter.recent[userID] {
+		if moment.After(since) {
+			times = append(times, moment)
+		}
+	}
+	limited := len(times) >= limiter.burst
+	if !limited {
+		times = append(times, now)
+	}
+	if len(times) == 0 {
+		delete(limiter.recent, userID)
+	} else {
+		limiter.recent[userID] = times
+	}
+	// раз в interval забываем тех, кто давно ничего не присылал, иначе карта растёт вечно
+	if now.Sub(limiter.swept) >= limiter.interval {
+		for user, moments := range limiter.recent {
+			if !moments[len(moments)-1].After(since) {
+				delete(limiter.recent, user)
+			}
+		}
+		limiter.swept = now
+	}
+	return !limited
+}
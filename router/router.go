@@ -0,0 +1,155 @@
+// Package router раскладывает обновления Telegram по обработчикам: командам, кнопкам
+// обычной и встроенной клавиатуры и произвольным условиям на текст сообщения
+package router
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/GolosTools/golos-vote-bot/conversation"
+	"github.com/GolosTools/golos-vote-bot/i18n"
+)
+
+// Context — обновление вместе с тем, что бот знает об отправителе
+type Context struct {
+	Update tgbotapi.Update
+	UserID int
+	ChatID int64
+	Lang   string
+	Now    time.Time
+	// Dialog — диалог с пользователем, обработчики меняют его через Fire
+	Dialog conversation.Conversation
+	// Args — части данных встроенной кнопки после префикса
+	Args []string
+}
+
+// Message возвращает сообщение из обновления или nil, если это нажатие кнопки
+func (ctx *Context) Message() *tgbotapi.Message {
+	return ctx.Update.Message
+}
+
+// Callback возвращает нажатие встроенной кнопки или nil
+func (ctx *Context) Callback() *tgbotapi.CallbackQuery {
+	return ctx.Update.CallbackQuery
+}
+
+// From возвращает отправителя обновления
+func (ctx *Context) From() *tgbotapi.User {
+	if ctx.Update.Message != nil {
+		return ctx.Update.Message.From
+	}
+	if ctx.Update.CallbackQuery != nil {
+		return ctx.Update.CallbackQuery.From
+	}
+	return nil
+}
+
+// Arg возвращает часть данных кнопки или пустую строку, если её нет
+func (ctx *Context) Arg(index int) string {
+	if index < 0 || index >= len(ctx.Args) {
+		return ""
+	}
+	return ctx.Args[index]
+}
+
+// Handler обрабатывает обновление
+type Handler func(ctx *Context) error
+
+// Middleware оборачивает обработчик общей логикой
+type Middleware func(next Handler) Handler
+
+// Matcher решает, подходит ли обновление обработчику
+type Matcher func(ctx *Context) bool
+
+type route struct {
+	match   Matcher
+	handler Handler
+}
+
+// Router выбирает первый подходящий обработчик в порядке регистрации
+type Router struct {
+	routes     []route
+	middleware []Middleware
+	fallback   Handler
+}
+
+// ErrNotFound возвращается, когда обновлению не нашлось обработчика
+var ErrNotFound = errors.New("не найден обработчик обновления")
+
+func New() *Router {
+	return &Router{}
+}
+
+// Use добавляет middleware ко всем обработчикам. Первый добавленный выполняется первым
+func (router *Router) Use(middleware ...Middleware) {
+	router.middleware = append(router.middleware, middleware...)
+}
+
+// Match регистрирует обработчик для обновлений, удовлетворяющих условию
+func (router *Router) Match(match Matcher, handler Handler, middleware ...Middleware) {
+	router.routes = append(router.routes, route{match: match, handler: chain(handler, middleware)})
+}
+
+// Command регистрирует обработчик команды, name указывается без косой черты
+func (router *Router) Command(name string, handler Handler, middleware ...Middleware) {
+	router.Match(func(ctx *Context) bool {
+		message := ctx.Message()
+		return message != nil && message.IsCommand() && message.Command() == name
+	}, handler, middleware...)
+}
+
+// Button регистрирует обработчик кнопки обычной клавиатуры по ключу её надписи в каталоге i18n
+func (router *Router) Button(key string, handler Handler, middleware ...Middleware) {
+	router.Match(func(ctx *Context) bool {
+		message := ctx.Message()
+		return message != nil && !message.IsCommand() && i18n.IsButton(message.Text, key)
+	}, handler, middleware...)
+}
+
+// Callback регистрирует обработчик встроенных кнопок с данными вида prefix_arg1_arg2
+func (router *Router) Callback(prefix string, handler Handler, middleware ...Middleware) {
+	router.Match(func(ctx *Context) bool {
+		callback := ctx.Callback()
+		return callback != nil && CallbackPrefix(callback.Data) == prefix
+	}, handler, middleware...)
+}
+
+// Fallback регистрирует обработчик для обновлений, которым ничего не подошло
+func (router *Router) Fallback(handler Handler, middleware ...Middleware) {
+	router.fallback = chain(handler, middleware)
+}
+
+// Handle передаёт обновление первому подходящему обработчику
+func (router *Router) Handle(ctx *Context) error {
+	if callback := ctx.Callback(); callback != nil {
+		ctx.Args = strings.Split(callback.Data, "_")[1:]
+	}
+	handler := router.fallback
+	for _, route := range router.routes {
+		if route.match(ctx) {
+			handler = route.handler
+			break
+		}
+	}
+	if handler == nil {
+		handler = func(ctx *Context) error {
+			return ErrNotFound
+		}
+	}
+	return chain(handler, router.middleware)(ctx)
+}
+
+// CallbackPrefix возвращает часть данных кнопки до первого "_"
+func CallbackPrefix(data string) string {
+	return strings.SplitN(data, "_", 2)[0]
+}
+
+func chain(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,196 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func commandContext(text string) *Context {
+	return &Context{Update: tgbotapi.Update{Message: &tgbotapi.Message{
+		Text:     text,
+		Entities: &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(text)}},
+	}}}
+}
+
+func textContext(text string) *Context {
+	return &Context{Update: tgbotapi.Update{Message: &tgbotapi.Message{Text: text}}}
+}
+
+func callbackContext(data string) *Context {
+	return &Context{Update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{Data: data}}}
+}
+
+func record(handled *string, name string) Handler {
+	return func(ctx *Context) error {
+		*handled = name
+		return nil
+	}
+}
+
+func TestRouter_Handle(t *testing.T) {
+	var handled string
+	router := New()
+	router.Command("start", record(&handled, "start"))
+	router.Button("button.add_key", record(&handled, "add_key"))
+	router.Callback("stats", record(&handled, "stats"))
+	router.Match(func(ctx *Context) bool {
+		return ctx.Message() != nil && ctx.Message().Text == "golos.io"
+	}, record(&handled, "link"))
+	router.Fallback(record(&handled, "fallback"))
+
+	cases := map[string]*Context{
+		"start":    commandContext("/start"),
+		"add_key":  textContext("🐬Delegate"),
+		"stats":    callbackContext("stats_week"),
+		"link":     textContext("golos.io"),
+		"fallback": commandContext("/unknown"),
+	}
+	for expected, ctx := range cases {
+		handled = ""
+		err := router.Handle(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if handled != expected {
+			t.Errorf("ожидали обработчик %s, сработал %q", expected, handled)
+		}
+	}
+}
+
+func TestRouter_CallbackArgs(t *testing.T) {
+	router := New()
+	var args []string
+	router.Callback("referral", func(ctx *Context) error {
+		args = ctx.Args
+		if ctx.Arg(5) != "" {
+			t.Error("отсутствующая часть данных должна быть пустой")
+		}
+		return nil
+	})
+	err := router.Handle(callbackContext("referral_approve_42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 2 || args[0] != "approve" || args[1] != "42" {
+		t.Errorf("неверно разобрали данные кнопки: %v", args)
+	}
+	// данные без "_" не должны ронять разбор
+	if err = router.Handle(callbackContext("referral")); err != nil {
+		t.Fatal(err)
+	}
+	if err = router.Handle(callbackContext("unknown_data")); err != ErrNotFound {
+		t.Errorf("ожидали ErrNotFound, получили %v", err)
+	}
+}
+
+func TestRouter_Middleware(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx *Context) error {
+				order = append(order, name)
+				return next(ctx)
+			}
+		}
+	}
+	router := New()
+	router.Use(trace("global"))
+	router.Command("start", func(ctx *Context) error {
+		order = append(order, "handler")
+		return nil
+	}, trace("route"))
+	err := router.Handle(commandContext("/start"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != "global" || order[1] != "route" || order[2] != "handler" {
+		t.Errorf("неверный порядок middleware: %v", order)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	handler := Recover()(func(ctx *Context) error {
+		var args []string
+		_ = args[1]
+		return nil
+	})
+	if err := handler(callbackContext("stats")); err == nil {
+		t.Error("паника должна превратиться в ошибку")
+	}
+}
+
+func TestAllow(t *testing.T) {
+	denied := errors.New("запрещено")
+	handler := Allow(func(ctx *Context) bool {
+		return ctx.UserID == 1
+	}, func(ctx *Context) error {
+		return denied
+	})(func(ctx *Context) error {
+		return nil
+	})
+	if err := handler(&Context{UserID: 1}); err != nil {
+		t.Error("администратора нужно пропустить")
+	}
+	if err := handler(&Context{UserID: 2}); err != denied {
+		t.Error("остальным нужно отказать")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limited := errors.New("слишком часто")
+	handler := RateLimit(2, time.Minute, func(ctx *Context) error {
+		return limited
+	})(func(ctx *Context) error {
+		return nil
+	})
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := handler(&Context{UserID: 1, Now: now}); err != nil {
+			t.Fatalf("запрос %d должен пройти", i)
+		}
+	}
+	if err := handler(&Context{UserID: 1, Now: now}); err != limited {
+		t.Error("третий запрос за минуту должен быть отклонён")
+	}
+	if err := handler(&Context{UserID: 2, Now: now}); err != nil {
+		t.Error("ограничение действует на каждого пользователя отдельно")
+	}
+	if err := handler(&Context{UserID: 1, Now: now.Add(time.Minute)}); err != nil {
+		t.Error("через минуту запросы снова должны проходить")
+	}
+}
+
+func TestRateLimiterForgetsIdleUsers(t *testing.T) {
+	limiter := newRateLimiter(2, time.Minute)
+	now := time.Now()
+	for user := 1; user <= 3; user++ {
+		limiter.allow(user, now)
+	}
+	limiter.allow(4, now.Add(2*time.Minute))
+	if len(limiter.recent) != 1 {
+		t.Errorf("%d пользователей в памяти вместо 1", len(limiter.recent))
+	}
+}
+
+func TestWhen(t *testing.T) {
+	denied := errors.New("отклонено")
+	deny := func(next Handler) Handler {
+		return func(ctx *Context) error {
+			return denied
+		}
+	}
+	handler := When(func(ctx *Context) bool {
+		return ctx.UserID == 1
+	}, deny)(func(ctx *Context) error {
+		return nil
+	})
+	if err := handler(&Context{UserID: 1}); err != denied {
+		t.Error("middleware должен применяться к подходящим обновлениям")
+	}
+	if err := handler(&Context{UserID: 2}); err != nil {
+		t.Error("остальные обновления должны идти мимо middleware")
+	}
+}
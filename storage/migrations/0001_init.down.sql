@@ -0,0 +1,7 @@
+DROP TABLE events;
+DROP TABLE states;
+DROP TABLE referrals;
+DROP TABLE responses;
+DROP TABLE vote_tags;
+DROP TABLE votes;
+DROP TABLE credentials;
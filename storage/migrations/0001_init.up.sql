@@ -0,0 +1,66 @@
+-- Схема, которую раньше создавал NewPostgres. IF NOT EXISTS позволяет отметить миграцию
+-- применённой и в базах, созданных до появления миграций
+CREATE TABLE IF NOT EXISTS credentials(
+	id BIGSERIAL NOT NULL,
+	user_name TEXT PRIMARY KEY NOT NULL,
+	user_id BIGINT NOT NULL,
+	chat_id BIGINT NOT NULL DEFAULT 0,
+	power INTEGER NOT NULL DEFAULT 100,
+	active BOOLEAN NOT NULL DEFAULT FALSE,
+	curates BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS idx_credentials_user_id ON credentials(user_id);
+CREATE TABLE IF NOT EXISTS votes(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	user_id BIGINT NOT NULL,
+	author TEXT NOT NULL,
+	permalink TEXT NOT NULL,
+	percent INTEGER NOT NULL DEFAULT 0,
+	completed BOOLEAN NOT NULL DEFAULT FALSE,
+	rejected BOOLEAN NOT NULL DEFAULT FALSE,
+	addled BOOLEAN NOT NULL DEFAULT FALSE,
+	plagiarism BOOLEAN NOT NULL DEFAULT FALSE,
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_author_permalink ON votes(author, permalink);
+CREATE TABLE IF NOT EXISTS vote_tags(
+	vote_id BIGINT NOT NULL,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (vote_id, tag)
+);
+CREATE TABLE IF NOT EXISTS responses(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	user_id BIGINT NOT NULL,
+	vote_id BIGINT NOT NULL,
+	result BOOLEAN NOT NULL,
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_vote ON responses(user_id, vote_id);
+CREATE TABLE IF NOT EXISTS referrals(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	user_id BIGINT UNIQUE NOT NULL,
+	referrer TEXT NOT NULL,
+	referral TEXT NOT NULL DEFAULT '',
+	campaign TEXT NOT NULL DEFAULT '',
+	completed BOOLEAN NOT NULL DEFAULT FALSE,
+	status TEXT NOT NULL DEFAULT '',
+	deadline TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	referrer_paid BOOLEAN NOT NULL DEFAULT FALSE,
+	referral_paid BOOLEAN NOT NULL DEFAULT FALSE,
+	approved BOOLEAN NOT NULL DEFAULT FALSE,
+	paid_at TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00'
+);
+CREATE TABLE IF NOT EXISTS states(
+	user_id BIGINT PRIMARY KEY NOT NULL,
+	action TEXT NOT NULL DEFAULT '',
+	payload TEXT NOT NULL DEFAULT '',
+	expires TIMESTAMPTZ NOT NULL DEFAULT '1970-01-01 00:00:00+00'
+);
+CREATE TABLE IF NOT EXISTS events(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	type TEXT NOT NULL,
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+INSERT INTO events(type) SELECT 'POST' WHERE NOT EXISTS (SELECT 1 FROM events WHERE type = 'POST');
+INSERT INTO events(type) SELECT 'REWARD' WHERE NOT EXISTS (SELECT 1 FROM events WHERE type = 'REWARD');
@@ -0,0 +1,3 @@
+DROP TRIGGER audit_no_change ON audit;
+DROP FUNCTION audit_append_only();
+DROP TABLE audit;
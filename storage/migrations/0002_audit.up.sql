@@ -0,0 +1,20 @@
+-- журнал изменений хранится в той же базе, что и данные, чтобы писать его одной транзакцией с ними
+CREATE TABLE audit(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	actor TEXT NOT NULL,
+	entity TEXT NOT NULL,
+	entity_id TEXT NOT NULL,
+	old_value TEXT NOT NULL,
+	new_value TEXT NOT NULL,
+	date TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX audit_entity ON audit(entity, entity_id);
+CREATE INDEX audit_actor ON audit(actor);
+-- журнал изменений только дописывается
+CREATE FUNCTION audit_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'журнал изменений нельзя менять';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER audit_no_change BEFORE UPDATE OR DELETE ON audit
+	FOR EACH ROW EXECUTE PROCEDURE audit_append_only();
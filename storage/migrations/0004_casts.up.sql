@@ -0,0 +1,11 @@
+-- голоса, отданные ботом от имени аккаунтов
+CREATE TABLE casts(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	vote_id BIGINT NOT NULL,
+	user_name TEXT NOT NULL,
+	author TEXT NOT NULL,
+	permalink TEXT NOT NULL,
+	weight INTEGER NOT NULL,
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_casts_user_name ON casts(user_name, date);
@@ -0,0 +1,9 @@
+DROP TABLE cursors;
+DROP TABLE outbox;
+DROP TABLE removals;
+DROP TABLE members;
+DROP TABLE languages;
+DROP TABLE admin_actions;
+DROP TABLE bans;
+DROP TABLE claims;
+DROP TABLE trusts;
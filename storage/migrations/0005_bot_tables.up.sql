@@ -0,0 +1,69 @@
+-- остальные таблицы бота, чтобы он целиком работал на PostgreSQL: доверие, заявки на аккаунты,
+-- баны и команды администраторов, языки, участники группы, очередь сообщений и курсоры блокчейна
+CREATE TABLE trusts(
+	user_id BIGINT PRIMARY KEY NOT NULL,
+	score DOUBLE PRECISION NOT NULL DEFAULT 0,
+	approval_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+	addled_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+	plagiarism_count INTEGER NOT NULL DEFAULT 0,
+	closed_votes INTEGER NOT NULL DEFAULT 0,
+	account_created TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00',
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE claims(
+	user_id BIGINT PRIMARY KEY NOT NULL,
+	chat_id BIGINT NOT NULL DEFAULT 0,
+	user_name TEXT NOT NULL,
+	code TEXT NOT NULL,
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_claims_user_name ON claims(user_name);
+CREATE TABLE bans(
+	kind TEXT NOT NULL,
+	target TEXT NOT NULL,
+	admin_id BIGINT NOT NULL,
+	date TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (kind, target)
+);
+CREATE TABLE admin_actions(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	admin_id BIGINT NOT NULL,
+	command TEXT NOT NULL,
+	arguments TEXT NOT NULL DEFAULT '',
+	result TEXT NOT NULL DEFAULT '',
+	date TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_admin_actions_date ON admin_actions(date);
+CREATE TABLE languages(
+	user_id BIGINT PRIMARY KEY NOT NULL,
+	code TEXT NOT NULL,
+	manual BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE TABLE members(
+	user_id BIGINT PRIMARY KEY NOT NULL,
+	verified BOOLEAN NOT NULL,
+	since TIMESTAMPTZ NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	retry_at TIMESTAMPTZ NOT NULL DEFAULT '1970-01-01 00:00:00+00'
+);
+CREATE TABLE removals(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	user_id BIGINT NOT NULL,
+	reason TEXT NOT NULL,
+	date TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE outbox(
+	id BIGSERIAL PRIMARY KEY NOT NULL,
+	chat_id BIGINT NOT NULL,
+	config TEXT NOT NULL,
+	date TIMESTAMPTZ NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	retry_at TIMESTAMPTZ NOT NULL DEFAULT '1970-01-01 00:00:00+00',
+	dead BOOLEAN NOT NULL DEFAULT FALSE,
+	error TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE cursors(
+	name TEXT PRIMARY KEY NOT NULL,
+	block_num BIGINT NOT NULL DEFAULT 0,
+	date TIMESTAMPTZ NOT NULL DEFAULT now()
+);
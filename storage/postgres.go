@@ -0,0 +1,1140 @@
+package storage
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+const (
+	eventReport = "POST"
+	eventReward = "REWARD"
+)
+
+const voteColumns = "id, user_id, author, permalink, percent, completed, rejected, addled, plagiarism, closed, date"
+
+const credentialColumns = "user_id, chat_id, user_name, power, active, curates"
+
+const referralColumns = "user_id, referrer, referral, campaign, completed, status, deadline, attempts, " +
+	"referrer_paid, referral_paid, approved, paid_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// postgresStorage хранит все таблицы бота в PostgreSQL. Запросы повторяют запросы пакета models,
+// но с нумерованными параметрами и ON CONFLICT вместо INSERT OR REPLACE.
+// Журнал изменений ведётся в таблице audit той же базы
+type postgresStorage struct {
+	db models.Executor
+}
+
+// NewPostgres применяет недостающие миграции из storage/migrations и возвращает хранилище поверх db
+func NewPostgres(db *sql.DB) (Storage, error) {
+	err := migratePostgres(db)
+	if err != nil {
+		return nil, err
+	}
+	return postgresStorage{db: db}, nil
+}
+
+func (storage postgresStorage) Close() error {
+	if database, ok := storage.db.(*sql.DB); ok {
+		return database.Close()
+	}
+	return nil
+}
+
+func (storage postgresStorage) Transaction(do func(store Storage) error) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		return do(tx)
+	})
+}
+
+func scanCredential(row scanner) (credential models.Credential, err error) {
+	err = row.Scan(&credential.UserID,
+		&credential.ChatID,
+		&credential.UserName,
+		&credential.Power,
+		&credential.Active,
+		&credential.Curates)
+	return credential, err
+}
+
+func scanCredentials(rows *sql.Rows, err error) (credentials []models.Credential, _ error) {
+	if err != nil {
+		return credentials, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			return credentials, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+func (storage postgresStorage) SaveCredential(credential models.Credential, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.credentialValue(credential.UserName)
+		_, err := tx.db.Exec("INSERT INTO credentials(user_id, chat_id, user_name, power, active, curates) "+
+			"VALUES($1, $2, $3, $4, $5, $6) ON CONFLICT (user_name) DO UPDATE SET "+
+			"user_id = EXCLUDED.user_id, chat_id = EXCLUDED.chat_id, power = EXCLUDED.power, "+
+			"active = EXCLUDED.active, curates = EXCLUDED.curates",
+			credential.UserID,
+			credential.ChatID,
+			credential.UserName,
+			credential.Power,
+			credential.Active,
+			credential.Curates)
+		if err != nil {
+			return err
+		}
+		after := tx.credentialValue(credential.UserName)
+		return tx.record(actor, models.EntityCredential, credential.UserName, before, after)
+	})
+}
+
+func (storage postgresStorage) credentialValue(userName string) interface{} {
+	credential, err := storage.GetCredentialByUserName(userName)
+	if err != nil {
+		return nil
+	}
+	return credential
+}
+
+func (storage postgresStorage) GetCredentialByUserID(userID int) (models.Credential, error) {
+	row := storage.db.QueryRow("SELECT "+credentialColumns+" FROM credentials "+
+		"WHERE user_id = $1 ORDER BY active DESC, id LIMIT 1", userID)
+	return scanCredential(row)
+}
+
+func (storage postgresStorage) GetCredentialsByUserID(userID int) ([]models.Credential, error) {
+	return scanCredentials(storage.db.Query("SELECT "+credentialColumns+" FROM credentials "+
+		"WHERE user_id = $1 ORDER BY id", userID))
+}
+
+func (storage postgresStorage) GetActiveCredentialsByUserID(userID int) ([]models.Credential, error) {
+	return scanCredentials(storage.db.Query("SELECT "+credentialColumns+" FROM credentials "+
+		"WHERE user_id = $1 AND active ORDER BY id", userID))
+}
+
+func (storage postgresStorage) GetCredentialByUserName(userName string) (models.Credential, error) {
+	row := storage.db.QueryRow("SELECT "+credentialColumns+" FROM credentials WHERE user_name = $1", userName)
+	return scanCredential(row)
+}
+
+func (storage postgresStorage) GetAllActiveCredentials() ([]models.Credential, error) {
+	return scanCredentials(storage.db.Query("SELECT " + credentialColumns + " FROM credentials WHERE active ORDER BY id"))
+}
+
+func (storage postgresStorage) GetAllCredentials() ([]models.Credential, error) {
+	return scanCredentials(storage.db.Query("SELECT " + credentialColumns + " FROM credentials ORDER BY id"))
+}
+
+func (storage postgresStorage) UpdatePower(userName string, power int, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.credentialValue(userName)
+		_, err := tx.db.Exec("UPDATE credentials SET power = $1 WHERE user_name = $2", power, userName)
+		if err != nil || before == nil {
+			return err
+		}
+		return tx.record(actor, models.EntityCredential, userName, before, tx.credentialValue(userName))
+	})
+}
+
+func (storage postgresStorage) IsActiveCredential(userID int) bool {
+	return storage.count("SELECT COUNT(*) FROM credentials "+
+		"WHERE user_id = $1 AND active AND user_name != ''", userID) > 0
+}
+
+func (storage postgresStorage) ActivateCurator(userID int, actor models.Actor) error {
+	return storage.setCurates(userID, true, actor)
+}
+
+func (storage postgresStorage) DeactivateCurator(userID int, actor models.Actor) error {
+	return storage.setCurates(userID, false, actor)
+}
+
+func (storage postgresStorage) setCurates(userID int, curates bool, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		credentials, err := tx.GetCredentialsByUserID(userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.db.Exec("UPDATE credentials SET curates = $1 WHERE user_id = $2", curates, userID)
+		if err != nil {
+			return err
+		}
+		for _, credential := range credentials {
+			err = tx.record(actor, models.EntityCredential, credential.UserName,
+				credential, tx.credentialValue(credential.UserName))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (storage postgresStorage) IsActiveCurator(userID int) bool {
+	return storage.count("SELECT COUNT(*) FROM credentials WHERE user_id = $1 AND curates AND active", userID) > 0
+}
+
+func (storage postgresStorage) GetAllActiveCuratorsChatID() (chatIDs []int64, err error) {
+	rows, err := storage.db.Query("SELECT DISTINCT chat_id FROM credentials WHERE curates AND active")
+	if err != nil {
+		return chatIDs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chatID int64
+		err = rows.Scan(&chatID)
+		if err != nil {
+			return chatIDs, err
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, rows.Err()
+}
+
+func (storage postgresStorage) GetAllActiveCuratorsID() ([]int, error) {
+	return storage.userIDs("SELECT DISTINCT user_id FROM credentials WHERE curates AND active")
+}
+
+func (storage postgresStorage) GetAllChatIDs() (chatIDs []int64, err error) {
+	rows, err := storage.db.Query("SELECT DISTINCT chat_id FROM credentials WHERE chat_id != 0")
+	if err != nil {
+		return chatIDs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chatID int64
+		err = rows.Scan(&chatID)
+		if err != nil {
+			return chatIDs, err
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, rows.Err()
+}
+
+func scanVote(row scanner) (vote models.Vote, err error) {
+	err = row.Scan(&vote.VoteID,
+		&vote.UserID,
+		&vote.Author,
+		&vote.Permalink,
+		&vote.Percent,
+		&vote.Completed,
+		&vote.Rejected,
+		&vote.Addled,
+		&vote.Plagiarism,
+		&vote.Closed,
+		&vote.Date)
+	return vote, err
+}
+
+func scanVotes(rows *sql.Rows, err error) (votes []models.Vote, _ error) {
+	if err != nil {
+		return votes, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		vote, err := scanVote(rows)
+		if err != nil {
+			return votes, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+// SaveVote обновляет сохранённое голосование, не меняя его id, или добавляет новое
+func (storage postgresStorage) SaveVote(vote models.Vote, actor models.Actor) (voteID int64, err error) {
+	err = storage.inTransaction(func(tx postgresStorage) error {
+		var before interface{}
+		if vote.VoteID != 0 {
+			before = tx.voteValue(vote.VoteID)
+		}
+		voteID, err = tx.saveVote(vote)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityVote, strconv.FormatInt(voteID, 10),
+			before, tx.voteValue(voteID))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return voteID, nil
+}
+
+func (storage postgresStorage) voteValue(voteID int64) interface{} {
+	vote := storage.GetVote(voteID)
+	if vote.VoteID == 0 {
+		return nil
+	}
+	return vote
+}
+
+func (storage postgresStorage) saveVote(vote models.Vote) (int64, error) {
+	if vote.VoteID == 0 {
+		row := storage.db.QueryRow("INSERT INTO votes("+
+			"user_id, author, permalink, percent, completed, rejected, addled, plagiarism, closed, date) "+
+			"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id",
+			vote.UserID,
+			vote.Author,
+			vote.Permalink,
+			vote.Percent,
+			vote.Completed,
+			vote.Rejected,
+			vote.Addled,
+			vote.Plagiarism,
+			vote.Closed,
+			vote.Date)
+		var id int64
+		err := row.Scan(&id)
+		return id, err
+	}
+	_, err := storage.db.Exec("INSERT INTO votes("+voteColumns+") "+
+		"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (id) DO UPDATE SET "+
+		"user_id = EXCLUDED.user_id, author = EXCLUDED.author, permalink = EXCLUDED.permalink, "+
+		"percent = EXCLUDED.percent, completed = EXCLUDED.completed, rejected = EXCLUDED.rejected, "+
+		"addled = EXCLUDED.addled, plagiarism = EXCLUDED.plagiarism, closed = EXCLUDED.closed, date = EXCLUDED.date",
+		vote.VoteID,
+		vote.UserID,
+		vote.Author,
+		vote.Permalink,
+		vote.Percent,
+		vote.Completed,
+		vote.Rejected,
+		vote.Addled,
+		vote.Plagiarism,
+		vote.Closed,
+		vote.Date)
+	if err != nil {
+		return 0, err
+	}
+	// голосование с явным id не сдвигает последовательность, сдвигаем её сами
+	_, err = storage.db.Exec("SELECT setval(pg_get_serial_sequence('votes', 'id'), " +
+		"GREATEST((SELECT MAX(id) FROM votes), 1))")
+	return vote.VoteID, err
+}
+
+func (storage postgresStorage) GetVote(voteID int64) models.Vote {
+	vote, _ := scanVote(storage.db.QueryRow("SELECT "+voteColumns+" FROM votes WHERE id = $1", voteID))
+	return vote
+}
+
+func (storage postgresStorage) VoteExists(vote models.Vote) bool {
+	return storage.count("SELECT COUNT(*) FROM votes WHERE author = $1 AND permalink = $2",
+		vote.Author, vote.Permalink) > 0
+}
+
+func (storage postgresStorage) GetOpenedVotesCount() int {
+	return storage.count("SELECT COUNT(*) FROM votes WHERE NOT completed")
+}
+
+func (storage postgresStorage) GetOpenedVotesCountForUserID(userID int) int {
+	return storage.count("SELECT COUNT(*) FROM votes WHERE NOT completed AND user_id = $1", userID)
+}
+
+func (storage postgresStorage) GetLastVotesForUserID(userID int, num int) ([]models.Vote, error) {
+	return scanVotes(storage.db.Query("SELECT "+voteColumns+" FROM votes "+
+		"WHERE user_id = $1 ORDER BY id DESC LIMIT $2", userID, num))
+}
+
+func (storage postgresStorage) GetVotesPageForUserID(userID int, limit int, offset int) ([]models.Vote, error) {
+	return scanVotes(storage.db.Query("SELECT "+voteColumns+" FROM votes "+
+		"WHERE user_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3", userID, limit, offset))
+}
+
+func (storage postgresStorage) GetVotesCountForUserID(userID int) int {
+	return storage.count("SELECT COUNT(*) FROM votes WHERE user_id = $1", userID)
+}
+
+func (storage postgresStorage) GetLastVoteForUserID(userID int) models.Vote {
+	vote, _ := scanVote(storage.db.QueryRow("SELECT "+voteColumns+" FROM votes "+
+		"WHERE user_id = $1 ORDER BY id DESC LIMIT 1", userID))
+	return vote
+}
+
+func (storage postgresStorage) GetAllOpenedVotes() ([]models.Vote, error) {
+	return scanVotes(storage.db.Query("SELECT " + voteColumns + " FROM votes WHERE NOT completed ORDER BY id"))
+}
+
+func (storage postgresStorage) GetTrulyCompletedVotesSince(date time.Time) ([]models.Vote, error) {
+	return scanVotes(storage.db.Query("SELECT "+voteColumns+" FROM votes "+
+		"WHERE date > $1 AND completed AND NOT rejected AND NOT addled AND NOT plagiarism AND NOT closed ORDER BY id", date))
+}
+
+func (storage postgresStorage) GetVotesBetween(since time.Time, until time.Time) ([]models.Vote, error) {
+	return scanVotes(storage.db.Query("SELECT "+voteColumns+" FROM votes "+
+		"WHERE date >= $1 AND date < $2 ORDER BY id", since, until))
+}
+
+func (storage postgresStorage) SaveVoteTags(voteID int64, tags []string) error {
+	for _, tag := range tags {
+		_, err := storage.db.Exec("INSERT INTO vote_tags(vote_id, tag) VALUES($1, $2) ON CONFLICT DO NOTHING",
+			voteID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (storage postgresStorage) GetVoteTags(voteID int64) (tags []string, err error) {
+	rows, err := storage.db.Query("SELECT tag FROM vote_tags WHERE vote_id = $1", voteID)
+	if err != nil {
+		return tags, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (storage postgresStorage) GetOpenedVotesCountForTag(tag string) int {
+	return storage.count("SELECT COUNT(DISTINCT votes.id) FROM votes "+
+		"JOIN vote_tags ON vote_tags.vote_id = votes.id "+
+		"WHERE NOT votes.completed AND vote_tags.tag = $1", tag)
+}
+
+func (storage postgresStorage) SaveCast(cast models.Cast) error {
+	_, err := storage.db.Exec("INSERT INTO casts(vote_id, user_name, author, permalink, weight, date) "+
+		"VALUES($1, $2, $3, $4, $5, $6)",
+		cast.VoteID, cast.UserName, cast.Author, cast.Permalink, cast.Weight, cast.Date)
+	return err
+}
+
+func (storage postgresStorage) GetCastsCountForVoteID(voteID int64) int {
+	return storage.count("SELECT COUNT(DISTINCT user_name) FROM casts WHERE vote_id = $1", voteID)
+}
+
+func (storage postgresStorage) GetCastsByUserNameSince(userName string, date time.Time) (casts []models.Cast, err error) {
+	rows, err := storage.db.Query("SELECT vote_id, user_name, author, permalink, weight, date FROM casts "+
+		"WHERE user_name = $1 AND date > $2 ORDER BY id", userName, date)
+	if err != nil {
+		return casts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cast models.Cast
+		err = rows.Scan(&cast.VoteID, &cast.UserName, &cast.Author, &cast.Permalink, &cast.Weight, &cast.Date)
+		if err != nil {
+			return casts, err
+		}
+		casts = append(casts, cast)
+	}
+	return casts, rows.Err()
+}
+
+func (storage postgresStorage) SaveResponse(response models.Response, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.responseValue(response)
+		_, err := tx.db.Exec("INSERT INTO responses(user_id, vote_id, result, date) "+
+			"VALUES($1, $2, $3, $4) ON CONFLICT (user_id, vote_id) DO UPDATE SET "+
+			"result = EXCLUDED.result, date = EXCLUDED.date",
+			response.UserID, response.VoteID, response.Result, response.Date)
+		if err != nil {
+			return err
+		}
+		entityID := strconv.Itoa(response.UserID) + ":" + strconv.FormatInt(response.VoteID, 10)
+		return tx.record(actor, models.EntityResponse, entityID, before, tx.responseValue(response))
+	})
+}
+
+func (storage postgresStorage) responseValue(response models.Response) interface{} {
+	row := storage.db.QueryRow("SELECT user_id, vote_id, result, date FROM responses "+
+		"WHERE user_id = $1 AND vote_id = $2", response.UserID, response.VoteID)
+	var stored models.Response
+	err := row.Scan(&stored.UserID, &stored.VoteID, &stored.Result, &stored.Date)
+	if err != nil {
+		return nil
+	}
+	return stored
+}
+
+func (storage postgresStorage) ResponseExists(response models.Response) bool {
+	return storage.count("SELECT COUNT(*) FROM responses WHERE user_id = $1 AND vote_id = $2",
+		response.UserID, response.VoteID) > 0
+}
+
+func (storage postgresStorage) GetAllResponsesForVoteID(voteID int64) ([]models.Response, error) {
+	return scanResponses(storage.db.Query("SELECT user_id, vote_id, result, date FROM responses "+
+		"WHERE vote_id = $1 ORDER BY id", voteID))
+}
+
+func (storage postgresStorage) GetResponsesBetween(since time.Time, until time.Time) ([]models.Response, error) {
+	return scanResponses(storage.db.Query("SELECT user_id, vote_id, result, date FROM responses "+
+		"WHERE date >= $1 AND date < $2 ORDER BY id", since, until))
+}
+
+func scanResponses(rows *sql.Rows, err error) (responses []models.Response, _ error) {
+	if err != nil {
+		return responses, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var response models.Response
+		err = rows.Scan(&response.UserID, &response.VoteID, &response.Result, &response.Date)
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, rows.Err()
+}
+
+func (storage postgresStorage) GetNumResponsesVoteID(voteID int64) (int, int) {
+	positives := storage.count("SELECT COUNT(*) FROM responses WHERE vote_id = $1 AND result", voteID)
+	negatives := storage.count("SELECT COUNT(*) FROM responses WHERE vote_id = $1 AND NOT result", voteID)
+	return positives, negatives
+}
+
+func (storage postgresStorage) GetNumResponsesForMotivation(date time.Time) int {
+	return storage.count("SELECT COUNT(*) FROM responses WHERE date > $1", date)
+}
+
+func (storage postgresStorage) GetUserIDsForMotivation(date time.Time) ([]int, error) {
+	return storage.userIDs("SELECT DISTINCT user_id FROM responses WHERE date > $1", date)
+}
+
+func (storage postgresStorage) GetNumResponsesForMotivationForUserID(userID int, date time.Time) int {
+	return storage.count("SELECT COUNT(*) FROM responses WHERE date > $1 AND user_id = $2", date, userID)
+}
+
+func scanReferral(row scanner) (referral models.Referral, err error) {
+	err = row.Scan(&referral.UserID,
+		&referral.Referrer,
+		&referral.UserName,
+		&referral.Campaign,
+		&referral.Completed,
+		&referral.Status,
+		&referral.Deadline,
+		&referral.Attempts,
+		&referral.ReferrerPaid,
+		&referral.ReferralPaid,
+		&referral.Approved,
+		&referral.PaidAt)
+	return referral, err
+}
+
+func (storage postgresStorage) SaveReferral(referral models.Referral, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.referralValue(referral.UserID)
+		_, err := tx.db.Exec("INSERT INTO referrals("+referralColumns+") "+
+			"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (user_id) DO UPDATE SET "+
+			"referrer = EXCLUDED.referrer, referral = EXCLUDED.referral, campaign = EXCLUDED.campaign, "+
+			"completed = EXCLUDED.completed, status = EXCLUDED.status, deadline = EXCLUDED.deadline, "+
+			"attempts = EXCLUDED.attempts, referrer_paid = EXCLUDED.referrer_paid, "+
+			"referral_paid = EXCLUDED.referral_paid, approved = EXCLUDED.approved, paid_at = EXCLUDED.paid_at",
+			referral.UserID,
+			referral.Referrer,
+			referral.UserName,
+			referral.Campaign,
+			referral.Completed,
+			referral.Status,
+			referral.Deadline,
+			referral.Attempts,
+			referral.ReferrerPaid,
+			referral.ReferralPaid,
+			referral.Approved,
+			referral.PaidAt)
+		if err != nil {
+			return err
+		}
+		after := tx.referralValue(referral.UserID)
+		return tx.record(actor, models.EntityReferral, strconv.Itoa(referral.UserID), before, after)
+	})
+}
+
+func (storage postgresStorage) SetReferralCompleted(userID int, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.referralValue(userID)
+		_, err := tx.db.Exec("UPDATE referrals SET completed = TRUE WHERE user_id = $1", userID)
+		if err != nil || before == nil {
+			return err
+		}
+		return tx.record(actor, models.EntityReferral, strconv.Itoa(userID), before, tx.referralValue(userID))
+	})
+}
+
+func (storage postgresStorage) referralValue(userID int) interface{} {
+	referral, err := storage.GetReferralByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return referral
+}
+
+func (storage postgresStorage) GetReferralByUserID(userID int) (models.Referral, error) {
+	return scanReferral(storage.db.QueryRow("SELECT "+referralColumns+" FROM referrals WHERE user_id = $1", userID))
+}
+
+func (storage postgresStorage) GetPendingReferrals() (referrals []models.Referral, err error) {
+	rows, err := storage.db.Query("SELECT "+referralColumns+" FROM referrals WHERE status = $1 ORDER BY id",
+		models.ReferralPending)
+	if err != nil {
+		return referrals, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		referral, err := scanReferral(rows)
+		if err != nil {
+			return referrals, err
+		}
+		referrals = append(referrals, referral)
+	}
+	return referrals, rows.Err()
+}
+
+func (storage postgresStorage) GetAllReferrals() (referrals []models.Referral, err error) {
+	rows, err := storage.db.Query("SELECT " + referralColumns + " FROM referrals ORDER BY id")
+	if err != nil {
+		return referrals, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		referral, err := scanReferral(rows)
+		if err != nil {
+			return referrals, err
+		}
+		referrals = append(referrals, referral)
+	}
+	return referrals, rows.Err()
+}
+
+func (storage postgresStorage) GetPaidReferralsCountSince(referrer string, date time.Time) int {
+	return storage.count("SELECT COUNT(*) FROM referrals WHERE referrer = $1 AND status = $2 AND paid_at > $3",
+		referrer, models.ReferralPaid, date)
+}
+
+func (storage postgresStorage) GetReferralStats(referrer string) (stats []models.ReferralStats, err error) {
+	rows, err := storage.db.Query("SELECT referrer, campaign, COUNT(*), "+
+		"SUM(CASE WHEN completed THEN 1 ELSE 0 END), "+
+		"SUM(CASE WHEN status = $1 THEN 1 ELSE 0 END) FROM referrals "+
+		"WHERE $2 = '' OR referrer = $2 GROUP BY referrer, campaign ORDER BY referrer, campaign",
+		models.ReferralPaid, referrer)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row models.ReferralStats
+		err = rows.Scan(&row.Referrer, &row.Campaign, &row.Invited, &row.Registered, &row.Paid)
+		if err != nil {
+			return stats, err
+		}
+		stats = append(stats, row)
+	}
+	return stats, rows.Err()
+}
+
+func (storage postgresStorage) IsReferralExists(referral string) bool {
+	return storage.count("SELECT COUNT(*) FROM referrals WHERE referral = $1", referral) > 0
+}
+
+func (storage postgresStorage) SaveState(state models.State) error {
+	_, err := storage.db.Exec("INSERT INTO states(user_id, action, payload, expires) "+
+		"VALUES($1, $2, $3, $4) ON CONFLICT (user_id) DO UPDATE SET "+
+		"action = EXCLUDED.action, payload = EXCLUDED.payload, expires = EXCLUDED.expires",
+		state.UserID, state.Action, state.Payload, state.Expires)
+	return err
+}
+
+func (storage postgresStorage) GetStateByUserID(userID int) (state models.State, err error) {
+	row := storage.db.QueryRow("SELECT user_id, action, payload, expires FROM states WHERE user_id = $1", userID)
+	err = row.Scan(&state.UserID, &state.Action, &state.Payload, &state.Expires)
+	if err == sql.ErrNoRows {
+		return models.State{UserID: userID, Action: ""}, nil
+	}
+	return state, err
+}
+
+func (storage postgresStorage) GetLastReportDate() time.Time {
+	return storage.lastEvent(eventReport)
+}
+
+func (storage postgresStorage) NewReportPosted() error {
+	_, err := storage.db.Exec("INSERT INTO events(type) VALUES($1)", eventReport)
+	return err
+}
+
+func (storage postgresStorage) GetLastRewardDate() time.Time {
+	return storage.lastEvent(eventReward)
+}
+
+func (storage postgresStorage) NewRewardDistributed() error {
+	_, err := storage.db.Exec("INSERT INTO events(type) VALUES($1)", eventReward)
+	return err
+}
+
+func (storage postgresStorage) lastEvent(kind string) (date time.Time) {
+	row := storage.db.QueryRow("SELECT date FROM events WHERE type = $1 ORDER BY date DESC LIMIT 1", kind)
+	row.Scan(&date)
+	return date
+}
+
+// record пишет изменение в журнал той же базы; before и after — значения, прочитанные из PostgreSQL
+func (storage postgresStorage) record(actor models.Actor, entity string, entityID string, before interface{}, after interface{}) error {
+	change, err := models.NewChange(actor, entity, entityID, before, after)
+	if err != nil || change.Before == change.After {
+		return err
+	}
+	_, err = storage.db.Exec("INSERT INTO audit(actor, entity, entity_id, old_value, new_value, date) "+
+		"VALUES($1, $2, $3, $4, $5, $6)",
+		change.Actor, change.Entity, change.EntityID, change.Before, change.After, change.Date)
+	return err
+}
+
+// inTransaction выполняет изменение и запись о нём в журнале одной транзакцией
+func (storage postgresStorage) inTransaction(do func(tx postgresStorage) error) error {
+	return models.Transaction(storage.db, func(tx models.Executor) error {
+		return do(postgresStorage{db: tx})
+	})
+}
+
+func (storage postgresStorage) count(query string, args ...interface{}) (count int) {
+	storage.db.QueryRow(query, args...).Scan(&count)
+	return count
+}
+
+func (storage postgresStorage) userIDs(query string, args ...interface{}) (userIDs []int, err error) {
+	rows, err := storage.db.Query(query, args...)
+	if err != nil {
+		return userIDs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		err = rows.Scan(&userID)
+		if err != nil {
+			return userIDs, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+const trustColumns = "user_id, score, approval_rate, addled_rate, plagiarism_count, closed_votes, account_created, date"
+
+func scanTrust(row scanner) (trust models.Trust, err error) {
+	err = row.Scan(&trust.UserID,
+		&trust.Score,
+		&trust.ApprovalRate,
+		&trust.AddledRate,
+		&trust.PlagiarismCount,
+		&trust.ClosedVotes,
+		&trust.AccountCreated,
+		&trust.Date)
+	return trust, err
+}
+
+func (storage postgresStorage) SaveTrust(trust models.Trust, actor models.Actor) (saved bool, err error) {
+	err = storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.trustValue(trust.UserID)
+		if stored, ok := before.(models.Trust); ok && !trust.Differs(stored) {
+			return nil
+		}
+		saved = true
+		_, err := tx.db.Exec("INSERT INTO trusts("+trustColumns+") VALUES($1, $2, $3, $4, $5, $6, $7, $8) "+
+			"ON CONFLICT (user_id) DO UPDATE SET score = EXCLUDED.score, approval_rate = EXCLUDED.approval_rate, "+
+			"addled_rate = EXCLUDED.addled_rate, plagiarism_count = EXCLUDED.plagiarism_count, "+
+			"closed_votes = EXCLUDED.closed_votes, account_created = EXCLUDED.account_created, date = EXCLUDED.date",
+			trust.UserID,
+			trust.Score,
+			trust.ApprovalRate,
+			trust.AddledRate,
+			trust.PlagiarismCount,
+			trust.ClosedVotes,
+			trust.AccountCreated,
+			trust.Date)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityTrust, strconv.Itoa(trust.UserID), before, tx.trustValue(trust.UserID))
+	})
+	return saved && err == nil, err
+}
+
+func (storage postgresStorage) trustValue(userID int) interface{} {
+	trust, err := scanTrust(storage.db.QueryRow("SELECT "+trustColumns+" FROM trusts WHERE user_id = $1", userID))
+	if err != nil {
+		return nil
+	}
+	return trust
+}
+
+func (storage postgresStorage) GetTrustByUserID(userID int) (models.Trust, error) {
+	trust, err := scanTrust(storage.db.QueryRow("SELECT "+trustColumns+" FROM trusts WHERE user_id = $1", userID))
+	if err == sql.ErrNoRows {
+		return models.ComputeTrust(userID, nil, time.Time{}, time.Now()), nil
+	}
+	return trust, err
+}
+
+const claimColumns = "user_id, chat_id, user_name, code, date"
+
+func scanClaim(row scanner) (claim models.Claim, err error) {
+	err = row.Scan(&claim.UserID, &claim.ChatID, &claim.UserName, &claim.Code, &claim.Date)
+	return claim, err
+}
+
+func (storage postgresStorage) SaveClaim(claim models.Claim, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.claimValue(claim.UserID)
+		_, err := tx.db.Exec("INSERT INTO claims("+claimColumns+") VALUES($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (user_id) DO UPDATE SET chat_id = EXCLUDED.chat_id, user_name = EXCLUDED.user_name, "+
+			"code = EXCLUDED.code, date = EXCLUDED.date",
+			claim.UserID, claim.ChatID, claim.UserName, claim.Code, claim.Date)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityClaim, strconv.Itoa(claim.UserID), before, tx.claimValue(claim.UserID))
+	})
+}
+
+func (storage postgresStorage) DeleteClaim(userID int, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.claimValue(userID)
+		_, err := tx.db.Exec("DELETE FROM claims WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityClaim, strconv.Itoa(userID), before, nil)
+	})
+}
+
+func (storage postgresStorage) claimValue(userID int) interface{} {
+	claim, err := storage.GetClaimByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return claim
+}
+
+func (storage postgresStorage) GetClaimByUserID(userID int) (models.Claim, error) {
+	return scanClaim(storage.db.QueryRow("SELECT "+claimColumns+" FROM claims WHERE user_id = $1", userID))
+}
+
+func (storage postgresStorage) GetRivalClaims(claim models.Claim) (claims []models.Claim, err error) {
+	rows, err := storage.db.Query("SELECT "+claimColumns+" FROM claims "+
+		"WHERE user_name = $1 AND user_id != $2 AND date > $3", claim.UserName, claim.UserID, time.Now().Add(-models.ClaimTTL))
+	if err != nil {
+		return claims, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rival, err := scanClaim(rows)
+		if err != nil {
+			return claims, err
+		}
+		claims = append(claims, rival)
+	}
+	return claims, rows.Err()
+}
+
+func (storage postgresStorage) PurgeExpiredClaims(now time.Time, actor models.Actor) (int, error) {
+	userIDs, err := storage.userIDs("SELECT user_id FROM claims WHERE date <= $1", now.Add(-models.ClaimTTL))
+	if err != nil {
+		return 0, err
+	}
+	for i, userID := range userIDs {
+		err = storage.DeleteClaim(userID, actor)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(userIDs), nil
+}
+
+func (storage postgresStorage) SaveBan(ban models.Ban, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.banValue(ban.Kind, ban.Target)
+		_, err := tx.db.Exec("INSERT INTO bans(kind, target, admin_id, date) VALUES($1, $2, $3, $4) "+
+			"ON CONFLICT (kind, target) DO UPDATE SET admin_id = EXCLUDED.admin_id, date = EXCLUDED.date",
+			ban.Kind, ban.Target, ban.AdminID, ban.Date)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityBan, ban.Kind+":"+ban.Target, before, tx.banValue(ban.Kind, ban.Target))
+	})
+}
+
+func (storage postgresStorage) DeleteBan(kind string, target string, actor models.Actor) (deleted bool, err error) {
+	err = storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.banValue(kind, target)
+		result, err := tx.db.Exec("DELETE FROM bans WHERE kind = $1 AND target = $2", kind, target)
+		if err != nil {
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil || count == 0 {
+			return err
+		}
+		deleted = true
+		return tx.record(actor, models.EntityBan, kind+":"+target, before, nil)
+	})
+	return deleted && err == nil, err
+}
+
+func (storage postgresStorage) banValue(kind string, target string) interface{} {
+	row := storage.db.QueryRow("SELECT kind, target, admin_id, date FROM bans WHERE kind = $1 AND target = $2", kind, target)
+	var ban models.Ban
+	err := row.Scan(&ban.Kind, &ban.Target, &ban.AdminID, &ban.Date)
+	if err != nil {
+		return nil
+	}
+	return ban
+}
+
+func (storage postgresStorage) IsBanned(kind string, target string) bool {
+	return storage.count("SELECT COUNT(*) FROM bans WHERE kind = $1 AND target = $2", kind, target) > 0
+}
+
+func (storage postgresStorage) SaveAdminAction(action models.AdminAction) error {
+	_, err := storage.db.Exec("INSERT INTO admin_actions(admin_id, command, arguments, result, date) "+
+		"VALUES($1, $2, $3, $4, $5)",
+		action.AdminID, action.Command, action.Arguments, action.Result, action.Date)
+	return err
+}
+
+func (storage postgresStorage) GetLastAdminActions(limit int) (actions []models.AdminAction, err error) {
+	rows, err := storage.db.Query("SELECT id, admin_id, command, arguments, result, date FROM admin_actions "+
+		"ORDER BY id DESC LIMIT $1", limit)
+	if err != nil {
+		return actions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var action models.AdminAction
+		err = rows.Scan(&action.ID, &action.AdminID, &action.Command, &action.Arguments, &action.Result, &action.Date)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
+
+func (storage postgresStorage) SaveLanguage(language models.Language, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.languageValue(language.UserID)
+		_, err := tx.db.Exec("INSERT INTO languages(user_id, code, manual) VALUES($1, $2, $3) "+
+			"ON CONFLICT (user_id) DO UPDATE SET code = EXCLUDED.code, manual = EXCLUDED.manual",
+			language.UserID, language.Code, language.Manual)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityLanguage, strconv.Itoa(language.UserID), before, tx.languageValue(language.UserID))
+	})
+}
+
+func (storage postgresStorage) languageValue(userID int) interface{} {
+	row := storage.db.QueryRow("SELECT user_id, code, manual FROM languages WHERE user_id = $1", userID)
+	var language models.Language
+	err := row.Scan(&language.UserID, &language.Code, &language.Manual)
+	if err != nil {
+		return nil
+	}
+	return language
+}
+
+func (storage postgresStorage) GetLanguageByUserID(userID int, defaultCode string) (language models.Language, err error) {
+	row := storage.db.QueryRow("SELECT user_id, code, manual FROM languages WHERE user_id = $1", userID)
+	err = row.Scan(&language.UserID, &language.Code, &language.Manual)
+	if err == sql.ErrNoRows {
+		return models.Language{UserID: userID, Code: defaultCode}, nil
+	}
+	return language, err
+}
+
+const memberColumns = "user_id, verified, since, attempts, retry_at"
+
+func scanMember(row scanner) (member models.Member, err error) {
+	err = row.Scan(&member.UserID, &member.Verified, &member.Since, &member.Attempts, &member.RetryAt)
+	return member, err
+}
+
+func (storage postgresStorage) SaveMember(member models.Member, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.memberValue(member.UserID)
+		_, err := tx.db.Exec("INSERT INTO members("+memberColumns+") VALUES($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (user_id) DO UPDATE SET verified = EXCLUDED.verified, since = EXCLUDED.since, "+
+			"attempts = EXCLUDED.attempts, retry_at = EXCLUDED.retry_at",
+			member.UserID, member.Verified, member.Since, member.Attempts, member.RetryAt)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityMember, strconv.Itoa(member.UserID), before, tx.memberValue(member.UserID))
+	})
+}
+
+func (storage postgresStorage) memberValue(userID int) interface{} {
+	member, err := storage.GetMemberByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return member
+}
+
+func (storage postgresStorage) GetMemberByUserID(userID int) (models.Member, error) {
+	return scanMember(storage.db.QueryRow("SELECT "+memberColumns+" FROM members WHERE user_id = $1", userID))
+}
+
+func (storage postgresStorage) GetAllMembers() (members []models.Member, err error) {
+	rows, err := storage.db.Query("SELECT " + memberColumns + " FROM members")
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return members, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (storage postgresStorage) DeleteMember(userID int, actor models.Actor) error {
+	return storage.inTransaction(func(tx postgresStorage) error {
+		before := tx.memberValue(userID)
+		_, err := tx.db.Exec("DELETE FROM members WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+		return tx.record(actor, models.EntityMember, strconv.Itoa(userID), before, nil)
+	})
+}
+
+func (storage postgresStorage) SaveRemoval(removal models.Removal) error {
+	_, err := storage.db.Exec("INSERT INTO removals(user_id, reason, date) VALUES($1, $2, $3)",
+		removal.UserID, removal.Reason, removal.Date)
+	return err
+}
+
+func (storage postgresStorage) GetLastRemovals(limit int) (removals []models.Removal, err error) {
+	rows, err := storage.db.Query("SELECT user_id, reason, date FROM removals ORDER BY id DESC LIMIT $1", limit)
+	if err != nil {
+		return removals, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var removal models.Removal
+		err = rows.Scan(&removal.UserID, &removal.Reason, &removal.Date)
+		if err != nil {
+			return removals, err
+		}
+		removals = append(removals, removal)
+	}
+	return removals, rows.Err()
+}
+
+func (storage postgresStorage) AddOutboxMessage(message models.OutboxMessage) (id int64, err error) {
+	err = storage.db.QueryRow("INSERT INTO outbox(chat_id, config, date) VALUES($1, $2, $3) RETURNING id",
+		message.ChatID, message.Config, message.Date).Scan(&id)
+	return id, err
+}
+
+func (storage postgresStorage) DeleteOutboxMessage(id int64) error {
+	_, err := storage.db.Exec("DELETE FROM outbox WHERE id = $1", id)
+	return err
+}
+
+func (storage postgresStorage) RetryOutboxMessage(id int64, attempts int, retryAt time.Time, lastError string) error {
+	_, err := storage.db.Exec("UPDATE outbox SET attempts = $1, retry_at = $2, error = $3 WHERE id = $4",
+		attempts, retryAt, lastError, id)
+	return err
+}
+
+func (storage postgresStorage) BuryOutboxMessage(id int64, attempts int, lastError string) error {
+	_, err := storage.db.Exec("UPDATE outbox SET dead = TRUE, attempts = $1, error = $2 WHERE id = $3",
+		attempts, lastError, id)
+	return err
+}
+
+func (storage postgresStorage) GetOutboxMessages() (messages []models.OutboxMessage, err error) {
+	rows, err := storage.db.Query("SELECT id, chat_id, config, date, attempts, retry_at, dead, error FROM outbox " +
+		"WHERE NOT dead ORDER BY id")
+	if err != nil {
+		return messages, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var message models.OutboxMessage
+		err = rows.Scan(&message.ID, &message.ChatID, &message.Config, &message.Date,
+			&message.Attempts, &message.RetryAt, &message.Dead, &message.Error)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (storage postgresStorage) SaveCursor(cursor models.Cursor) error {
+	_, err := storage.db.Exec("INSERT INTO cursors(name, block_num, date) VALUES($1, $2, $3) "+
+		"ON CONFLICT (name) DO UPDATE SET block_num = EXCLUDED.block_num, date = EXCLUDED.date",
+		cursor.Name, int64(cursor.BlockNum), time.Now())
+	return err
+}
+
+func (storage postgresStorage) GetCursor(name string) (cursor models.Cursor, err error) {
+	row := storage.db.QueryRow("SELECT name, block_num FROM cursors WHERE name = $1", name)
+	err = row.Scan(&cursor.Name, &cursor.BlockNum)
+	if err == sql.ErrNoRows {
+		return models.Cursor{Name: name}, nil
+	}
+	return cursor, err
+}
+
+func (storage postgresStorage) GetChanges(filter models.ChangeFilter, limit int) (changes []models.Change, err error) {
+	query := "SELECT id, actor, entity, entity_id, old_value, new_value, date FROM audit WHERE date >= $1"
+	args := []interface{}{filter.Since}
+	where := func(column string, value string) {
+		if len(value) > 0 {
+			args = append(args, value)
+			query += " AND " + column + " = $" + strconv.Itoa(len(args))
+		}
+	}
+	where("actor", filter.Actor)
+	where("entity", filter.Entity)
+	where("entity_id", filter.EntityID)
+	args = append(args, limit)
+	rows, err := storage.db.Query(query+" ORDER BY id DESC LIMIT $"+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return changes, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change models.Change
+		err = rows.Scan(&change.ID,
+			&change.Actor,
+			&change.Entity,
+			&change.EntityID,
+			&change.Before,
+			&change.After,
+			&change.Date)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+)
+
+// Миграции схемы PostgreSQL устроены так же, как миграции SQLite в пакете db:
+// <номер>_<название>.up.sql и .down.sql, применённую миграцию менять нельзя
+//
+//go:embed migrations/*.sql
+var postgresMigrationFiles embed.FS
+
+// PostgresMigrations возвращает встроенные миграции схемы PostgreSQL по возрастанию номера
+func PostgresMigrations() ([]db.Migration, error) {
+	return db.LoadMigrations(postgresMigrationFiles, "migrations")
+}
+
+// migratePostgres применяет недостающие миграции, каждую в своей транзакции.
+// Если применённая миграция изменилась, не применяет ничего
+func migratePostgres(database *sql.DB) error {
+	migrations, err := PostgresMigrations()
+	if err != nil {
+		return err
+	}
+	_, err = database.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY NOT NULL,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	applied := make(map[int]string)
+	rows, err := database.Query("SELECT version, checksum FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var checksum string
+		err = rows.Scan(&version, &checksum)
+		if err != nil {
+			return err
+		}
+		applied[version] = checksum
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(applied) > len(migrations) {
+		return fmt.Errorf("в базе PostgreSQL %d миграций, а известно только %d", len(applied), len(migrations))
+	}
+	for _, migration := range migrations {
+		if checksum, ok := applied[migration.Version]; ok && checksum != migration.Checksum {
+			return fmt.Errorf("миграция PostgreSQL %d_%s изменилась после применения", migration.Version, migration.Name)
+		}
+	}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = applyPostgresMigration(database, migration)
+		if err != nil {
+			return fmt.Errorf("миграция PostgreSQL %d_%s: %s", migration.Version, migration.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+func applyPostgresMigration(database *sql.DB, migration db.Migration) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(migration.Up)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES($1, $2, $3, $4)",
+		migration.Version, migration.Name, migration.Checksum, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,377 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+// sqliteStorage хранит данные в таблицах SQLite, созданных миграциями из пакета db
+type sqliteStorage struct {
+	db models.Executor
+}
+
+// NewSQLite создаёт хранилище поверх базы, открытой db.InitDB
+func NewSQLite(db *sql.DB) Storage {
+	return sqliteStorage{db: db}
+}
+
+func (storage sqliteStorage) Transaction(do func(store Storage) error) error {
+	return models.Transaction(storage.db, func(tx models.Executor) error {
+		return do(sqliteStorage{db: tx})
+	})
+}
+
+func (storage sqliteStorage) Close() error {
+	if database, ok := storage.db.(*sql.DB); ok {
+		return database.Close()
+	}
+	return nil
+}
+
+func (storage sqliteStorage) SaveCredential(credential models.Credential, actor models.Actor) error {
+	_, err := credential.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetCredentialByUserID(userID int) (models.Credential, error) {
+	return models.GetCredentialByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetCredentialsByUserID(userID int) ([]models.Credential, error) {
+	return models.GetCredentialsByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetActiveCredentialsByUserID(userID int) ([]models.Credential, error) {
+	return models.GetActiveCredentialsByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetCredentialByUserName(userName string) (models.Credential, error) {
+	return models.GetCredentialByUserName(userName, storage.db)
+}
+
+func (storage sqliteStorage) GetAllActiveCredentials() ([]models.Credential, error) {
+	return models.GetAllActiveCredentials(storage.db)
+}
+
+func (storage sqliteStorage) GetAllCredentials() ([]models.Credential, error) {
+	return models.GetAllCredentials(storage.db)
+}
+
+func (storage sqliteStorage) UpdatePower(userName string, power int, actor models.Actor) error {
+	return models.Credential{UserName: userName}.UpdatePower(power, actor, storage.db)
+}
+
+func (storage sqliteStorage) IsActiveCredential(userID int) bool {
+	return models.IsActiveCredential(userID, storage.db)
+}
+
+func (storage sqliteStorage) ActivateCurator(userID int, actor models.Actor) error {
+	return models.ActivateCurator(userID, actor, storage.db)
+}
+
+func (storage sqliteStorage) DeactivateCurator(userID int, actor models.Actor) error {
+	return models.DeactivateCurator(userID, actor, storage.db)
+}
+
+func (storage sqliteStorage) IsActiveCurator(userID int) bool {
+	return models.IsActiveCurator(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetAllActiveCuratorsChatID() ([]int64, error) {
+	return models.GetAllActiveCurstorsChatID(storage.db)
+}
+
+func (storage sqliteStorage) GetAllActiveCuratorsID() ([]int, error) {
+	return models.GetAllActiveCurstorsID(storage.db)
+}
+
+func (storage sqliteStorage) GetAllChatIDs() ([]int64, error) {
+	return models.GetAllChatIDs(storage.db)
+}
+
+func (storage sqliteStorage) SaveVote(vote models.Vote, actor models.Actor) (int64, error) {
+	return vote.Save(actor, storage.db)
+}
+
+func (storage sqliteStorage) GetVote(voteID int64) models.Vote {
+	return models.GetVote(storage.db, voteID)
+}
+
+func (storage sqliteStorage) VoteExists(vote models.Vote) bool {
+	return vote.Exists(storage.db)
+}
+
+func (storage sqliteStorage) GetOpenedVotesCount() int {
+	return models.GetOpenedVotesCount(storage.db)
+}
+
+func (storage sqliteStorage) GetOpenedVotesCountForUserID(userID int) int {
+	return models.GetOpenedVotesCountForUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetLastVotesForUserID(userID int, num int) ([]models.Vote, error) {
+	return models.GetLastVotesForUserID(userID, num, storage.db)
+}
+
+func (storage sqliteStorage) GetVotesPageForUserID(userID int, limit int, offset int) ([]models.Vote, error) {
+	return models.GetVotesPageForUserID(userID, limit, offset, storage.db)
+}
+
+func (storage sqliteStorage) GetVotesCountForUserID(userID int) int {
+	return models.GetVotesCountForUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetLastVoteForUserID(userID int) models.Vote {
+	return models.GetLastVoteForUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetAllOpenedVotes() ([]models.Vote, error) {
+	return models.GetAllOpenedVotes(storage.db)
+}
+
+func (storage sqliteStorage) GetTrulyCompletedVotesSince(date time.Time) ([]models.Vote, error) {
+	return models.GetTrulyCompletedVotesSince(date, storage.db)
+}
+
+func (storage sqliteStorage) GetVotesBetween(since time.Time, until time.Time) ([]models.Vote, error) {
+	return models.GetVotesBetween(since, until, storage.db)
+}
+
+func (storage sqliteStorage) SaveVoteTags(voteID int64, tags []string) error {
+	return models.SaveVoteTags(voteID, tags, storage.db)
+}
+
+func (storage sqliteStorage) GetVoteTags(voteID int64) ([]string, error) {
+	return models.GetVoteTags(voteID, storage.db)
+}
+
+func (storage sqliteStorage) GetOpenedVotesCountForTag(tag string) int {
+	return models.GetOpenedVotesCountForTag(tag, storage.db)
+}
+
+func (storage sqliteStorage) SaveCast(cast models.Cast) error {
+	_, err := cast.Save(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetCastsCountForVoteID(voteID int64) int {
+	return models.GetCastsCountForVoteID(voteID, storage.db)
+}
+
+func (storage sqliteStorage) GetCastsByUserNameSince(userName string, date time.Time) ([]models.Cast, error) {
+	return models.GetCastsByUserNameSince(userName, date, storage.db)
+}
+
+func (storage sqliteStorage) SaveResponse(response models.Response, actor models.Actor) error {
+	_, err := response.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) ResponseExists(response models.Response) bool {
+	return response.Exists(storage.db)
+}
+
+func (storage sqliteStorage) GetAllResponsesForVoteID(voteID int64) ([]models.Response, error) {
+	return models.GetAllResponsesForVoteID(voteID, storage.db)
+}
+
+func (storage sqliteStorage) GetResponsesBetween(since time.Time, until time.Time) ([]models.Response, error) {
+	return models.GetResponsesBetween(since, until, storage.db)
+}
+
+func (storage sqliteStorage) GetNumResponsesVoteID(voteID int64) (int, int) {
+	return models.GetNumResponsesVoteID(voteID, storage.db)
+}
+
+func (storage sqliteStorage) GetNumResponsesForMotivation(date time.Time) int {
+	return models.GetNumResponsesForMotivation(date, storage.db)
+}
+
+func (storage sqliteStorage) GetUserIDsForMotivation(date time.Time) ([]int, error) {
+	return models.GetUserIDsForMotivation(date, storage.db)
+}
+
+func (storage sqliteStorage) GetNumResponsesForMotivationForUserID(userID int, date time.Time) int {
+	return models.GetNumResponsesForMotivationForUserID(userID, date, storage.db)
+}
+
+func (storage sqliteStorage) SaveReferral(referral models.Referral, actor models.Actor) error {
+	_, err := referral.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) SetReferralCompleted(userID int, actor models.Actor) error {
+	return models.Referral{UserID: userID}.SetCompleted(actor, storage.db)
+}
+
+func (storage sqliteStorage) GetReferralByUserID(userID int) (models.Referral, error) {
+	return models.GetReferralByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetPendingReferrals() ([]models.Referral, error) {
+	return models.GetPendingReferrals(storage.db)
+}
+
+func (storage sqliteStorage) GetAllReferrals() ([]models.Referral, error) {
+	return models.GetAllReferrals(storage.db)
+}
+
+func (storage sqliteStorage) GetPaidReferralsCountSince(referrer string, date time.Time) int {
+	return models.GetPaidReferralsCountSince(referrer, date, storage.db)
+}
+
+func (storage sqliteStorage) GetReferralStats(referrer string) ([]models.ReferralStats, error) {
+	return models.GetReferralStats(referrer, storage.db)
+}
+
+func (storage sqliteStorage) IsReferralExists(referral string) bool {
+	return models.IsReferralExists(referral, storage.db)
+}
+
+func (storage sqliteStorage) SaveState(state models.State) error {
+	_, err := state.Save(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetStateByUserID(userID int) (models.State, error) {
+	return models.GetStateByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetLastReportDate() time.Time {
+	return models.GetLastReportDate(storage.db)
+}
+
+func (storage sqliteStorage) NewReportPosted() error {
+	_, err := models.NewReportPosted(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetLastRewardDate() time.Time {
+	return models.GetLastRewardDate(storage.db)
+}
+
+func (storage sqliteStorage) NewRewardDistributed() error {
+	_, err := models.NewRewardDistributed(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) SaveTrust(trust models.Trust, actor models.Actor) (bool, error) {
+	return trust.Save(actor, storage.db)
+}
+
+func (storage sqliteStorage) GetTrustByUserID(userID int) (models.Trust, error) {
+	return models.GetTrustByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) SaveClaim(claim models.Claim, actor models.Actor) error {
+	_, err := claim.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) DeleteClaim(userID int, actor models.Actor) error {
+	return models.Claim{UserID: userID}.Delete(actor, storage.db)
+}
+
+func (storage sqliteStorage) GetClaimByUserID(userID int) (models.Claim, error) {
+	return models.GetClaimByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetRivalClaims(claim models.Claim) ([]models.Claim, error) {
+	return models.GetRivalClaims(claim, storage.db)
+}
+
+func (storage sqliteStorage) PurgeExpiredClaims(now time.Time, actor models.Actor) (int, error) {
+	return models.PurgeExpiredClaims(now, actor, storage.db)
+}
+
+func (storage sqliteStorage) SaveBan(ban models.Ban, actor models.Actor) error {
+	_, err := ban.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) DeleteBan(kind string, target string, actor models.Actor) (bool, error) {
+	return models.DeleteBan(kind, target, actor, storage.db)
+}
+
+func (storage sqliteStorage) IsBanned(kind string, target string) bool {
+	return models.IsBanned(kind, target, storage.db)
+}
+
+func (storage sqliteStorage) SaveAdminAction(action models.AdminAction) error {
+	_, err := action.Save(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetLastAdminActions(limit int) ([]models.AdminAction, error) {
+	return models.GetLastAdminActions(limit, storage.db)
+}
+
+func (storage sqliteStorage) SaveLanguage(language models.Language, actor models.Actor) error {
+	_, err := language.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetLanguageByUserID(userID int, defaultCode string) (models.Language, error) {
+	return models.GetLanguageByUserID(userID, defaultCode, storage.db)
+}
+
+func (storage sqliteStorage) SaveMember(member models.Member, actor models.Actor) error {
+	_, err := member.Save(actor, storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetMemberByUserID(userID int) (models.Member, error) {
+	return models.GetMemberByUserID(userID, storage.db)
+}
+
+func (storage sqliteStorage) GetAllMembers() ([]models.Member, error) {
+	return models.GetAllMembers(storage.db)
+}
+
+func (storage sqliteStorage) DeleteMember(userID int, actor models.Actor) error {
+	return models.DeleteMember(userID, actor, storage.db)
+}
+
+func (storage sqliteStorage) SaveRemoval(removal models.Removal) error {
+	_, err := removal.Save(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetLastRemovals(limit int) ([]models.Removal, error) {
+	return models.GetLastRemovals(limit, storage.db)
+}
+
+func (storage sqliteStorage) AddOutboxMessage(message models.OutboxMessage) (int64, error) {
+	return message.Save(storage.db)
+}
+
+func (storage sqliteStorage) DeleteOutboxMessage(id int64) error {
+	return models.DeleteOutboxMessage(id, storage.db)
+}
+
+func (storage sqliteStorage) RetryOutboxMessage(id int64, attempts int, retryAt time.Time, lastError string) error {
+	return models.RetryOutboxMessage(id, attempts, retryAt, lastError, storage.db)
+}
+
+func (storage sqliteStorage) BuryOutboxMessage(id int64, attempts int, lastError string) error {
+	return models.BuryOutboxMessage(id, attempts, lastError, storage.db)
+}
+
+func (storage sqliteStorage) GetOutboxMessages() ([]models.OutboxMessage, error) {
+	return models.GetOutboxMessages(storage.db)
+}
+
+func (storage sqliteStorage) SaveCursor(cursor models.Cursor) error {
+	_, err := cursor.Save(storage.db)
+	return err
+}
+
+func (storage sqliteStorage) GetCursor(name string) (models.Cursor, error) {
+	return models.GetCursor(name, storage.db)
+}
+
+func (storage sqliteStorage) GetChanges(filter models.ChangeFilter, limit int) ([]models.Change, error) {
+	return models.GetChanges(filter, limit, storage.db)
+}
@@ -0,0 +1,214 @@
+// Package storage отделяет бота от конкретной базы данных: все его таблицы — аккаунты, голосования,
+// ответы кураторов, рефералы, доверие, заявки, баны, участники группы, очередь сообщений,
+// журнал изменений и остальное — хранятся в SQLite или PostgreSQL
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+// Драйверы, которые можно указать в database_driver
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// Storage — всё, что бот хранит о пользователях и голосованиях. Методы, меняющие сущности
+// из журнала изменений, принимают участника для него
+type Storage interface {
+	Credentials
+	Votes
+	Casts
+	Responses
+	Referrals
+	States
+	Events
+	Trusts
+	Claims
+	Bans
+	AdminActions
+	Languages
+	Members
+	Outbox
+	Cursors
+	Changes
+	// Transaction выполняет do над хранилищем внутри одной транзакции: если do вернёт ошибку,
+	// ни одно из сделанных в нём изменений не сохранится
+	Transaction(do func(store Storage) error) error
+	Close() error
+}
+
+// Credentials — аккаунты Голоса, доверившие боту свою Силу Голоса
+type Credentials interface {
+	SaveCredential(credential models.Credential, actor models.Actor) error
+	GetCredentialByUserID(userID int) (models.Credential, error)
+	GetCredentialsByUserID(userID int) ([]models.Credential, error)
+	GetActiveCredentialsByUserID(userID int) ([]models.Credential, error)
+	GetCredentialByUserName(userName string) (models.Credential, error)
+	GetAllActiveCredentials() ([]models.Credential, error)
+	GetAllCredentials() ([]models.Credential, error)
+	UpdatePower(userName string, power int, actor models.Actor) error
+	IsActiveCredential(userID int) bool
+	ActivateCurator(userID int, actor models.Actor) error
+	DeactivateCurator(userID int, actor models.Actor) error
+	IsActiveCurator(userID int) bool
+	GetAllActiveCuratorsChatID() ([]int64, error)
+	GetAllActiveCuratorsID() ([]int, error)
+	GetAllChatIDs() ([]int64, error)
+}
+
+// Votes — предложенные посты и их теги
+type Votes interface {
+	SaveVote(vote models.Vote, actor models.Actor) (int64, error)
+	GetVote(voteID int64) models.Vote
+	VoteExists(vote models.Vote) bool
+	GetOpenedVotesCount() int
+	GetOpenedVotesCountForUserID(userID int) int
+	GetLastVotesForUserID(userID int, num int) ([]models.Vote, error)
+	GetVotesPageForUserID(userID int, limit int, offset int) ([]models.Vote, error)
+	GetVotesCountForUserID(userID int) int
+	GetLastVoteForUserID(userID int) models.Vote
+	GetAllOpenedVotes() ([]models.Vote, error)
+	GetTrulyCompletedVotesSince(date time.Time) ([]models.Vote, error)
+	GetVotesBetween(since time.Time, until time.Time) ([]models.Vote, error)
+	SaveVoteTags(voteID int64, tags []string) error
+	GetVoteTags(voteID int64) ([]string, error)
+	GetOpenedVotesCountForTag(tag string) int
+}
+
+// Casts — голоса, отданные ботом от имени аккаунтов. Они только дописываются и сами служат историей,
+// поэтому в журнал изменений не попадают
+type Casts interface {
+	SaveCast(cast models.Cast) error
+	GetCastsCountForVoteID(voteID int64) int
+	GetCastsByUserNameSince(userName string, date time.Time) ([]models.Cast, error)
+}
+
+// Responses — оценки кураторов
+type Responses interface {
+	SaveResponse(response models.Response, actor models.Actor) error
+	ResponseExists(response models.Response) bool
+	GetAllResponsesForVoteID(voteID int64) ([]models.Response, error)
+	GetResponsesBetween(since time.Time, until time.Time) ([]models.Response, error)
+	GetNumResponsesVoteID(voteID int64) (int, int)
+	GetNumResponsesForMotivation(date time.Time) int
+	GetUserIDsForMotivation(date time.Time) ([]int, error)
+	GetNumResponsesForMotivationForUserID(userID int, date time.Time) int
+}
+
+// Referrals — участники партнёрской программы
+type Referrals interface {
+	SaveReferral(referral models.Referral, actor models.Actor) error
+	SetReferralCompleted(userID int, actor models.Actor) error
+	GetReferralByUserID(userID int) (models.Referral, error)
+	GetPendingReferrals() ([]models.Referral, error)
+	GetAllReferrals() ([]models.Referral, error)
+	GetPaidReferralsCountSince(referrer string, date time.Time) int
+	GetReferralStats(referrer string) ([]models.ReferralStats, error)
+	IsReferralExists(referral string) bool
+}
+
+// States — незаконченные диалоги с пользователями
+type States interface {
+	SaveState(state models.State) error
+	GetStateByUserID(userID int) (models.State, error)
+}
+
+// Events — даты еженедельного отчёта и распределения наград
+type Events interface {
+	GetLastReportDate() time.Time
+	NewReportPosted() error
+	GetLastRewardDate() time.Time
+	NewRewardDistributed() error
+}
+
+// Trusts — доверие к предлагающим посты пользователям
+type Trusts interface {
+	// SaveTrust сохраняет доверие и возвращает true, если оно отличалось от сохранённого
+	SaveTrust(trust models.Trust, actor models.Actor) (bool, error)
+	GetTrustByUserID(userID int) (models.Trust, error)
+}
+
+// Claims — заявки на аккаунты Голоса, ожидающие подтверждения владения
+type Claims interface {
+	SaveClaim(claim models.Claim, actor models.Actor) error
+	DeleteClaim(userID int, actor models.Actor) error
+	GetClaimByUserID(userID int) (models.Claim, error)
+	GetRivalClaims(claim models.Claim) ([]models.Claim, error)
+	PurgeExpiredClaims(now time.Time, actor models.Actor) (int, error)
+}
+
+// Bans — баны пользователей и авторов
+type Bans interface {
+	SaveBan(ban models.Ban, actor models.Actor) error
+	// DeleteBan снимает бан и сообщает, был ли он
+	DeleteBan(kind string, target string, actor models.Actor) (bool, error)
+	IsBanned(kind string, target string) bool
+}
+
+// AdminActions — журнал команд администраторов
+type AdminActions interface {
+	SaveAdminAction(action models.AdminAction) error
+	GetLastAdminActions(limit int) ([]models.AdminAction, error)
+}
+
+// Languages — выбранные пользователями языки
+type Languages interface {
+	SaveLanguage(language models.Language, actor models.Actor) error
+	GetLanguageByUserID(userID int, defaultCode string) (models.Language, error)
+}
+
+// Members — участники группы кураторов и удаления из неё
+type Members interface {
+	SaveMember(member models.Member, actor models.Actor) error
+	GetMemberByUserID(userID int) (models.Member, error)
+	GetAllMembers() ([]models.Member, error)
+	DeleteMember(userID int, actor models.Actor) error
+	SaveRemoval(removal models.Removal) error
+	GetLastRemovals(limit int) ([]models.Removal, error)
+}
+
+// Outbox — сообщения, которые ещё не удалось отправить в Telegram
+type Outbox interface {
+	AddOutboxMessage(message models.OutboxMessage) (int64, error)
+	DeleteOutboxMessage(id int64) error
+	RetryOutboxMessage(id int64, attempts int, retryAt time.Time, lastError string) error
+	BuryOutboxMessage(id int64, attempts int, lastError string) error
+	GetOutboxMessages() ([]models.OutboxMessage, error)
+}
+
+// Cursors — последние обработанные блоки
+type Cursors interface {
+	SaveCursor(cursor models.Cursor) error
+	GetCursor(name string) (models.Cursor, error)
+}
+
+// Changes — журнал изменений. Пишут в него сами методы, меняющие данные
+type Changes interface {
+	GetChanges(filter models.ChangeFilter, limit int) ([]models.Change, error)
+}
+
+// Open выбирает хранилище по драйверу из настроек. Для SQLite используется уже открытая база,
+// PostgreSQL открывается по url
+func Open(driver string, url string, sqlite *sql.DB) (Storage, error) {
+	switch driver {
+	case "", DriverSQLite:
+		return NewSQLite(sqlite), nil
+	case DriverPostgres:
+		postgres, err := sql.Open(DriverPostgres, url)
+		if err != nil {
+			return nil, err
+		}
+		store, err := NewPostgres(postgres)
+		if err != nil {
+			postgres.Close()
+			return nil, err
+		}
+		return store, nil
+	}
+	return nil, errors.New("неизвестный драйвер базы данных: " + driver)
+}
@@ -0,0 +1,592 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GolosTools/golos-vote-bot/db"
+	"github.com/GolosTools/golos-vote-bot/models"
+)
+
+func TestSQLite(t *testing.T) {
+	database, err := db.InitDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	testStorage(t, NewSQLite(database))
+}
+
+// TestPostgres прогоняет те же проверки на пустой базе из POSTGRES_TEST_URL.
+// Без адреса тест пропускается
+func TestPostgres(t *testing.T) {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if len(url) == 0 {
+		t.Skip("POSTGRES_TEST_URL не задан")
+	}
+	store, err := Open(DriverPostgres, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	_, err = store.(postgresStorage).db.Exec("TRUNCATE credentials, votes, vote_tags, casts, responses, referrals, " +
+		"states, events, audit, trusts, claims, bans, admin_actions, languages, members, removals, outbox, cursors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, store)
+}
+
+var testActor = models.SystemActor("test")
+
+// testStorage — общие проверки, которые должно проходить любое хранилище
+func testStorage(t *testing.T, store Storage) {
+	t.Run("Credentials", func(t *testing.T) { testCredentials(t, store) })
+	t.Run("Votes", func(t *testing.T) { testVotes(t, store) })
+	t.Run("Casts", func(t *testing.T) { testCasts(t, store) })
+	t.Run("Responses", func(t *testing.T) { testResponses(t, store) })
+	t.Run("Referrals", func(t *testing.T) { testReferrals(t, store) })
+	t.Run("States", func(t *testing.T) { testStates(t, store) })
+	t.Run("Events", func(t *testing.T) { testEvents(t, store) })
+	t.Run("Trusts", func(t *testing.T) { testTrusts(t, store) })
+	t.Run("Claims", func(t *testing.T) { testClaims(t, store) })
+	t.Run("Bans", func(t *testing.T) { testBans(t, store) })
+	t.Run("Languages", func(t *testing.T) { testLanguages(t, store) })
+	t.Run("Members", func(t *testing.T) { testMembers(t, store) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, store) })
+	t.Run("Cursors", func(t *testing.T) { testCursors(t, store) })
+	t.Run("Transaction", func(t *testing.T) { testTransaction(t, store) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, store) })
+}
+
+func testTransaction(t *testing.T, store Storage) {
+	failed := errors.New("откат")
+	err := store.Transaction(func(tx Storage) error {
+		err := tx.SaveCredential(models.Credential{UserID: 9, UserName: "rollback", Active: true}, testActor)
+		if err != nil {
+			return err
+		}
+		if !tx.IsActiveCredential(9) {
+			t.Error("транзакция должна видеть свои изменения")
+		}
+		return failed
+	})
+	if err != failed {
+		t.Errorf("транзакция вернула %v", err)
+	}
+	if store.IsActiveCredential(9) {
+		t.Error("изменения отменённой транзакции не должны сохраняться")
+	}
+	err = store.Transaction(func(tx Storage) error {
+		return tx.SaveCredential(models.Credential{UserID: 9, UserName: "commit", Active: true}, testActor)
+	})
+	if err != nil || !store.IsActiveCredential(9) {
+		t.Errorf("изменения транзакции не сохранены, ошибка %v", err)
+	}
+}
+
+func testCredentials(t *testing.T, store Storage) {
+	credentials := []models.Credential{
+		{UserID: 1, ChatID: 10, UserName: "chiliec", Power: 100, Active: true, Curates: true},
+		{UserID: 1, ChatID: 10, UserName: "chiliec-test", Power: 50, Active: false},
+		{UserID: 2, ChatID: 20, UserName: "babin", Power: 100, Active: true},
+	}
+	for _, credential := range credentials {
+		err := store.SaveCredential(credential, testActor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	credential, err := store.GetCredentialByUserID(1)
+	if err != nil || credential != credentials[0] {
+		t.Errorf("основной аккаунт %#v, ошибка %v", credential, err)
+	}
+	all, err := store.GetCredentialsByUserID(1)
+	if err != nil || len(all) != 2 {
+		t.Errorf("%d аккаунтов вместо 2, ошибка %v", len(all), err)
+	}
+	active, err := store.GetActiveCredentialsByUserID(1)
+	if err != nil || len(active) != 1 || active[0].UserName != "chiliec" {
+		t.Errorf("неожиданные активные аккаунты %#v, ошибка %v", active, err)
+	}
+	credential, err = store.GetCredentialByUserName("babin")
+	if err != nil || credential != credentials[2] {
+		t.Errorf("аккаунт по логину %#v, ошибка %v", credential, err)
+	}
+	if _, err = store.GetCredentialByUserName("nobody"); err == nil {
+		t.Error("несуществующий аккаунт должен возвращать ошибку")
+	}
+	all, err = store.GetAllActiveCredentials()
+	if err != nil || len(all) != 2 {
+		t.Errorf("%d активных аккаунтов вместо 2, ошибка %v", len(all), err)
+	}
+	all, err = store.GetAllCredentials()
+	if err != nil || len(all) != 3 {
+		t.Errorf("%d аккаунтов вместо 3, ошибка %v", len(all), err)
+	}
+	err = store.UpdatePower("babin", 30, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential, _ = store.GetCredentialByUserName("babin"); credential.Power != 30 {
+		t.Errorf("сила %d вместо 30", credential.Power)
+	}
+	if !store.IsActiveCredential(1) || store.IsActiveCredential(3) {
+		t.Error("неверно определили наличие активного аккаунта")
+	}
+	if !store.IsActiveCurator(1) || store.IsActiveCurator(2) {
+		t.Error("неверно определили кураторов")
+	}
+	err = store.ActivateCurator(2, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DeactivateCurator(1, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chatIDs, err := store.GetAllActiveCuratorsChatID()
+	if err != nil || !reflect.DeepEqual(chatIDs, []int64{20}) {
+		t.Errorf("чаты кураторов %v, ошибка %v", chatIDs, err)
+	}
+	userIDs, err := store.GetAllActiveCuratorsID()
+	if err != nil || !reflect.DeepEqual(userIDs, []int{2}) {
+		t.Errorf("кураторы %v, ошибка %v", userIDs, err)
+	}
+	chatIDs, err = store.GetAllChatIDs()
+	if err != nil || len(chatIDs) != 2 {
+		t.Errorf("%d чатов вместо 2, ошибка %v", len(chatIDs), err)
+	}
+}
+
+func testVotes(t *testing.T, store Storage) {
+	now := time.Now().UTC().Truncate(time.Second)
+	first := models.Vote{UserID: 1, Author: "chiliec", Permalink: "first", Percent: 100, Date: now.Add(-time.Hour)}
+	id, err := store.SaveVote(first, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.VoteID = id
+	second := models.Vote{UserID: 1, Author: "chiliec", Permalink: "second", Percent: 100, Date: now}
+	second.VoteID, err = store.SaveVote(second, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.VoteID == first.VoteID {
+		t.Fatal("голосования получили одинаковый id")
+	}
+	stored := store.GetVote(first.VoteID)
+	if !stored.Date.Equal(first.Date) {
+		t.Errorf("дата %s вместо %s", stored.Date, first.Date)
+	}
+	stored.Date = first.Date
+	if stored != first {
+		t.Errorf("\n%#v\n%#v\nНе равны!", stored, first)
+	}
+	if !store.VoteExists(models.Vote{Author: "chiliec", Permalink: "first"}) ||
+		store.VoteExists(models.Vote{Author: "chiliec", Permalink: "third"}) {
+		t.Error("неверно определили существование голосования")
+	}
+
+	first.Completed = true
+	id, err = store.SaveVote(first, testActor)
+	if err != nil || id != first.VoteID {
+		t.Fatalf("сохранённое голосование получило id %d вместо %d, ошибка %v", id, first.VoteID, err)
+	}
+	if count := store.GetOpenedVotesCount(); count != 1 {
+		t.Errorf("%d открытых голосований вместо 1", count)
+	}
+	if count := store.GetOpenedVotesCountForUserID(1); count != 1 {
+		t.Errorf("%d открытых голосований пользователя вместо 1", count)
+	}
+	if count := store.GetVotesCountForUserID(1); count != 2 {
+		t.Errorf("%d голосований пользователя вместо 2", count)
+	}
+	votes, err := store.GetLastVotesForUserID(1, 1)
+	if err != nil || len(votes) != 1 || votes[0].VoteID != second.VoteID {
+		t.Errorf("неожиданные последние голосования %#v, ошибка %v", votes, err)
+	}
+	votes, err = store.GetVotesPageForUserID(1, 1, 1)
+	if err != nil || len(votes) != 1 || votes[0].VoteID != first.VoteID {
+		t.Errorf("неожиданная страница голосований %#v, ошибка %v", votes, err)
+	}
+	if vote := store.GetLastVoteForUserID(1); vote.VoteID != second.VoteID {
+		t.Errorf("последнее голосование %d вместо %d", vote.VoteID, second.VoteID)
+	}
+	votes, err = store.GetAllOpenedVotes()
+	if err != nil || len(votes) != 1 || votes[0].VoteID != second.VoteID {
+		t.Errorf("неожиданные открытые голосования %#v, ошибка %v", votes, err)
+	}
+	votes, err = store.GetTrulyCompletedVotesSince(now.Add(-2 * time.Hour))
+	if err != nil || len(votes) != 1 || votes[0].VoteID != first.VoteID {
+		t.Errorf("неожиданные завершённые голосования %#v, ошибка %v", votes, err)
+	}
+	votes, err = store.GetVotesBetween(now.Add(-2*time.Hour), now)
+	if err != nil || len(votes) != 1 || votes[0].VoteID != first.VoteID {
+		t.Errorf("неожиданные голосования за период %#v, ошибка %v", votes, err)
+	}
+
+	err = store.SaveVoteTags(second.VoteID, []string{"golos", "art", "golos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := store.GetVoteTags(second.VoteID)
+	if err != nil || len(tags) != 2 {
+		t.Errorf("теги %v, ошибка %v", tags, err)
+	}
+	if count := store.GetOpenedVotesCountForTag("art"); count != 1 {
+		t.Errorf("%d открытых голосований с тегом вместо 1", count)
+	}
+}
+
+func testCasts(t *testing.T, store Storage) {
+	now := time.Now().UTC().Truncate(time.Second)
+	casts := []models.Cast{
+		{VoteID: 1, UserName: "chiliec", Author: "babin", Permalink: "old", Weight: 10000, Date: now.Add(-40 * 24 * time.Hour)},
+		{VoteID: 2, UserName: "chiliec", Author: "babin", Permalink: "fresh", Weight: 5000, Date: now},
+		{VoteID: 2, UserName: "babin", Author: "babin", Permalink: "fresh", Weight: 5000, Date: now},
+	}
+	for _, cast := range casts {
+		err := store.SaveCast(cast)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := store.GetCastsCountForVoteID(2); count != 2 {
+		t.Errorf("%d аккаунтов проголосовало вместо 2", count)
+	}
+	fresh, err := store.GetCastsByUserNameSince("chiliec", now.Add(-7*24*time.Hour))
+	if err != nil || len(fresh) != 1 || fresh[0].Permalink != "fresh" || !fresh[0].Date.Equal(now) {
+		t.Errorf("неожиданные свежие голоса %#v, ошибка %v", fresh, err)
+	}
+}
+
+func testTrusts(t *testing.T, store Storage) {
+	initial, err := store.GetTrustByUserID(5)
+	if err != nil || initial.UserID != 5 || initial.ApprovalRate != 1 {
+		t.Fatalf("неожиданное начальное доверие %#v, ошибка %v", initial, err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	trust := models.Trust{UserID: 5, Score: 0.8, ApprovalRate: 0.9, ClosedVotes: 6, AccountCreated: now.AddDate(-1, 0, 0), Date: now}
+	saved, err := store.SaveTrust(trust, testActor)
+	if err != nil || !saved {
+		t.Fatalf("доверие не сохранено: %v", err)
+	}
+	trust.Score = 0.801
+	trust.Date = now.Add(time.Minute)
+	saved, err = store.SaveTrust(trust, testActor)
+	if err != nil || saved {
+		t.Errorf("неизменившееся доверие переписано, ошибка %v", err)
+	}
+	stored, err := store.GetTrustByUserID(5)
+	if err != nil || stored.Score != 0.8 || stored.ClosedVotes != 6 || !stored.AccountCreated.Equal(trust.AccountCreated) {
+		t.Errorf("неожиданное доверие %#v, ошибка %v", stored, err)
+	}
+}
+
+func testClaims(t *testing.T, store Storage) {
+	now := time.Now()
+	claims := []models.Claim{
+		{UserID: 1, ChatID: 1, UserName: "chiliec", Code: "gvb-1", Date: now},
+		{UserID: 2, ChatID: 2, UserName: "chiliec", Code: "gvb-2", Date: now},
+		{UserID: 3, ChatID: 3, UserName: "chiliec", Code: "gvb-3", Date: now.Add(-2 * models.ClaimTTL)},
+	}
+	for _, claim := range claims {
+		err := store.SaveClaim(claim, testActor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rivals, err := store.GetRivalClaims(claims[0])
+	if err != nil || len(rivals) != 1 || rivals[0].UserID != 2 {
+		t.Errorf("неожиданные соперники %#v, ошибка %v", rivals, err)
+	}
+	purged, err := store.PurgeExpiredClaims(now, testActor)
+	if err != nil || purged != 1 {
+		t.Errorf("удалено %d просроченных заявок вместо 1, ошибка %v", purged, err)
+	}
+	err = store.DeleteClaim(2, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim, err := store.GetClaimByUserID(1)
+	if err != nil || claim.Code != "gvb-1" {
+		t.Errorf("неожиданная заявка %#v, ошибка %v", claim, err)
+	}
+	if _, err = store.GetClaimByUserID(2); err != sql.ErrNoRows {
+		t.Errorf("удалённая заявка осталась, ошибка %v", err)
+	}
+}
+
+func testBans(t *testing.T, store Storage) {
+	err := store.SaveBan(models.Ban{Kind: models.BanAuthor, Target: "spammer", AdminID: 1, Date: time.Now()}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsBanned(models.BanAuthor, "spammer") || store.IsBanned(models.BanUser, "spammer") {
+		t.Error("бан не сохранён или задел не тот вид")
+	}
+	deleted, err := store.DeleteBan(models.BanAuthor, "spammer", testActor)
+	if err != nil || !deleted || store.IsBanned(models.BanAuthor, "spammer") {
+		t.Errorf("бан не снят, ошибка %v", err)
+	}
+	deleted, err = store.DeleteBan(models.BanAuthor, "spammer", testActor)
+	if err != nil || deleted {
+		t.Errorf("снят несуществующий бан, ошибка %v", err)
+	}
+	for _, command := range []string{"ban", "unban"} {
+		err = store.SaveAdminAction(models.AdminAction{AdminID: 1, Command: command, Date: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	actions, err := store.GetLastAdminActions(1)
+	if err != nil || len(actions) != 1 || actions[0].Command != "unban" {
+		t.Errorf("неожиданные команды %#v, ошибка %v", actions, err)
+	}
+}
+
+func testLanguages(t *testing.T, store Storage) {
+	language, err := store.GetLanguageByUserID(1, "ru")
+	if err != nil || language.Code != "ru" || language.Manual {
+		t.Fatalf("неожиданный язык по умолчанию %#v, ошибка %v", language, err)
+	}
+	err = store.SaveLanguage(models.Language{UserID: 1, Code: "en", Manual: true}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	language, err = store.GetLanguageByUserID(1, "ru")
+	if err != nil || language.Code != "en" || !language.Manual {
+		t.Errorf("неожиданный язык %#v, ошибка %v", language, err)
+	}
+}
+
+func testMembers(t *testing.T, store Storage) {
+	now := time.Now().UTC().Truncate(time.Second)
+	err := store.SaveMember(models.Member{UserID: 1, Verified: true, Since: now}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SaveMember(models.Member{UserID: 2, Since: now, Attempts: 2, RetryAt: now.Add(time.Hour)}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := store.GetMemberByUserID(2)
+	if err != nil || member.Attempts != 2 || !member.RetryAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("неожиданный участник %#v, ошибка %v", member, err)
+	}
+	err = store.DeleteMember(2, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := store.GetAllMembers()
+	if err != nil || len(members) != 1 || members[0].UserID != 1 {
+		t.Errorf("неожиданные участники %#v, ошибка %v", members, err)
+	}
+	for _, reason := range []string{"first", "second"} {
+		err = store.SaveRemoval(models.Removal{UserID: 2, Reason: reason, Date: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	removals, err := store.GetLastRemovals(1)
+	if err != nil || len(removals) != 1 || removals[0].Reason != "second" {
+		t.Errorf("неожиданные удаления %#v, ошибка %v", removals, err)
+	}
+}
+
+func testOutbox(t *testing.T, store Storage) {
+	var ids []int64
+	for _, chatID := range []int64{1, 2, 3} {
+		id, err := store.AddOutboxMessage(models.OutboxMessage{ChatID: chatID, Config: "{}", Date: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	retryAt := time.Now().UTC().Truncate(time.Second).Add(time.Minute)
+	err := store.RetryOutboxMessage(ids[0], 1, retryAt, "timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.BuryOutboxMessage(ids[1], 5, "forbidden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DeleteOutboxMessage(ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := store.GetOutboxMessages()
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("неожиданная очередь %#v, ошибка %v", messages, err)
+	}
+	if messages[0].ID != ids[0] || messages[0].Attempts != 1 || !messages[0].RetryAt.Equal(retryAt) || messages[0].Error != "timeout" {
+		t.Errorf("неудачная попытка не сохранена: %#v", messages[0])
+	}
+}
+
+func testCursors(t *testing.T, store Storage) {
+	cursor, err := store.GetCursor("events")
+	if err != nil || cursor.Name != "events" || cursor.BlockNum != 0 {
+		t.Fatalf("неожиданный новый курсор %#v, ошибка %v", cursor, err)
+	}
+	for _, blockNum := range []uint32{10, 4000000000} {
+		err = store.SaveCursor(models.Cursor{Name: "events", BlockNum: blockNum})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cursor, err = store.GetCursor("events")
+	if err != nil || cursor.BlockNum != 4000000000 {
+		t.Errorf("неожиданный курсор %#v, ошибка %v", cursor, err)
+	}
+}
+
+func testResponses(t *testing.T, store Storage) {
+	now := time.Now()
+	responses := []models.Response{
+		{UserID: 1, VoteID: 100, Result: true, Date: now},
+		{UserID: 2, VoteID: 100, Result: false, Date: now},
+		{UserID: 2, VoteID: 101, Result: true, Date: now.Add(-48 * time.Hour)},
+	}
+	for _, response := range responses {
+		err := store.SaveResponse(response, testActor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// повторный ответ заменяет прежний
+	err := store.SaveResponse(models.Response{UserID: 2, VoteID: 100, Result: true, Date: now}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.ResponseExists(responses[0]) || store.ResponseExists(models.Response{UserID: 3, VoteID: 100}) {
+		t.Error("неверно определили существование ответа")
+	}
+	all, err := store.GetAllResponsesForVoteID(100)
+	if err != nil || len(all) != 2 {
+		t.Errorf("%d ответов вместо 2, ошибка %v", len(all), err)
+	}
+	if positives, negatives := store.GetNumResponsesVoteID(100); positives != 2 || negatives != 0 {
+		t.Errorf("%d за и %d против вместо 2 и 0", positives, negatives)
+	}
+	since := now.Add(-24 * time.Hour)
+	if num := store.GetNumResponsesForMotivation(since); num != 2 {
+		t.Errorf("%d свежих ответов вместо 2", num)
+	}
+	userIDs, err := store.GetUserIDsForMotivation(since)
+	if err != nil || len(userIDs) != 2 {
+		t.Errorf("кураторы %v, ошибка %v", userIDs, err)
+	}
+	if num := store.GetNumResponsesForMotivationForUserID(2, since); num != 1 {
+		t.Errorf("%d свежих ответов куратора вместо 1", num)
+	}
+	all, err = store.GetResponsesBetween(now.Add(-72*time.Hour), since)
+	if err != nil || len(all) != 1 || all[0].VoteID != 101 {
+		t.Errorf("неожиданные ответы за период %#v, ошибка %v", all, err)
+	}
+}
+
+func testReferrals(t *testing.T, store Storage) {
+	now := time.Now().UTC().Truncate(time.Second)
+	pending := models.Referral{UserID: 1, Referrer: "chiliec", UserName: "newbie", Campaign: "summer",
+		Status: models.ReferralPending, Deadline: now.Add(time.Hour)}
+	paid := models.Referral{UserID: 2, Referrer: "chiliec", UserName: "oldbie", Completed: true,
+		Status: models.ReferralPaid, ReferrerPaid: true, ReferralPaid: true, PaidAt: now}
+	for _, referral := range []models.Referral{pending, paid} {
+		err := store.SaveReferral(referral, testActor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	referral, err := store.GetReferralByUserID(1)
+	if err != nil || referral.UserName != "newbie" || !referral.Deadline.Equal(pending.Deadline) {
+		t.Errorf("неожиданный реферал %#v, ошибка %v", referral, err)
+	}
+	pendings, err := store.GetPendingReferrals()
+	if err != nil || len(pendings) != 1 || pendings[0].UserID != 1 {
+		t.Errorf("неожиданные ожидающие рефералы %#v, ошибка %v", pendings, err)
+	}
+	if all, err := store.GetAllReferrals(); err != nil || len(all) != 2 {
+		t.Errorf("%d рефералов вместо 2, ошибка %v", len(all), err)
+	}
+	if count := store.GetPaidReferralsCountSince("chiliec", now.Add(-time.Hour)); count != 1 {
+		t.Errorf("%d выплат вместо 1", count)
+	}
+	err = store.SetReferralCompleted(1, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := store.GetReferralStats("chiliec")
+	if err != nil || len(stats) != 2 {
+		t.Fatalf("неожиданная статистика %#v, ошибка %v", stats, err)
+	}
+	expected := models.ReferralStats{Referrer: "chiliec", Campaign: "summer", Invited: 1, Registered: 1}
+	if stats[1] != expected {
+		t.Errorf("\n%#v\n%#v\nНе равны!", stats[1], expected)
+	}
+	if !store.IsReferralExists("newbie") || store.IsReferralExists("nobody") {
+		t.Error("неверно определили существование реферала")
+	}
+}
+
+func testStates(t *testing.T, store Storage) {
+	state, err := store.GetStateByUserID(1)
+	if err != nil || state.UserID != 1 || state.Action != "" {
+		t.Errorf("пустое состояние %#v, ошибка %v", state, err)
+	}
+	expires := time.Now().UTC().Truncate(time.Second).Add(time.Hour)
+	err = store.SaveState(models.State{UserID: 1, Action: "login", Payload: "chiliec", Expires: expires})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err = store.GetStateByUserID(1)
+	if err != nil || state.Action != "login" || state.Payload != "chiliec" || !state.Expires.Equal(expires) {
+		t.Errorf("неожиданное состояние %#v, ошибка %v", state, err)
+	}
+}
+
+func testEvents(t *testing.T, store Storage) {
+	before := time.Now().Add(-time.Minute)
+	err := store.NewReportPosted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.NewRewardDistributed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date := store.GetLastReportDate(); date.Before(before) {
+		t.Errorf("дата отчёта %s раньше %s", date, before)
+	}
+	if date := store.GetLastRewardDate(); date.Before(before) {
+		t.Errorf("дата наград %s раньше %s", date, before)
+	}
+}
+
+// testAudit проверяет, что изменения из предыдущих проверок попали в журнал
+func testAudit(t *testing.T, store Storage) {
+	changes, err := store.GetChanges(models.ChangeFilter{Entity: models.EntityCredential, EntityID: "babin"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[2].Before != "" || changes[2].Actor != "system:test" {
+		t.Fatalf("неожиданный журнал аккаунта %#v", changes)
+	}
+	if !strings.Contains(changes[1].Before, `"Power":100`) || !strings.Contains(changes[1].After, `"Power":30`) {
+		t.Errorf("изменение силы не записано: %#v", changes[1])
+	}
+	for _, entity := range []string{models.EntityVote, models.EntityResponse, models.EntityReferral,
+		models.EntityTrust, models.EntityClaim, models.EntityBan, models.EntityLanguage, models.EntityMember} {
+		changes, err = store.GetChanges(models.ChangeFilter{Entity: entity}, 10)
+		if err != nil || len(changes) == 0 {
+			t.Errorf("нет изменений %s в журнале, ошибка %v", entity, err)
+		}
+	}
+}